@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 
 	"github.com/joho/godotenv"
+	"github.com/mrparano1d/noxite/pkg/adapters"
 	"github.com/mrparano1d/noxite/pkg/app"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
 
 	"github.com/spf13/cobra"
 )
@@ -68,6 +70,7 @@ to quickly create a Cobra application.`,
 		}
 
 		entClient := app.EntClient()
+		hasher := adapters.NewPasswordHasherAdapter(adapters.DefaultArgon2Params)
 
 		adminPermissions := entities.Permissions{}
 		err := json.Unmarshal([]byte(adminRoleJSON()), &adminPermissions)
@@ -80,7 +83,12 @@ to quickly create a Cobra application.`,
 			panic(err)
 		}
 
-		_, err = entClient.User.Create().SetName("admin").SetEmail("admin@example.com").SetPassword([]byte("AdminPassw0rd!")).SetRoleID(adminRole.ID).Save(cmd.Context())
+		adminPassword, err := hasher.Hash(fields.Password("AdminPassw0rd!"))
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = entClient.User.Create().SetName("admin").SetEmail("admin@example.com").SetPassword([]byte(adminPassword.String())).SetRoleID(adminRole.ID).Save(cmd.Context())
 		if err != nil {
 			panic(err)
 		}
@@ -96,7 +104,12 @@ to quickly create a Cobra application.`,
 			panic(err)
 		}
 
-		_, err = entClient.User.Create().SetName("demo").SetEmail("demo@example.com").SetPassword([]byte("DemoPassw0rd!")).SetRoleID(userRole.ID).Save(cmd.Context())
+		demoPassword, err := hasher.Hash(fields.Password("DemoPassw0rd!"))
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = entClient.User.Create().SetName("demo").SetEmail("demo@example.com").SetPassword([]byte(demoPassword.String())).SetRoleID(userRole.ID).Save(cmd.Context())
 		if err != nil {
 			panic(err)
 		}
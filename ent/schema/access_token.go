@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+)
+
+// AccessToken holds the schema definition for the long-lived tokens of users.
+type AccessToken struct {
+	ent.Schema
+}
+
+// Annotations of the AccessToken.
+func (AccessToken) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the AccessToken.
+func (AccessToken) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		// key is the SHA-256 of the token, the token itself is never stored
+		field.String("key").NotEmpty().Unique().Sensitive(),
+		field.String("display_prefix").NotEmpty(),
+		field.Bool("readonly").Default(false),
+		// automation tokens skip the one-time passwords of writes
+		field.Bool("automation").Default(false),
+		field.Strings("cidr_whitelist").Optional().Default([]string{}),
+		// packages and scopes restrict the token, both empty allow all packages
+		field.Strings("packages").Optional().Default([]string{}),
+		field.Strings("scopes").Optional().Default([]string{}),
+		field.Time("expires_at").Optional().Nillable(),
+		field.Int("user_id").Positive(),
+		field.Time("created_at").Default(time.Now).Immutable(),
+		field.Time("updated_at").Optional().Nillable(),
+		field.Time("last_used_at").Optional().Nillable(),
+		field.Time("deleted_at").Optional().Nillable(),
+	}
+}
+
+// Edges of the AccessToken.
+func (AccessToken) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("access_tokens").Unique().Required().Field("user_id"),
+	}
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// AuditEntry holds the schema definition for the audit log of security relevant events like account lockouts.
+type AuditEntry struct {
+	ent.Schema
+}
+
+// Annotations of the AuditEntry.
+func (AuditEntry) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entsql.Annotation{Table: "audit_log"},
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the AuditEntry.
+func (AuditEntry) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.String("action").NotEmpty().Immutable(),
+		// actor_id is the user who caused the entry, it is unset for anonymous clients
+		field.Int("actor_id").Optional().Nillable().Immutable(),
+		field.String("subject").Default("").Immutable(),
+		field.String("ip").Default("").Immutable(),
+		field.String("details").Default("").Immutable(),
+		field.Time("created_at").Default(time.Now).Immutable(),
+	}
+}
+
+// Indexes of the AuditEntry.
+func (AuditEntry) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("action", "created_at"),
+	}
+}
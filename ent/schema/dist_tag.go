@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// DistTag holds the schema definition for the DistTag entity.
+// A dist-tag is a named pointer (e.g. "latest", "next") from a package to one of its versions.
+type DistTag struct {
+	ent.Schema
+}
+
+// Fields of the DistTag.
+func (DistTag) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.String("tag").NotEmpty(),
+		field.Int("package_id"),
+		field.Int("version_id"),
+		field.Time("created_at").Default(time.Now).Immutable(),
+		field.Time("updated_at").Optional().Nillable(),
+	}
+}
+
+// Edges of the DistTag.
+func (DistTag) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("package", RepoPackage.Type).Ref("dist_tags").Unique().Required().Field("package_id"),
+		edge.From("version", Version.Type).Ref("dist_tags").Unique().Required().Field("version_id"),
+	}
+}
+
+// Indexes of the DistTag.
+func (DistTag) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("package_id", "tag").Unique(),
+	}
+}
@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// Invite holds the schema definition for the invites of the invite-only signup policy.
+type Invite struct {
+	ent.Schema
+}
+
+// Annotations of the Invite.
+func (Invite) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the Invite.
+func (Invite) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.String("email").NotEmpty(),
+		// role_id replaces the default role of the signup if set
+		field.Int("role_id").Optional().Nillable(),
+		field.Int("created_by").Positive(),
+		field.Int("used_by").Optional().Nillable(),
+		field.Time("expires_at").Optional().Nillable(),
+		field.Time("used_at").Optional().Nillable(),
+		field.Time("created_at").Default(time.Now).Immutable(),
+	}
+}
+
+// Indexes of the Invite.
+func (Invite) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("email"),
+	}
+}
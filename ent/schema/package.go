@@ -28,6 +28,8 @@ func (p RepoPackage) Fields() []ent.Field {
 	return []ent.Field{
 		field.Int("id").Unique(),
 		field.String("name").Unique().NotEmpty(),
+		// scope of scoped packages like "@scope/name" including the "@"
+		field.String("scope").Optional().Nillable(),
 		field.Int("creator_id"),
 		field.Time("created_at").Default(time.Now).Immutable(),
 		field.Time("updated_at").Optional().Nillable(),
@@ -38,6 +40,7 @@ func (p RepoPackage) Fields() []ent.Field {
 func (p RepoPackage) Edges() []ent.Edge {
 	return []ent.Edge{
 		edge.To("versions", Version.Type).Annotations(entgql.MultiOrder(), entgql.RelayConnection()),
+		edge.To("dist_tags", DistTag.Type),
 		edge.From("creator", User.Type).Ref("packages").Unique().Required().Field("creator_id"),
 	}
 }
@@ -34,6 +34,8 @@ func (Role) Fields() []ent.Field {
 		field.String("name").NotEmpty().Unique(),
 		field.String("description").Optional(),
 		field.JSON("permissions", entities.Permissions{}),
+		// require_two_factor blocks writes of users of the role until they enabled two-factor authentication
+		field.Bool("require_two_factor").Default(false),
 		field.Time("created_at").Immutable().Default(time.Now),
 		field.Time("updated_at").Optional().Nillable(),
 		field.Time("deleted_at").Optional().Nillable(),
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+)
+
+// SearchDocument holds the schema definition for the search index of packages, which uses the full-text search of Postgres.
+type SearchDocument struct {
+	ent.Schema
+}
+
+// Annotations of the SearchDocument.
+func (SearchDocument) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the SearchDocument.
+func (SearchDocument) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.String("name").NotEmpty().Unique(),
+		// scope is the scope of the package with "@", it is empty for unscoped packages
+		field.String("scope").Default(""),
+		// document is the indexed document returned by searches
+		field.JSON("document", &entities.SearchDocument{}),
+		// names, keywords, authors and maintainers hold the lower case values matched by the qualifiers of a search
+		field.Strings("names").Optional().Default([]string{}),
+		field.Strings("keywords").Optional().Default([]string{}),
+		field.Strings("authors").Optional().Default([]string{}),
+		field.Strings("maintainers").Optional().Default([]string{}),
+		field.Bool("deprecated").Default(false),
+		// search_vector holds the words of the name, keywords, description, author and readme weighted in this order
+		field.String("search_vector").Default("").SchemaType(map[string]string{
+			dialect.Postgres: "tsvector",
+		}),
+	}
+}
+
+// Indexes of the SearchDocument.
+func (SearchDocument) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("scope"),
+		index.Fields("search_vector").Annotations(entsql.IndexTypes(map[string]string{
+			dialect.Postgres: "GIN",
+		})),
+	}
+}
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// Session holds the schema definition for the sessions of the database session store.
+type Session struct {
+	ent.Schema
+}
+
+// Annotations of the Session.
+func (Session) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the Session.
+func (Session) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		// token holds the SHA-256 of the session token, see fields.SessionTokenKey
+		field.String("token").NotEmpty().Unique().Sensitive(),
+		// user_id is set once the session is linked to a user, it is no edge since sessions start anonymous
+		field.Int("user_id").Optional().Nillable(),
+		field.Time("expires_at"),
+		field.Time("created_at").Default(time.Now).Immutable(),
+		field.Time("last_used_at").Default(time.Now),
+		field.String("ip").Default(""),
+		field.String("user_agent").Default(""),
+	}
+}
+
+// Edges of the Session.
+func (Session) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.To("values", SessionValue.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+	}
+}
+
+// Indexes of the Session.
+func (Session) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id"),
+		index.Fields("expires_at"),
+	}
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+)
+
+// SessionValue holds the schema definition for the serialized values of a session.
+type SessionValue struct {
+	ent.Schema
+}
+
+// Annotations of the SessionValue.
+func (SessionValue) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the SessionValue.
+func (SessionValue) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.Int("session_id"),
+		field.String("key").NotEmpty(),
+		field.Bytes("value"),
+	}
+}
+
+// Edges of the SessionValue.
+func (SessionValue) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("session", Session.Type).Ref("values").Unique().Required().Field("session_id"),
+	}
+}
+
+// Indexes of the SessionValue.
+func (SessionValue) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("session_id", "key").Unique(),
+	}
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/contrib/entgql"
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+)
+
+// TwoFactor holds the schema definition for the TOTP settings of users.
+type TwoFactor struct {
+	ent.Schema
+}
+
+// Annotations of the TwoFactor.
+func (TwoFactor) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entgql.Skip(entgql.SkipAll),
+	}
+}
+
+// Fields of the TwoFactor.
+func (TwoFactor) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int("id").Unique(),
+		field.Int("user_id").Positive().Unique(),
+		// secret is needed to compute codes, so unlike passwords it can't be hashed
+		field.String("secret").NotEmpty().Sensitive(),
+		field.String("mode").NotEmpty(),
+		field.Bool("pending").Default(true),
+		// recovery_codes holds the SHA-256 of the unused recovery codes
+		field.Strings("recovery_codes").Optional().Default([]string{}).Sensitive(),
+		field.Int64("last_used_step").Default(0),
+		field.Time("created_at").Default(time.Now).Immutable(),
+		field.Time("updated_at").Optional().Nillable(),
+	}
+}
+
+// Edges of the TwoFactor.
+func (TwoFactor) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("two_factor").Unique().Required().Field("user_id"),
+	}
+}
@@ -37,6 +37,9 @@ func (User) Fields() []ent.Field {
 		field.Bytes("avatar").Optional().Nillable().Annotations(entgql.Type("Bytes")),
 		field.Bytes("password").NotEmpty().Sensitive().Annotations(entgql.Skip()),
 		field.Int("role_id").Positive(),
+		// auth_source is the external directory managing the user, e.g. "ldap", and empty for local users.
+		// Administrators link existing users to a directory by setting it.
+		field.String("auth_source").Optional(),
 		field.Time("created_at").Default(time.Now).Immutable(),
 		field.Time("updated_at").Optional().Nillable(),
 		field.Time("deleted_at").Optional().Nillable(),
@@ -49,5 +52,7 @@ func (User) Edges() []ent.Edge {
 		edge.From("role", Role.Type).Ref("user_role").Unique().Required().Field("role_id"),
 		edge.To("packages", RepoPackage.Type).Annotations(entgql.RelayConnection(), entgql.MultiOrder()),
 		edge.To("publishes", Version.Type).Annotations(entgql.RelayConnection(), entgql.MultiOrder()),
+		edge.To("access_tokens", AccessToken.Type).Annotations(entgql.Skip(entgql.SkipAll)),
+		edge.To("two_factor", TwoFactor.Type).Unique().Annotations(entgql.Skip(entgql.SkipAll)),
 	}
 }
@@ -60,8 +60,16 @@ func (v Version) Fields() []ent.Field {
 		field.JSON("publish_config", map[fields.RequiredString]interface{}{}).Optional().Annotations(entgql.Type("RequiredKeyMap")),
 		field.Strings("workspaces").Optional().Default([]string{}),
 		field.String("readme").Optional(),
+		// deprecated is the deprecation message set by "npm deprecate", empty if the version is not deprecated
+		field.String("deprecated").Optional(),
+		// raw_manifest is the version manifest as it was published, including fields without a typed column
+		field.Text("raw_manifest").Optional(),
 		field.String("content_type"),
-		field.String("data"),
+		// tarball_digest addresses the tarball in the blob storage
+		field.String("tarball_digest").Optional(),
+		// data holds the base64 encoded tarballs of versions published before the blob storage existed.
+		// It is moved to the blob storage and emptied on startup.
+		field.String("data").Optional(),
 		field.String("integrity"),
 		field.String("shasum"),
 		field.Int("length"),
@@ -77,5 +85,6 @@ func (v Version) Edges() []ent.Edge {
 	return []ent.Edge{
 		edge.From("publisher", User.Type).Ref("publishes").Unique().Required().Field("publisher_id"),
 		edge.From("package", RepoPackage.Type).Ref("versions").Unique().Required().Field("package_id"),
+		edge.To("dist_tags", DistTag.Type),
 	}
 }
@@ -6,14 +6,21 @@ package resolvers
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/mrparano1d/noxite/graph"
+	"github.com/mrparano1d/noxite/pkg/core/services"
+	noxqgql "github.com/mrparano1d/noxite/pkg/graphql"
 )
 
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, usernameOrEmail string, password string) (*graph.AuthPayload, error) {
 	sess, err := r.core.AuthService().Login(ctx, usernameOrEmail, password)
 	if err != nil {
+		if throttled, ok := err.(*services.AuthServiceLoginThrottledError); ok {
+			noxqgql.SetRetryAfter(ctx, throttled.RetryAfterSeconds())
+		}
 		return nil, err
 	}
 	return &graph.AuthPayload{
@@ -22,6 +29,121 @@ func (r *mutationResolver) Login(ctx context.Context, usernameOrEmail string, pa
 	}, nil
 }
 
+// CreateInvite is the resolver for the createInvite field.
+func (r *mutationResolver) CreateInvite(ctx context.Context, email string, roleID *int, expiresInDays *int) (*graph.InvitePayload, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := services.CreateInviteRequest{
+		Email: email,
+	}
+	if roleID != nil {
+		id := strconv.Itoa(*roleID)
+		req.RoleID = &id
+	}
+	if expiresInDays != nil {
+		req.ExpiresIn = time.Duration(*expiresInDays) * 24 * time.Hour
+	}
+
+	id, err := r.core.SignupService().CreateInvite(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+	return &graph.InvitePayload{
+		ID: id.Int(),
+	}, nil
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.core.SessionService().RevokeMySession(ctx, user, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeAllOtherSessions is the resolver for the revokeAllOtherSessions field.
+func (r *mutationResolver) RevokeAllOtherSessions(ctx context.Context) (int, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	token, _ := noxqgql.TokenFromContext(ctx)
+	return r.core.SessionService().RevokeMyOtherSessions(ctx, user, token)
+}
+
+// RevokeUserSession is the resolver for the revokeUserSession field.
+func (r *mutationResolver) RevokeUserSession(ctx context.Context, userID int, id string) (bool, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.core.SessionService().RevokeUserSession(ctx, user, strconv.Itoa(userID), id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeAllUserSessions is the resolver for the revokeAllUserSessions field.
+func (r *mutationResolver) RevokeAllUserSessions(ctx context.Context, userID int) (int, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.core.SessionService().RevokeAllUserSessions(ctx, user, strconv.Itoa(userID))
+}
+
+// UnlockUser is the resolver for the unlockUser field.
+func (r *mutationResolver) UnlockUser(ctx context.Context, userID int) (bool, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.core.AuthService().UnlockUser(ctx, user, strconv.Itoa(userID)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*graph.UserSession, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := r.core.SessionService().ListMySessions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return toUserSessions(ctx, sessions), nil
+}
+
+// UserSessions is the resolver for the userSessions field.
+func (r *queryResolver) UserSessions(ctx context.Context, userID int) ([]*graph.UserSession, error) {
+	user, err := r.userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := r.core.SessionService().ListUserSessions(ctx, user, strconv.Itoa(userID))
+	if err != nil {
+		return nil, err
+	}
+	return toUserSessions(ctx, sessions), nil
+}
+
 // Mutation returns graph.MutationResolver implementation.
 func (r *Resolver) Mutation() graph.MutationResolver { return &mutationResolver{r} }
 
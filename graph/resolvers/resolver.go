@@ -8,6 +8,8 @@ import (
 	"github.com/mrparano1d/noxite/ent"
 	"github.com/mrparano1d/noxite/graph"
 	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
 
 	noxqgql "github.com/mrparano1d/noxite/pkg/graphql"
 )
@@ -46,3 +48,32 @@ func NewSchema(client *ent.Client, core *core.ApplicationCore) graphql.Executabl
 		},
 	})
 }
+
+// userFromContext returns the user of the session the request was authenticated with.
+func (r *Resolver) userFromContext(ctx context.Context) (*entities.User, error) {
+	token, exists := noxqgql.TokenFromContext(ctx)
+	if !exists {
+		return nil, fmt.Errorf("no token found in context")
+	}
+	return r.core.SessionService().GetSessionUser(ctx, token)
+}
+
+// toUserSessions converts the sessions and marks the session the request was authenticated with.
+func toUserSessions(ctx context.Context, sessions []*entities.Session) []*graph.UserSession {
+	token, _ := noxqgql.TokenFromContext(ctx)
+	tokenKey := fields.SessionToken(token).Key()
+
+	userSessions := make([]*graph.UserSession, 0, len(sessions))
+	for _, session := range sessions {
+		userSessions = append(userSessions, &graph.UserSession{
+			ID:         session.ID(),
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IP:         session.Client.IP,
+			UserAgent:  session.Client.UserAgent,
+			Current:    session.Key == tokenKey,
+		})
+	}
+	return userSessions
+}
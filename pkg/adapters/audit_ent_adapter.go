@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// AuditEntAdapter stores audit entries in the audit_log table.
+type AuditEntAdapter struct {
+	entClient *ent.Client
+}
+
+var _ ports.AuditPort = (*AuditEntAdapter)(nil)
+
+func NewAuditEntAdapter(entClient *ent.Client) *AuditEntAdapter {
+	return &AuditEntAdapter{
+		entClient: entClient,
+	}
+}
+
+func (a *AuditEntAdapter) RecordAuditEntry(ctx context.Context, input ports.RecordAuditEntryInput) error {
+	create := a.entClient.AuditEntry.Create().
+		SetAction(string(input.Action)).
+		SetSubject(input.Subject).
+		SetIP(input.IP).
+		SetDetails(input.Details)
+	if input.ActorID != nil {
+		create.SetActorID(input.ActorID.Int())
+	}
+
+	if err := create.Exec(ctx); err != nil {
+		return &ports.AuditAdapterRecordError{
+			Action: input.Action,
+			Err:    err,
+		}
+	}
+
+	return nil
+}
@@ -2,8 +2,10 @@ package adapters
 
 import (
 	"context"
+	"time"
 
 	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/predicate"
 	"github.com/mrparano1d/noxite/ent/user"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
 	"github.com/mrparano1d/noxite/pkg/core/fields"
@@ -22,44 +24,54 @@ func NewAuthAdapter(entClient *ent.Client) *AuthAdapter {
 	}
 }
 
-func (a *AuthAdapter) Login(ctx context.Context, username fields.Username, password fields.Password) (*entities.User, error) {
-	user, err := a.entClient.User.Query().
-		WithRole().
-		Where(user.DeletedAtIsNil()).
-		Where(user.Name(username.String())).
-		Where(user.Password(password.Bytes())).
-		Only(ctx)
+func (a *AuthAdapter) GetCredentials(ctx context.Context, username fields.Username) (*entities.User, fields.PasswordHash, error) {
+	return a.getCredentials(ctx, username, user.Name(username.String()))
+}
+
+func (a *AuthAdapter) GetCredentialsByEmail(ctx context.Context, email fields.Email) (*entities.User, fields.PasswordHash, error) {
+	return a.getCredentials(ctx, fields.Username(email.String()), user.Email(email.String()))
+}
+
+func (a *AuthAdapter) UpdatePasswordHash(ctx context.Context, userID fields.EntityID, hash fields.PasswordHash) error {
+	err := a.entClient.User.UpdateOneID(userID.Int()).
+		SetPassword([]byte(hash.String())).
+		SetUpdatedAt(time.Now()).
+		Exec(ctx)
 	if err != nil {
-		if ent.IsNotFound(err) {
-			return nil, &ports.AuthAdapterUserNotFoundError{
-				Username: username,
-			}
-		}
-		return nil, &ports.AuthAdapterLoginFailedError{
-			Err: err,
+		return &ports.AuthAdapterUpdatePasswordHashError{
+			UserID: userID,
+			Err:    err,
 		}
 	}
 
-	return UserFromEntUser(user)
+	return nil
 }
 
-func (a *AuthAdapter) LoginByEmail(ctx context.Context, email fields.Email, password fields.Password) (*entities.User, error) {
-	user, err := a.entClient.User.Query().
+func (a *AuthAdapter) getCredentials(ctx context.Context, username fields.Username, where predicate.User) (*entities.User, fields.PasswordHash, error) {
+	u, err := a.entClient.User.Query().
 		WithRole().
 		Where(user.DeletedAtIsNil()).
-		Where(user.Email(email.String())).
-		Where(user.Password(password.Bytes())).
+		Where(where).
 		Only(ctx)
 	if err != nil {
 		if ent.IsNotFound(err) {
-			return nil, &ports.AuthAdapterUserNotFoundError{
-				Username: fields.Username(email.String()),
+			return nil, "", &ports.AuthAdapterUserNotFoundError{
+				Username: username,
 			}
 		}
-		return nil, &ports.AuthAdapterLoginFailedError{
-			Err: err,
+		return nil, "", &ports.AuthAdapterLoginFailedError{
+			Username: username,
+			Err:      err,
+		}
+	}
+
+	result, err := UserFromEntUser(u)
+	if err != nil {
+		return nil, "", &ports.AuthAdapterLoginFailedError{
+			Username: username,
+			Err:      err,
 		}
 	}
 
-	return UserFromEntUser(user)
+	return result, fields.PasswordHash(u.Password), nil
 }
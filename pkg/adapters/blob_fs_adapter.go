@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// BlobFSAdapter stores blobs as files below a root directory in the layout "sha256/<first 2 hex chars>/<hex>".
+type BlobFSAdapter struct {
+	root string
+}
+
+var _ ports.BlobPort = (*BlobFSAdapter)(nil)
+
+func NewBlobFSAdapter(root string) (*BlobFSAdapter, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory %s: %w", root, err)
+	}
+	return &BlobFSAdapter{
+		root: root,
+	}, nil
+}
+
+func (a *BlobFSAdapter) path(digest fields.Digest) string {
+	hex := digest.Hex()
+	return filepath.Join(a.root, "sha256", hex[:2], hex)
+}
+
+func (a *BlobFSAdapter) Put(ctx context.Context, r io.Reader) (fields.Digest, error) {
+	// write to a temporary file first since the digest is only known after reading the whole content
+	tmp, err := os.CreateTemp(filepath.Join(a.root, "tmp"), "blob-*")
+	if err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+	if err := tmp.Close(); err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	digest := fields.DigestFromHash(h)
+	target := a.path(digest)
+
+	if _, err := os.Stat(target); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	return digest, nil
+}
+
+func (a *BlobFSAdapter) Get(ctx context.Context, digest fields.Digest) (io.ReadCloser, int64, error) {
+	f, err := os.Open(a.path(digest))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, 0, &ports.BlobAdapterNotFoundError{Digest: digest}
+		}
+		return nil, 0, &ports.BlobAdapterGetError{Digest: digest, Err: err}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, &ports.BlobAdapterGetError{Digest: digest, Err: err}
+	}
+
+	return f, info.Size(), nil
+}
+
+func (a *BlobFSAdapter) Delete(ctx context.Context, digest fields.Digest) error {
+	if err := os.Remove(a.path(digest)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &ports.BlobAdapterNotFoundError{Digest: digest}
+		}
+		return &ports.BlobAdapterDeleteError{Digest: digest, Err: err}
+	}
+	return nil
+}
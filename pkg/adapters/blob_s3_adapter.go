@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// BlobS3Config configures the connection to an S3 compatible object storage like AWS S3 or MinIO.
+type BlobS3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// Prefix is prepended to all object keys, e.g. "noxite/".
+	Prefix string
+}
+
+// BlobS3Adapter stores blobs as objects with the key "<prefix>sha256/<hex>" in a S3 compatible bucket.
+type BlobS3Adapter struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+var _ ports.BlobPort = (*BlobS3Adapter)(nil)
+
+// NewBlobS3Adapter connects to the object storage and creates the bucket if it doesn't exist yet.
+func NewBlobS3Adapter(ctx context.Context, config BlobS3Config) (*BlobS3Adapter, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, config.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", config.Bucket, err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, config.Bucket, minio.MakeBucketOptions{Region: config.Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", config.Bucket, err)
+		}
+	}
+
+	return &BlobS3Adapter{
+		client: client,
+		bucket: config.Bucket,
+		prefix: config.Prefix,
+	}, nil
+}
+
+func (a *BlobS3Adapter) key(digest fields.Digest) string {
+	return a.prefix + path.Join("sha256", digest.Hex())
+}
+
+func (a *BlobS3Adapter) stat(ctx context.Context, digest fields.Digest) (minio.ObjectInfo, bool, error) {
+	info, err := a.client.StatObject(ctx, a.bucket, a.key(digest), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return info, false, nil
+		}
+		return info, false, err
+	}
+	return info, true, nil
+}
+
+func (a *BlobS3Adapter) Put(ctx context.Context, r io.Reader) (fields.Digest, error) {
+	// spool to a temporary file first since the object key is only known after reading the whole content
+	tmp, err := os.CreateTemp("", "noxite-blob-*")
+	if err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	digest := fields.DigestFromHash(h)
+
+	_, exists, err := a.stat(ctx, digest)
+	if err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+	if exists {
+		return digest, nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	if _, err := a.client.PutObject(ctx, a.bucket, a.key(digest), tmp, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	}); err != nil {
+		return "", &ports.BlobAdapterPutError{Err: err}
+	}
+
+	return digest, nil
+}
+
+func (a *BlobS3Adapter) Get(ctx context.Context, digest fields.Digest) (io.ReadCloser, int64, error) {
+	info, exists, err := a.stat(ctx, digest)
+	if err != nil {
+		return nil, 0, &ports.BlobAdapterGetError{Digest: digest, Err: err}
+	}
+	if !exists {
+		return nil, 0, &ports.BlobAdapterNotFoundError{Digest: digest}
+	}
+
+	obj, err := a.client.GetObject(ctx, a.bucket, a.key(digest), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, &ports.BlobAdapterGetError{Digest: digest, Err: err}
+	}
+
+	return obj, info.Size, nil
+}
+
+func (a *BlobS3Adapter) Delete(ctx context.Context, digest fields.Digest) error {
+	_, exists, err := a.stat(ctx, digest)
+	if err != nil {
+		return &ports.BlobAdapterDeleteError{Digest: digest, Err: err}
+	}
+	if !exists {
+		return &ports.BlobAdapterNotFoundError{Digest: digest}
+	}
+
+	if err := a.client.RemoveObject(ctx, a.bucket, a.key(digest), minio.RemoveObjectOptions{}); err != nil {
+		return &ports.BlobAdapterDeleteError{Digest: digest, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// fakeS3 is an in-process stand-in for an S3 compatible object storage, serving the path style requests of the
+// minio client to buckets and objects kept in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, string) {
+	t.Helper()
+
+	s := &fakeS3{buckets: map[string]map[string][]byte{}}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return s, strings.TrimPrefix(server.URL, "http://")
+}
+
+func (s *fakeS3) object(bucket string, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.buckets[bucket][key]
+	return content, ok
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, ok := s.buckets[bucket]
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !ok {
+				writeFakeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+			}
+		case http.MethodPut:
+			if ok {
+				writeFakeS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou")
+				return
+			}
+			s.buckets[bucket] = map[string][]byte{}
+		default:
+			writeFakeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+		}
+		return
+	}
+
+	if !ok {
+		writeFakeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		content, err := readFakeS3Body(r)
+		if err != nil {
+			writeFakeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		objects[key] = content
+		w.Header().Set("ETag", fakeS3ETag(content))
+	case http.MethodHead, http.MethodGet:
+		content, ok := objects[key]
+		if !ok {
+			writeFakeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", fakeS3ETag(content))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, key, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), bytes.NewReader(content))
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// readFakeS3Body returns the object content of a PUT request, which the minio client sends in signed chunks
+// over plain HTTP.
+func readFakeS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var content []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		content = append(content, chunk[:size]...)
+	}
+}
+
+func writeFakeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func fakeS3ETag(content []byte) string {
+	sum := md5.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func newTestBlobS3Adapter(t *testing.T, endpoint string, prefix string) *BlobS3Adapter {
+	t.Helper()
+
+	adapter, err := NewBlobS3Adapter(context.Background(), BlobS3Config{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    "noxite",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Prefix:    prefix,
+	})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	return adapter
+}
+
+func TestBlobS3Adapter(t *testing.T) {
+	ctx := context.Background()
+	s3, endpoint := newFakeS3(t)
+	adapter := newTestBlobS3Adapter(t, endpoint, "blobs/")
+
+	content := []byte("tarball content")
+
+	digest, err := adapter.Put(ctx, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	h := sha256.New()
+	h.Write(content)
+	if want := fields.DigestFromHash(h); digest != want {
+		t.Fatalf("expected digest %s, got %s", want, digest)
+	}
+
+	if stored, ok := s3.object("noxite", "blobs/sha256/"+digest.Hex()); !ok || !bytes.Equal(stored, content) {
+		t.Fatalf("expected the content under the prefixed key, got %q", stored)
+	}
+
+	// storing the same content again is not an error
+	again, err := adapter.Put(ctx, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to put blob again: %v", err)
+	}
+	if again != digest {
+		t.Fatalf("expected digest %s, got %s", digest, again)
+	}
+
+	r, size, err := adapter.Get(ctx, digest)
+	if err != nil {
+		t.Fatalf("failed to get blob: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if size != int64(len(content)) || !bytes.Equal(got, content) {
+		t.Fatalf("expected %q of size %d, got %q of size %d", content, len(content), got, size)
+	}
+
+	if err := adapter.Delete(ctx, digest); err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+
+	var notFound *ports.BlobAdapterNotFoundError
+	if _, _, err := adapter.Get(ctx, digest); !errors.As(err, &notFound) {
+		t.Fatalf("expected BlobAdapterNotFoundError after delete, got %v", err)
+	}
+	if err := adapter.Delete(ctx, digest); !errors.As(err, &notFound) {
+		t.Fatalf("expected BlobAdapterNotFoundError on second delete, got %v", err)
+	}
+}
+
+func TestBlobS3AdapterExistingBucket(t *testing.T) {
+	ctx := context.Background()
+	_, endpoint := newFakeS3(t)
+
+	first := newTestBlobS3Adapter(t, endpoint, "")
+	digest, err := first.Put(ctx, strings.NewReader("kept"))
+	if err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+
+	// connecting again must reuse the bucket and its content
+	second := newTestBlobS3Adapter(t, endpoint, "")
+	r, _, err := second.Get(ctx, digest)
+	if err != nil {
+		t.Fatalf("expected blob in existing bucket, got %v", err)
+	}
+	r.Close()
+}
@@ -0,0 +1,247 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GehirnInc/crypt"
+	_ "github.com/GehirnInc/crypt/apr1_crypt"
+	_ "github.com/GehirnInc/crypt/md5_crypt"
+	_ "github.com/GehirnInc/crypt/sha256_crypt"
+	_ "github.com/GehirnInc/crypt/sha512_crypt"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HtpasswdConfig configures the htpasswd adapter.
+type HtpasswdConfig struct {
+	// Path is the htpasswd file, e.g. the one of a Verdaccio installation.
+	Path string
+	// DefaultRoleID is the role of users created on their first login.
+	DefaultRoleID fields.EntityID
+	// EmailDomain completes the email address of users created on their first login, since htpasswd files hold none.
+	EmailDomain string
+}
+
+// HtpasswdAdapter authenticates users against an htpasswd file, which is reloaded when it changes.
+// It verifies bcrypt, {SHA}, DES crypt, MD5 ($apr1$, $1$) and SHA crypt ($5$, $6$) entries and appends bcrypt entries.
+type HtpasswdAdapter struct {
+	config HtpasswdConfig
+
+	mu      sync.Mutex
+	entries map[string]string
+	modTime time.Time
+	size    int64
+}
+
+var _ ports.ExternalAuthPort = (*HtpasswdAdapter)(nil)
+var _ ports.ExternalRegistrationPort = (*HtpasswdAdapter)(nil)
+
+// NewHtpasswdAdapter loads the htpasswd file, which has to exist.
+func NewHtpasswdAdapter(config HtpasswdConfig) (*HtpasswdAdapter, error) {
+	if config.EmailDomain == "" {
+		config.EmailDomain = "localhost"
+	}
+
+	a := &HtpasswdAdapter{
+		config: config,
+	}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *HtpasswdAdapter) Authenticate(ctx context.Context, username fields.Username, password string) (*entities.ExternalIdentity, error) {
+	a.mu.Lock()
+	err := a.reload()
+	hash, ok := a.entries[username.String()]
+	a.mu.Unlock()
+
+	if err != nil {
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      err,
+		}
+	}
+	if !ok {
+		return nil, &ports.ExternalAuthUserNotFoundError{
+			Username: username,
+		}
+	}
+
+	match, err := verifyHtpasswdHash(hash, password)
+	if err != nil {
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      err,
+		}
+	}
+	if !match {
+		return nil, &ports.ExternalAuthInvalidCredentialsError{
+			Username: username,
+		}
+	}
+
+	email, err := fields.EmailFromString(username.String() + "@" + a.config.EmailDomain)
+	if err != nil {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   fmt.Sprintf("no valid email address in domain %s", a.config.EmailDomain),
+		}
+	}
+
+	return &entities.ExternalIdentity{
+		Username:      username,
+		Email:         email,
+		RoleID:        a.config.DefaultRoleID,
+		Source:        "htpasswd",
+		ProvisionOnly: true,
+	}, nil
+}
+
+// Register appends a bcrypt entry for the user to the htpasswd file.
+func (a *HtpasswdAdapter) Register(ctx context.Context, username fields.Username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return &ports.ExternalAuthError{
+			Username: username,
+			Err:      err,
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.reload(); err != nil {
+		return &ports.ExternalAuthError{
+			Username: username,
+			Err:      err,
+		}
+	}
+	if _, ok := a.entries[username.String()]; ok {
+		return &ports.ExternalAuthUserExistsError{
+			Username: username,
+		}
+	}
+
+	if err := a.appendEntry(username.String(), string(hash)); err != nil {
+		return &ports.ExternalAuthError{
+			Username: username,
+			Err:      fmt.Errorf("failed to append to %s: %w", a.config.Path, err),
+		}
+	}
+	a.entries[username.String()] = string(hash)
+
+	// the file changed, so the next reload reads it again and picks up concurrent edits
+	a.modTime = time.Time{}
+	return nil
+}
+
+// helpers
+
+// reload reads the file if its modification time or size changed. The caller holds mu.
+func (a *HtpasswdAdapter) reload() error {
+	info, err := os.Stat(a.config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", a.config.Path, err)
+	}
+	if a.entries != nil && info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return nil
+	}
+
+	content, err := os.ReadFile(a.config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", a.config.Path, err)
+	}
+
+	a.entries = parseHtpasswd(content)
+	a.modTime = info.ModTime()
+	a.size = info.Size()
+	return nil
+}
+
+func (a *HtpasswdAdapter) appendEntry(username string, hash string) error {
+	f, err := os.OpenFile(a.config.Path, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line := username + ":" + hash + "\n"
+
+	// files edited by hand may lack the final newline
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+			return err
+		}
+		if last[0] != '\n' {
+			line = "\n" + line
+		}
+	}
+
+	if _, err := f.WriteString(line); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// parseHtpasswd returns the hashes by username. Verdaccio appends comments as a third field, which are ignored.
+func parseHtpasswd(content []byte) map[string]string {
+	entries := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+		entries[parts[0]] = parts[1]
+	}
+
+	return entries
+}
+
+func verifyHtpasswdHash(hash string, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hash[len("{SHA}"):]), []byte(base64.StdEncoding.EncodeToString(sum[:]))) == 1, nil
+	case crypt.IsHashSupported(hash):
+		err := crypt.NewFromHash(hash).Verify(hash, []byte(password))
+		if err == crypt.ErrKeyMismatch {
+			return false, nil
+		}
+		return err == nil, err
+	case isDESCryptHash(hash):
+		return subtle.ConstantTimeCompare([]byte(desCrypt(password, hash[:2])), []byte(hash)) == 1, nil
+	default:
+		return false, fmt.Errorf("unsupported htpasswd hash format")
+	}
+}
@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/invite"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+type InviteEntAdapter struct {
+	entClient *ent.Client
+}
+
+var _ ports.InvitePort = (*InviteEntAdapter)(nil)
+
+func NewInviteEntAdapter(entClient *ent.Client) *InviteEntAdapter {
+	return &InviteEntAdapter{
+		entClient: entClient,
+	}
+}
+
+func (a *InviteEntAdapter) CreateInvite(ctx context.Context, input ports.CreateInviteInput) (fields.EntityID, error) {
+	create := a.entClient.Invite.Create().
+		SetEmail(input.Email.String()).
+		SetCreatedBy(input.CreatedBy.Int()).
+		SetNillableExpiresAt(input.ExpiresAt)
+	if input.RoleID != nil {
+		create.SetRoleID(input.RoleID.Int())
+	}
+
+	inv, err := create.Save(ctx)
+	if err != nil {
+		return fields.EntityID(0), &ports.InviteAdapterCreateInviteError{
+			Email: input.Email,
+			Err:   err,
+		}
+	}
+
+	id, err := fields.EntityIDFromInt(inv.ID)
+	if err != nil {
+		return fields.EntityID(0), &ports.InviteAdapterCreateInviteError{
+			Email: input.Email,
+			Err:   err,
+		}
+	}
+
+	return id, nil
+}
+
+func (a *InviteEntAdapter) GetInvitesByEmail(ctx context.Context, email fields.Email) ([]*entities.Invite, error) {
+	invites, err := a.entClient.Invite.Query().
+		Where(invite.EmailEqualFold(email.String())).
+		All(ctx)
+	if err != nil {
+		return nil, &ports.InviteAdapterGetInvitesError{
+			Email: email,
+			Err:   err,
+		}
+	}
+
+	result := make([]*entities.Invite, 0, len(invites))
+	for _, inv := range invites {
+		converted, err := inviteFromEntInvite(inv)
+		if err != nil {
+			return nil, &ports.InviteAdapterGetInvitesError{
+				Email: email,
+				Err:   err,
+			}
+		}
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+func (a *InviteEntAdapter) UseInvite(ctx context.Context, inviteID fields.EntityID, userID fields.EntityID) error {
+	// the used_at condition keeps an invite from being used by two concurrent signups
+	updated, err := a.entClient.Invite.Update().
+		Where(invite.ID(inviteID.Int()), invite.UsedAtIsNil()).
+		SetUsedAt(time.Now()).
+		SetUsedBy(userID.Int()).
+		Save(ctx)
+	if err != nil {
+		return &ports.InviteAdapterUseInviteError{
+			ID:  inviteID,
+			Err: err,
+		}
+	}
+
+	if updated == 0 {
+		return &ports.InviteAdapterInviteNotFoundError{
+			ID: inviteID,
+		}
+	}
+
+	return nil
+}
+
+func inviteFromEntInvite(inv *ent.Invite) (*entities.Invite, error) {
+	id, err := fields.EntityIDFromInt(inv.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invite id: %w", err)
+	}
+
+	createdBy, err := fields.EntityIDFromInt(inv.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invite creator: %w", err)
+	}
+
+	var roleID *fields.EntityID
+	if inv.RoleID != nil {
+		id, err := fields.EntityIDFromInt(*inv.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid invite role: %w", err)
+		}
+		roleID = &id
+	}
+
+	return &entities.Invite{
+		ID:        id,
+		Email:     fields.Email(inv.Email),
+		RoleID:    roleID,
+		CreatedBy: createdBy,
+		ExpiresAt: inv.ExpiresAt,
+		UsedAt:    inv.UsedAt,
+		CreatedAt: inv.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,231 @@
+package adapters
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// LDAPConfig configures the LDAP adapter. The defaults of NewLDAPAdapter fit OpenLDAP,
+// Active Directory needs a UserFilter like "(sAMAccountName=%s)".
+type LDAPConfig struct {
+	// URL is the ldap:// or ldaps:// URL of the server.
+	URL string
+	// StartTLS upgrades ldap:// connections to TLS.
+	StartTLS bool
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool
+	// BindDN and BindPassword are the service account used to search users, both empty search anonymously.
+	BindDN       string
+	BindPassword string
+	// BaseDN is the subtree that is searched for users.
+	BaseDN string
+	// UserFilter finds the entry of a user, %s is replaced with the escaped username.
+	UserFilter string
+	// EmailAttribute holds the email address of a user.
+	EmailAttribute string
+	// GroupAttribute holds the DNs of the groups of a user.
+	GroupAttribute string
+	// RoleRules map groups to roles, the first rule matching a group of the user wins.
+	RoleRules []LDAPRoleRule
+	// DefaultRoleID is the role of users without a matching group. Zero rejects those users.
+	DefaultRoleID fields.EntityID
+	// Timeout limits connecting and every request.
+	Timeout time.Duration
+}
+
+// LDAPRoleRule maps the members of a group to a role.
+type LDAPRoleRule struct {
+	// GroupDN is compared case-insensitively with the groups of the user.
+	GroupDN string
+	RoleID  fields.EntityID
+}
+
+// LDAPAdapter authenticates users by binding with their password against an LDAP directory or Active Directory.
+type LDAPAdapter struct {
+	config LDAPConfig
+	// dial connects to the directory, an in-process directory can stand in for it.
+	dial func(config LDAPConfig) (ldap.Client, error)
+}
+
+var _ ports.ExternalAuthPort = (*LDAPAdapter)(nil)
+
+func NewLDAPAdapter(config LDAPConfig) *LDAPAdapter {
+	if config.UserFilter == "" {
+		config.UserFilter = "(uid=%s)"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.GroupAttribute == "" {
+		config.GroupAttribute = "memberOf"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &LDAPAdapter{
+		config: config,
+		dial:   dialLDAP,
+	}
+}
+
+func (a *LDAPAdapter) Authenticate(ctx context.Context, username fields.Username, password string) (*entities.ExternalIdentity, error) {
+	// an empty password is an unauthenticated bind, which succeeds for every DN
+	if password == "" {
+		return nil, &ports.ExternalAuthInvalidCredentialsError{
+			Username: username,
+		}
+	}
+
+	conn, err := a.dial(a.config)
+	if err != nil {
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      err,
+		}
+	}
+	defer conn.Close()
+
+	if a.config.BindDN != "" {
+		err = conn.Bind(a.config.BindDN, a.config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      fmt.Errorf("failed to bind service account: %w", err),
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(a.config.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(a.config.UserFilter, ldap.EscapeFilter(username.String())),
+		[]string{a.config.EmailAttribute, a.config.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      fmt.Errorf("failed to search user: %w", err),
+		}
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return nil, &ports.ExternalAuthUserNotFoundError{
+			Username: username,
+		}
+	case 1:
+	default:
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      fmt.Errorf("user filter matches %d entries", len(result.Entries)),
+		}
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, &ports.ExternalAuthInvalidCredentialsError{
+				Username: username,
+			}
+		}
+		return nil, &ports.ExternalAuthError{
+			Username: username,
+			Err:      fmt.Errorf("failed to bind user: %w", err),
+		}
+	}
+
+	email, err := fields.EmailFromString(entry.GetAttributeValue(a.config.EmailAttribute))
+	if err != nil {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   fmt.Sprintf("attribute %s holds no valid email address", a.config.EmailAttribute),
+		}
+	}
+
+	roleID, ok := a.roleOfGroups(entry.GetAttributeValues(a.config.GroupAttribute))
+	if !ok {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   "no group of the user maps to a role",
+		}
+	}
+
+	return &entities.ExternalIdentity{
+		Username: username,
+		Email:    email,
+		RoleID:   roleID,
+		Source:   "ldap",
+	}, nil
+}
+
+// helpers
+
+func (a *LDAPAdapter) roleOfGroups(groups []string) (fields.EntityID, bool) {
+	for _, rule := range a.config.RoleRules {
+		for _, group := range groups {
+			if strings.EqualFold(normalizeDN(group), normalizeDN(rule.GroupDN)) {
+				return rule.RoleID, true
+			}
+		}
+	}
+	if a.config.DefaultRoleID != 0 {
+		return a.config.DefaultRoleID, true
+	}
+	return fields.EntityID(0), false
+}
+
+// normalizeDN removes the spaces after the separators of a DN, which directories return inconsistently.
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func dialLDAP(config LDAPConfig) (ldap.Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", config.URL, err)
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	conn, err := ldap.DialURL(config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.URL, err)
+	}
+	conn.SetTimeout(config.Timeout)
+
+	if config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,328 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// LDAP result codes and operations of RFC 4511 the fake directory uses.
+const (
+	ldapResultSuccess            = 0
+	ldapResultInvalidCredentials = 49
+
+	ldapBindRequest      = 0
+	ldapBindResponse     = 1
+	ldapUnbindRequest    = 2
+	ldapSearchRequest    = 3
+	ldapSearchResultItem = 4
+	ldapSearchResultDone = 5
+
+	ldapFilterEqualityMatch = 3
+)
+
+type fakeLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// fakeLDAP is an in-process LDAP server answering the simple binds and equality searches of the LDAP adapter.
+type fakeLDAP struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []fakeLDAPEntry
+}
+
+func newFakeLDAP(t *testing.T, entries ...fakeLDAPEntry) *fakeLDAP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &fakeLDAP{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeLDAP) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeLDAP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldapBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := int64(ldapResultInvalidCredentials)
+			if dn == "" && password == "" || s.checkPassword(dn, password) {
+				code = ldapResultSuccess
+			}
+			conn.Write(fakeLDAPResult(messageID, ldapBindResponse, code).Bytes())
+		case ldapSearchRequest:
+			for _, entry := range s.search(op.Children[6]) {
+				conn.Write(fakeLDAPSearchEntry(messageID, entry).Bytes())
+			}
+			conn.Write(fakeLDAPResult(messageID, ldapSearchResultDone, ldapResultSuccess).Bytes())
+		case ldapUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *fakeLDAP) checkPassword(dn string, password string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if entry.dn == dn {
+			return entry.password != "" && entry.password == password
+		}
+	}
+	return false
+}
+
+// search returns the entries matching an equality filter like "(uid=alice)", other filters match nothing.
+func (s *fakeLDAP) search(filter *ber.Packet) []fakeLDAPEntry {
+	if filter.Tag != ldapFilterEqualityMatch || len(filter.Children) != 2 {
+		return nil
+	}
+	attribute := filter.Children[0].Data.String()
+	value := filter.Children[1].Data.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []fakeLDAPEntry
+	for _, entry := range s.entries {
+		for _, v := range entry.attributes[attribute] {
+			if strings.EqualFold(v, value) {
+				matches = append(matches, entry)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func fakeLDAPMessage(messageID int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+func fakeLDAPResult(messageID int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return fakeLDAPMessage(messageID, op)
+}
+
+func fakeLDAPSearchEntry(messageID int64, entry fakeLDAPEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultItem, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "Object Name"))
+
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+
+	return fakeLDAPMessage(messageID, op)
+}
+
+func newTestLDAPDirectory(t *testing.T) *fakeLDAP {
+	return newFakeLDAP(t,
+		fakeLDAPEntry{
+			dn:       "cn=service,dc=example,dc=com",
+			password: "service-secret",
+		},
+		fakeLDAPEntry{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice-secret",
+			attributes: map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@example.com"},
+				"memberOf": {"cn=users,dc=example,dc=com", "cn=admins, dc=example, dc=com"},
+			},
+		},
+		fakeLDAPEntry{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob-secret",
+			attributes: map[string][]string{
+				"uid":  {"bob"},
+				"mail": {"bob@example.com"},
+			},
+		},
+	)
+}
+
+func newTestLDAPAdapter(url string, defaultRoleID fields.EntityID) *LDAPAdapter {
+	return NewLDAPAdapter(LDAPConfig{
+		URL:          url,
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "service-secret",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		RoleRules: []LDAPRoleRule{
+			{GroupDN: "cn=admins,dc=example,dc=com", RoleID: 1},
+			{GroupDN: "cn=users,dc=example,dc=com", RoleID: 2},
+		},
+		DefaultRoleID: defaultRoleID,
+		Timeout:       5 * time.Second,
+	})
+}
+
+func TestLDAPAdapterAuthenticate(t *testing.T) {
+	directory := newTestLDAPDirectory(t)
+	adapter := newTestLDAPAdapter(directory.URL(), 0)
+
+	identity, err := adapter.Authenticate(context.Background(), fields.Username("alice"), "alice-secret")
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+
+	if identity.Username != "alice" || identity.Email != "alice@example.com" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	// the first matching rule wins, regardless of the spaces in the DN of the group
+	if identity.RoleID != 1 {
+		t.Fatalf("expected role 1 of the admins group, got %d", identity.RoleID)
+	}
+	if identity.Source != "ldap" {
+		t.Fatalf("expected source ldap, got %q", identity.Source)
+	}
+}
+
+func TestLDAPAdapterErrors(t *testing.T) {
+	directory := newTestLDAPDirectory(t)
+
+	tests := []struct {
+		name     string
+		adapter  *LDAPAdapter
+		username string
+		password string
+		check    func(err error) bool
+	}{
+		{
+			name:     "wrong password",
+			adapter:  newTestLDAPAdapter(directory.URL(), 0),
+			username: "alice",
+			password: "wrong",
+			check:    isError[*ports.ExternalAuthInvalidCredentialsError],
+		},
+		{
+			name:     "empty password",
+			adapter:  newTestLDAPAdapter(directory.URL(), 0),
+			username: "alice",
+			password: "",
+			check:    isError[*ports.ExternalAuthInvalidCredentialsError],
+		},
+		{
+			name:     "unknown user",
+			adapter:  newTestLDAPAdapter(directory.URL(), 0),
+			username: "carol",
+			password: "carol-secret",
+			check:    isError[*ports.ExternalAuthUserNotFoundError],
+		},
+		{
+			name:     "no group maps to a role",
+			adapter:  newTestLDAPAdapter(directory.URL(), 0),
+			username: "bob",
+			password: "bob-secret",
+			check:    isError[*ports.ExternalAuthNotAllowedError],
+		},
+		{
+			name: "wrong service account password",
+			adapter: NewLDAPAdapter(LDAPConfig{
+				URL:          directory.URL(),
+				BindDN:       "cn=service,dc=example,dc=com",
+				BindPassword: "wrong",
+				BaseDN:       "ou=people,dc=example,dc=com",
+			}),
+			username: "alice",
+			password: "alice-secret",
+			check:    isError[*ports.ExternalAuthError],
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.adapter.Authenticate(context.Background(), fields.Username(test.username), test.password)
+			if !test.check(err) {
+				t.Fatalf("unexpected error %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestLDAPAdapterDefaultRole(t *testing.T) {
+	directory := newTestLDAPDirectory(t)
+	adapter := newTestLDAPAdapter(directory.URL(), 3)
+
+	identity, err := adapter.Authenticate(context.Background(), fields.Username("bob"), "bob-secret")
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if identity.RoleID != 3 {
+		t.Fatalf("expected default role 3, got %d", identity.RoleID)
+	}
+}
+
+func TestLDAPAdapterUnavailable(t *testing.T) {
+	directory := newTestLDAPDirectory(t)
+	url := directory.URL()
+	directory.listener.Close()
+
+	_, err := newTestLDAPAdapter(url, 0).Authenticate(context.Background(), fields.Username("alice"), "alice-secret")
+	if !isError[*ports.ExternalAuthError](err) {
+		t.Fatalf("expected ExternalAuthError, got %T: %v", err, err)
+	}
+}
+
+func isError[T error](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// loginThrottleMaxEntries bounds the entries kept in memory, so failed logins with ever new names can't exhaust it.
+const loginThrottleMaxEntries = 1 << 16
+
+// LoginThrottleMemoryAdapter keeps failed logins and blocks in memory, which suits single instance setups.
+// They are lost on restart.
+type LoginThrottleMemoryAdapter struct {
+	mu         sync.Mutex
+	entries    map[string]*loginThrottleEntry
+	maxEntries int
+}
+
+var _ ports.LoginThrottlePort = (*LoginThrottleMemoryAdapter)(nil)
+
+func NewLoginThrottleMemoryAdapter() *LoginThrottleMemoryAdapter {
+	return &LoginThrottleMemoryAdapter{
+		entries:    make(map[string]*loginThrottleEntry),
+		maxEntries: loginThrottleMaxEntries,
+	}
+}
+
+type loginThrottleEntry struct {
+	failures          int
+	failuresExpiresAt time.Time
+	blockedUntil      time.Time
+	usedAt            time.Time
+}
+
+// expired reports whether the entry holds neither failures nor a block anymore.
+func (e *loginThrottleEntry) expired(now time.Time) bool {
+	return !e.failuresExpiresAt.After(now) && !e.blockedUntil.After(now)
+}
+
+func (a *LoginThrottleMemoryAdapter) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	entry := a.entry(key, now)
+	if !entry.failuresExpiresAt.After(now) {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.failuresExpiresAt = now.Add(window)
+
+	return entry.failures, nil
+}
+
+func (a *LoginThrottleMemoryAdapter) Block(ctx context.Context, key string, until time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entry(key, time.Now()).blockedUntil = until
+	return nil
+}
+
+func (a *LoginThrottleMemoryAdapter) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.entries[key]
+	if !ok || !entry.blockedUntil.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return entry.blockedUntil, nil
+}
+
+func (a *LoginThrottleMemoryAdapter) Reset(ctx context.Context, key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.entries, key)
+	return nil
+}
+
+// helpers
+
+// entry returns the entry of the key, which is created if it does not exist, and records its use. The caller
+// holds mu.
+func (a *LoginThrottleMemoryAdapter) entry(key string, now time.Time) *loginThrottleEntry {
+	entry, ok := a.entries[key]
+	if !ok {
+		if len(a.entries) >= a.maxEntries {
+			a.evict(now)
+		}
+		entry = &loginThrottleEntry{}
+		a.entries[key] = entry
+	}
+
+	entry.usedAt = now
+	return entry
+}
+
+// evict removes the expired entries and, if more than three quarters of maxEntries remain, the least recently used
+// entries down to that, so evictions stay rare even while all entries are live. Entries of blocked keys are evicted
+// last, since evicting them lifts the block. The caller holds mu.
+func (a *LoginThrottleMemoryAdapter) evict(now time.Time) {
+	for k, entry := range a.entries {
+		if entry.expired(now) {
+			delete(a.entries, k)
+		}
+	}
+
+	keep := a.maxEntries * 3 / 4
+	if len(a.entries) <= keep {
+		return
+	}
+
+	keys := make([]string, 0, len(a.entries))
+	for k := range a.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		x, y := a.entries[keys[i]], a.entries[keys[j]]
+		if xBlocked, yBlocked := x.blockedUntil.After(now), y.blockedUntil.After(now); xBlocked != yBlocked {
+			return yBlocked
+		}
+		return x.usedAt.Before(y.usedAt)
+	})
+
+	for _, k := range keys[:len(keys)-keep] {
+		delete(a.entries, k)
+	}
+}
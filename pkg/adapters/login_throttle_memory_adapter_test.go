@@ -0,0 +1,76 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleMemoryAdapterEviction(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewLoginThrottleMemoryAdapter()
+	adapter.maxEntries = 8
+
+	until := time.Now().Add(time.Hour)
+	if err := adapter.Block(ctx, "account:locked", until); err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+
+	// failed logins with ever new names, none of which expire
+	for i := 0; i < 100; i++ {
+		if _, err := adapter.RegisterFailure(ctx, fmt.Sprintf("account:user%d", i), time.Hour); err != nil {
+			t.Fatalf("failed to register failure: %v", err)
+		}
+		if len(adapter.entries) > adapter.maxEntries {
+			t.Fatalf("expected at most %d entries, got %d", adapter.maxEntries, len(adapter.entries))
+		}
+	}
+
+	blockedUntil, err := adapter.BlockedUntil(ctx, "account:locked")
+	if err != nil {
+		t.Fatalf("failed to get block: %v", err)
+	}
+	if !blockedUntil.Equal(until) {
+		t.Fatalf("expected the block to survive the eviction, got %s", blockedUntil)
+	}
+
+	// the most recently used entries are kept
+	failures, err := adapter.RegisterFailure(ctx, "account:user99", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to register failure: %v", err)
+	}
+	if failures != 2 {
+		t.Fatalf("expected 2 failures of the latest name, got %d", failures)
+	}
+	if _, ok := adapter.entries["account:user0"]; ok {
+		t.Fatalf("expected the least recently used entry to be evicted")
+	}
+}
+
+func TestLoginThrottleMemoryAdapterEvictsExpiredFirst(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewLoginThrottleMemoryAdapter()
+	adapter.maxEntries = 4
+
+	for i := 0; i < 3; i++ {
+		if _, err := adapter.RegisterFailure(ctx, fmt.Sprintf("account:expired%d", i), time.Millisecond); err != nil {
+			t.Fatalf("failed to register failure: %v", err)
+		}
+	}
+	if _, err := adapter.RegisterFailure(ctx, "account:live", time.Hour); err != nil {
+		t.Fatalf("failed to register failure: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := adapter.RegisterFailure(ctx, "account:new", time.Hour); err != nil {
+		t.Fatalf("failed to register failure: %v", err)
+	}
+
+	if len(adapter.entries) != 2 {
+		t.Fatalf("expected the expired entries to be removed, got %d entries", len(adapter.entries))
+	}
+	if _, ok := adapter.entries["account:live"]; !ok {
+		t.Fatalf("expected the live entry to be kept")
+	}
+}
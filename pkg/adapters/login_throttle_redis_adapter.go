@@ -0,0 +1,90 @@
+package adapters
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginThrottleRedisAdapter keeps failed logins and blocks in Redis, so all instances throttle alike.
+// Counters and blocks expire by themselves.
+type LoginThrottleRedisAdapter struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ ports.LoginThrottlePort = (*LoginThrottleRedisAdapter)(nil)
+
+func NewLoginThrottleRedisAdapter(client *redis.Client) *LoginThrottleRedisAdapter {
+	return &LoginThrottleRedisAdapter{
+		client: client,
+		prefix: "login_throttle:",
+	}
+}
+
+func (a *LoginThrottleRedisAdapter) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures *redis.IntCmd
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(ctx, a.failuresKey(key))
+		pipe.PExpire(ctx, a.failuresKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, &ports.LoginThrottleAdapterRegisterFailureError{Key: key, Err: err}
+	}
+
+	return int(failures.Val()), nil
+}
+
+func (a *LoginThrottleRedisAdapter) Block(ctx context.Context, key string, until time.Time) error {
+	if !until.After(time.Now()) {
+		return nil
+	}
+
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, a.blockKey(key), until.UnixMilli(), 0)
+		pipe.PExpireAt(ctx, a.blockKey(key), until)
+		return nil
+	})
+	if err != nil {
+		return &ports.LoginThrottleAdapterBlockError{Key: key, Err: err}
+	}
+
+	return nil
+}
+
+func (a *LoginThrottleRedisAdapter) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	value, err := a.client.Get(ctx, a.blockKey(key)).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, &ports.LoginThrottleAdapterGetBlockError{Key: key, Err: err}
+	}
+
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, &ports.LoginThrottleAdapterGetBlockError{Key: key, Err: err}
+	}
+
+	return time.UnixMilli(until), nil
+}
+
+func (a *LoginThrottleRedisAdapter) Reset(ctx context.Context, key string) error {
+	if err := a.client.Del(ctx, a.failuresKey(key), a.blockKey(key)).Err(); err != nil {
+		return &ports.LoginThrottleAdapterResetError{Key: key, Err: err}
+	}
+	return nil
+}
+
+// helpers
+
+func (a *LoginThrottleRedisAdapter) failuresKey(key string) string {
+	return a.prefix + "failures:" + key
+}
+
+func (a *LoginThrottleRedisAdapter) blockKey(key string) string {
+	return a.prefix + "block:" + key
+}
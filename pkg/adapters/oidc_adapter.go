@@ -0,0 +1,190 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig configures the OpenID Connect adapter.
+type OIDCConfig struct {
+	// IssuerURL is the issuer whose discovery document is loaded by NewOIDCAdapter.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback of the registry, /-/v1/login/sso/callback.
+	RedirectURL string
+	// Scopes default to openid, profile and email.
+	Scopes []string
+	// UsernameClaim names the local user. It must not be editable by the users themselves,
+	// otherwise they can take over other accounts.
+	UsernameClaim string
+	EmailClaim    string
+	// GroupsClaim holds the groups of the user as a list of strings.
+	GroupsClaim string
+	// RoleRules map groups to roles, the first rule matching a group of the user wins.
+	RoleRules []OIDCRoleRule
+	// DefaultRoleID is the role of users without a matching group. Zero rejects those users.
+	DefaultRoleID fields.EntityID
+}
+
+// OIDCRoleRule maps the members of a group to a role.
+type OIDCRoleRule struct {
+	Group  string
+	RoleID fields.EntityID
+}
+
+// OIDCAdapter logs users in with the authorization code flow and PKCE of an OpenID Connect provider.
+type OIDCAdapter struct {
+	config   OIDCConfig
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var _ ports.IdentityProviderPort = (*OIDCAdapter)(nil)
+
+// NewOIDCAdapter loads the discovery document of the issuer, so the issuer has to be reachable on startup.
+func NewOIDCAdapter(ctx context.Context, config OIDCConfig) (*OIDCAdapter, error) {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover issuer %s: %w", config.IssuerURL, err)
+	}
+
+	return &OIDCAdapter{
+		config: config,
+		oauth2: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       config.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+func (a *OIDCAdapter) AuthCodeURL(state string, nonce string, codeChallenge string) string {
+	return a.oauth2.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+func (a *OIDCAdapter) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*entities.ExternalIdentity, error) {
+	token, err := a.oauth2.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, &ports.IdentityProviderExchangeError{Err: err}
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, &ports.IdentityProviderExchangeError{Err: fmt.Errorf("token response holds no id_token")}
+	}
+
+	idToken, err := a.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, &ports.IdentityProviderExchangeError{Err: err}
+	}
+	if idToken.Nonce != nonce {
+		return nil, &ports.IdentityProviderExchangeError{Err: fmt.Errorf("nonce of the id token does not match")}
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, &ports.IdentityProviderExchangeError{Err: err}
+	}
+
+	name, _ := claims[a.config.UsernameClaim].(string)
+	username, err := fields.UsernameFromString(name)
+	if err != nil {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: fields.Username(name),
+			Reason:   fmt.Sprintf("claim %s holds no valid username", a.config.UsernameClaim),
+		}
+	}
+
+	// providers omit email_verified if they verify every address
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   "the email address is not verified",
+		}
+	}
+
+	mail, _ := claims[a.config.EmailClaim].(string)
+	email, err := fields.EmailFromString(mail)
+	if err != nil {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   fmt.Sprintf("claim %s holds no valid email address", a.config.EmailClaim),
+		}
+	}
+
+	roleID, ok := a.roleOfGroups(claimStrings(claims[a.config.GroupsClaim]))
+	if !ok {
+		return nil, &ports.ExternalAuthNotAllowedError{
+			Username: username,
+			Reason:   "no group of the user maps to a role",
+		}
+	}
+
+	return &entities.ExternalIdentity{
+		Username: username,
+		Email:    email,
+		RoleID:   roleID,
+		Source:   "oidc",
+	}, nil
+}
+
+// helpers
+
+func (a *OIDCAdapter) roleOfGroups(groups []string) (fields.EntityID, bool) {
+	for _, rule := range a.config.RoleRules {
+		for _, group := range groups {
+			if group == rule.Group {
+				return rule.RoleID, true
+			}
+		}
+	}
+	if a.config.DefaultRoleID != 0 {
+		return a.config.DefaultRoleID, true
+	}
+	return fields.EntityID(0), false
+}
+
+// claimStrings returns the strings of a claim, which is a list or a single string.
+func claimStrings(claim any) []string {
+	switch c := claim.(type) {
+	case string:
+		return []string{c}
+	case []any:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,354 @@
+package adapters
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+const testOIDCClientID = "noxite"
+
+// mockIssuer is an in-process OpenID Connect provider. It serves the discovery document, the signing keys and a token
+// endpoint redeeming the codes issued with issueCode for ID tokens with the given claims.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]mockIssuerCode
+	lastCode int
+}
+
+type mockIssuerCode struct {
+	challenge string
+	claims    map[string]any
+	// key signs the ID token, the key of the issuer if nil
+	key *rsa.PrivateKey
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m := &mockIssuer{key: key, codes: map[string]mockIssuerCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/keys", m.keys)
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockIssuer) URL() string {
+	return m.server.URL
+}
+
+// issueCode returns an authorization code for the PKCE challenge, which is redeemed for an ID token with the claims.
+func (m *mockIssuer) issueCode(challenge string, claims map[string]any, key *rsa.PrivateKey) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastCode++
+	code := fmt.Sprintf("code-%d", m.lastCode)
+	m.codes[code] = mockIssuerCode{challenge: challenge, claims: claims, key: key}
+	return code
+}
+
+func (m *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                m.URL(),
+		"authorization_endpoint":                m.URL() + "/authorize",
+		"token_endpoint":                        m.URL() + "/token",
+		"jwks_uri":                              m.URL() + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (m *mockIssuer) keys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{Key: &m.key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"}},
+	})
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	code, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	key := code.key
+	if key == nil {
+		key = m.key
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "test"}}, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss": m.URL(),
+		"aud": testOIDCClientID,
+		"sub": "subject",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range code.claims {
+		claims[name] = value
+	}
+
+	idToken, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func newTestOIDCAdapter(t *testing.T, issuer *mockIssuer) *OIDCAdapter {
+	t.Helper()
+
+	adapter, err := NewOIDCAdapter(context.Background(), OIDCConfig{
+		IssuerURL:    issuer.URL(),
+		ClientID:     testOIDCClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://registry.example.com/-/v1/login/sso/callback",
+		RoleRules: []OIDCRoleRule{
+			{Group: "admins", RoleID: 1},
+			{Group: "developers", RoleID: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	return adapter
+}
+
+func testPKCE() (verifier string, challenge string) {
+	verifier = "verifier-of-at-least-43-characters-for-pkce-tests"
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOIDCAdapterAuthCodeURL(t *testing.T) {
+	adapter := newTestOIDCAdapter(t, newMockIssuer(t))
+	_, challenge := testPKCE()
+
+	authURL, err := url.Parse(adapter.AuthCodeURL("state", "nonce", challenge))
+	if err != nil {
+		t.Fatalf("invalid authorization url: %v", err)
+	}
+
+	query := authURL.Query()
+	expected := map[string]string{
+		"client_id":             testOIDCClientID,
+		"redirect_uri":          "http://registry.example.com/-/v1/login/sso/callback",
+		"response_type":         "code",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        challenge,
+		"code_challenge_method": "S256",
+	}
+	for param, value := range expected {
+		if got := query.Get(param); got != value {
+			t.Errorf("expected %s %q, got %q", param, value, got)
+		}
+	}
+}
+
+func TestOIDCAdapterExchange(t *testing.T) {
+	issuer := newMockIssuer(t)
+	adapter := newTestOIDCAdapter(t, issuer)
+	verifier, challenge := testPKCE()
+
+	code := issuer.issueCode(challenge, map[string]any{
+		"nonce":              "nonce",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"groups":             []string{"users", "developers", "admins"},
+	}, nil)
+
+	identity, err := adapter.Exchange(context.Background(), code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.Source != "oidc" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	// the first matching rule wins
+	if identity.RoleID != 1 {
+		t.Fatalf("expected role 1 of the admins group, got %d", identity.RoleID)
+	}
+}
+
+func TestOIDCAdapterExchangeErrors(t *testing.T) {
+	issuer := newMockIssuer(t)
+	adapter := newTestOIDCAdapter(t, issuer)
+	verifier, challenge := testPKCE()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	valid := map[string]any{
+		"nonce":              "nonce",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             "developers",
+	}
+	with := func(name string, value any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		key      *rsa.PrivateKey
+		verifier string
+		nonce    string
+		check    func(err error) bool
+	}{
+		{
+			name:     "wrong code verifier",
+			claims:   valid,
+			verifier: "another-verifier-of-at-least-43-characters-for-pkce",
+			nonce:    "nonce",
+			check:    isError[*ports.IdentityProviderExchangeError],
+		},
+		{
+			name:     "wrong nonce",
+			claims:   valid,
+			verifier: verifier,
+			nonce:    "other nonce",
+			check:    isError[*ports.IdentityProviderExchangeError],
+		},
+		{
+			name:     "signed by another key",
+			claims:   valid,
+			key:      otherKey,
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.IdentityProviderExchangeError],
+		},
+		{
+			name:     "expired",
+			claims:   with("exp", time.Now().Add(-time.Minute).Unix()),
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.IdentityProviderExchangeError],
+		},
+		{
+			name:     "other audience",
+			claims:   with("aud", "another-client"),
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.IdentityProviderExchangeError],
+		},
+		{
+			name:     "unverified email",
+			claims:   with("email_verified", false),
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.ExternalAuthNotAllowedError],
+		},
+		{
+			name:     "invalid username",
+			claims:   with("preferred_username", ""),
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.ExternalAuthNotAllowedError],
+		},
+		{
+			name:     "no group maps to a role",
+			claims:   with("groups", []string{"users"}),
+			verifier: verifier,
+			nonce:    "nonce",
+			check:    isError[*ports.ExternalAuthNotAllowedError],
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			code := issuer.issueCode(challenge, test.claims, test.key)
+			_, err := adapter.Exchange(context.Background(), code, test.verifier, test.nonce)
+			if !test.check(err) {
+				t.Fatalf("unexpected error %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestOIDCAdapterCodeIsSingleUse(t *testing.T) {
+	issuer := newMockIssuer(t)
+	adapter := newTestOIDCAdapter(t, issuer)
+	verifier, challenge := testPKCE()
+
+	code := issuer.issueCode(challenge, map[string]any{
+		"nonce":              "nonce",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"developers"},
+	}, nil)
+
+	if _, err := adapter.Exchange(context.Background(), code, verifier, "nonce"); err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+	if _, err := adapter.Exchange(context.Background(), code, verifier, "nonce"); !isError[*ports.IdentityProviderExchangeError](err) {
+		t.Fatalf("expected IdentityProviderExchangeError for a redeemed code, got %T: %v", err, err)
+	}
+}
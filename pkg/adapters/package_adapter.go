@@ -25,8 +25,19 @@ func NewPackageAdapter(usersAdapter ports.UserPort) *PackageAdapter {
 }
 
 func (a *PackageAdapter) ParseManifest(ctx context.Context, r io.Reader) (*entities.PackageVersion, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &ports.PackageAdapterManifestParseError{Err: err}
+	}
+
 	var m manifest
-	if err := json.ConfigDefault.NewDecoder(r).Decode(&m); err != nil {
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, &ports.PackageAdapterManifestParseError{Err: err}
+	}
+
+	// keep the published version manifest to preserve fields without a typed counterpart
+	var raw rawVersions
+	if err := json.Unmarshal(data, &raw); err != nil {
 		return nil, &ports.PackageAdapterManifestParseError{Err: err}
 	}
 
@@ -35,6 +46,8 @@ func (a *PackageAdapter) ParseManifest(ctx context.Context, r io.Reader) (*entit
 		return nil, &ports.PackageAdapterManifestConvertError{Err: err}
 	}
 
+	manifest.RawManifest = raw.Versions[manifest.Version.String()]
+
 	// check if contributors exist
 	contributors := make(fields.MixedAuthors, len(contributorsToCheck))
 	if len(contributorsToCheck) > 0 {
@@ -106,7 +119,26 @@ func (a *PackageAdapter) ParseManifest(ctx context.Context, r io.Reader) (*entit
 	return manifest, nil
 }
 
-func (a *PackageAdapter) SerializeManifest(ctx context.Context, ver *entities.PackageVersion) ([]byte, error) {
-	m := manifestFromPackageVersion(ver.Name, ver)
+func (a *PackageAdapter) ParsePackument(ctx context.Context, r io.Reader) (*entities.PackumentUpdate, error) {
+	var m manifest
+	if err := json.ConfigDefault.NewDecoder(r).Decode(&m); err != nil {
+		return nil, &ports.PackageAdapterManifestParseError{Err: err}
+	}
+
+	update, err := PackumentUpdateFromPackageJSON(m)
+	if err != nil {
+		return nil, &ports.PackageAdapterManifestConvertError{Err: err}
+	}
+
+	return update, nil
+}
+
+func (a *PackageAdapter) SerializeManifest(ctx context.Context, pkg *entities.Package) ([]byte, error) {
+	m := manifestFromPackage(pkg)
+	return json.Marshal(m)
+}
+
+func (a *PackageAdapter) SerializeAbbreviatedManifest(ctx context.Context, pkg *entities.Package) ([]byte, error) {
+	m := abbreviatedManifestFromPackage(pkg)
 	return json.Marshal(m)
 }
@@ -0,0 +1,89 @@
+package adapters
+
+import (
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// abbreviatedManifest is the install document of a package, also known as corgi document.
+// See https://github.com/npm/registry/blob/main/docs/responses/package-metadata.md#abbreviated-metadata-format
+type abbreviatedManifest struct {
+	Name     string                         `json:"name"`
+	Modified string                         `json:"modified"`
+	DistTags map[string]string              `json:"dist-tags"`
+	Versions map[string]abbreviatedRevision `json:"versions"`
+}
+
+type abbreviatedRevision struct {
+	Name                 string                    `json:"name"`
+	Version              string                    `json:"version"`
+	Deprecated           string                    `json:"deprecated,omitempty"`
+	Dependencies         map[string]string         `json:"dependencies,omitempty"`
+	OptionalDependencies map[string]string         `json:"optionalDependencies,omitempty"`
+	DevDependencies      map[string]string         `json:"devDependencies,omitempty"`
+	BundledDependencies  []string                  `json:"bundleDependencies,omitempty"`
+	PeerDependencies     map[string]string         `json:"peerDependencies,omitempty"`
+	PeerDependenciesMeta map[string]map[string]any `json:"peerDependenciesMeta,omitempty"`
+	Bin                  map[string]string         `json:"bin,omitempty"`
+	Directories          *directories              `json:"directories,omitempty"`
+	Engines              map[string]string         `json:"engines,omitempty"`
+	OS                   []string                  `json:"os,omitempty"`
+	CPU                  []string                  `json:"cpu,omitempty"`
+	HasInstallScript     bool                      `json:"hasInstallScript,omitempty"`
+	Dist                 dist                      `json:"dist"`
+}
+
+// installScripts are the lifecycle scripts run by package managers when a package is installed.
+var installScripts = []fields.RequiredString{"preinstall", "install", "postinstall"}
+
+func abbreviatedManifestFromPackage(pkg *entities.Package) abbreviatedManifest {
+	versions := make(map[string]abbreviatedRevision, len(pkg.Versions))
+	for _, ver := range pkg.Versions {
+		versions[ver.Version.String()] = abbreviatedRevisionFromPackageVersion(pkg.Name, ver)
+	}
+
+	distTags := make(map[string]string, len(pkg.DistTags))
+	for tag, ver := range pkg.DistTags {
+		distTags[tag.String()] = ver.String()
+	}
+
+	return abbreviatedManifest{
+		Name:     pkg.Name.String(),
+		Modified: registryTime(pkg.ModifiedAt),
+		DistTags: distTags,
+		Versions: versions,
+	}
+}
+
+func abbreviatedRevisionFromPackageVersion(packageName fields.PackageName, ver *entities.PackageVersion) abbreviatedRevision {
+	var deprecated string
+	if ver.Deprecated != nil {
+		deprecated = *ver.Deprecated
+	}
+
+	hasInstallScript := false
+	for _, script := range installScripts {
+		if _, ok := ver.Scripts[script]; ok {
+			hasInstallScript = true
+		}
+	}
+
+	return abbreviatedRevision{
+		Name:                 packageName.String(),
+		Version:              ver.Version.String(),
+		Deprecated:           deprecated,
+		Dependencies:         stringMapFromRequiredStringMap(ver.Dependencies),
+		OptionalDependencies: stringMapFromRequiredStringMap(ver.OptionalDependencies),
+		DevDependencies:      stringMapFromRequiredStringMap(ver.DevDependencies),
+		BundledDependencies:  fields.StringsFromRequiredStrings(ver.BundledDependencies),
+		PeerDependencies:     stringMapFromRequiredStringMap(ver.PeerDependencies),
+		PeerDependenciesMeta: peerDependenciesMetaFromFieldPeerDependenciesMeta(ver.PeerDependenciesMeta),
+		Bin:                  stringMapFromRequiredStringMap(ver.Bin),
+		Directories:          directoriesFromFieldDirectories(ver.Directories),
+		Engines:              stringMapFromRequiredStringMap(ver.Engines),
+		OS:                   fields.StringsFromRequiredStrings(ver.OS),
+		CPU:                  fields.StringsFromRequiredStrings(ver.CPU),
+		HasInstallScript:     hasInstallScript,
+		Dist:                 distFromPackageVersion(packageName, ver),
+	}
+}
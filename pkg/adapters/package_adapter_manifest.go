@@ -1,6 +1,8 @@
 package adapters
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -49,6 +51,7 @@ type attachment struct {
 }
 
 type revision struct {
+	ID                   string                    `json:"_id,omitempty"`
 	Name                 string                    `json:"name"`
 	Version              string                    `json:"version"`
 	Description          string                    `json:"description,omitempty"`
@@ -81,23 +84,74 @@ type revision struct {
 	PublishConfig        map[string]any            `json:"publishConfig"`
 	Workspaces           []string                  `json:"workspaces"`
 	Readme               string                    `json:"readme"`
+	Maintainers          []author                  `json:"maintainers,omitempty"`
+	NpmUser              *author                   `json:"_npmUser,omitempty"`
+	Deprecated           string                    `json:"deprecated,omitempty"`
 	Dist                 dist                      `json:"dist"`
+
+	// raw is the version manifest as it was published. If set, it is serialized instead of the typed fields,
+	// except for the registryRevisionFields.
+	raw map[string]json.RawMessage
+}
+
+// registryRevisionFields are the fields of a version that are owned by the registry
+// and always serialized from the typed fields instead of the raw manifest.
+// The files are those read from the tarball on publish, the bin and dependencies must equal the tarball anyway.
+var registryRevisionFields = []string{"_id", "name", "version", "dist", "files", "maintainers", "_npmUser", "deprecated"}
+
+// MarshalJSON writes the raw manifest of the version with the registryRevisionFields overlayed,
+// so that fields without a typed counterpart like "exports" or "types" are kept verbatim.
+func (r revision) MarshalJSON() ([]byte, error) {
+	type typedRevision revision
+
+	typed, err := json.Marshal(typedRevision(r))
+	if err != nil || r.raw == nil {
+		return typed, err
+	}
+
+	var typedFields map[string]json.RawMessage
+	if err := json.Unmarshal(typed, &typedFields); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(r.raw)+len(registryRevisionFields))
+	for k, v := range r.raw {
+		merged[k] = v
+	}
+	for _, k := range registryRevisionFields {
+		if v, ok := typedFields[k]; ok {
+			merged[k] = v
+		} else {
+			delete(merged, k)
+		}
+	}
+
+	return json.Marshal(merged)
+}
+
+// rawVersions holds the versions of a publish manifest without decoding them.
+type rawVersions struct {
+	Versions map[string]json.RawMessage `json:"versions"`
 }
 
 type manifest struct {
+	ID          string                `json:"_id,omitempty"`
+	Rev         string                `json:"_rev,omitempty"`
 	Name        string                `json:"name"`
 	Description string                `json:"description,omitempty"`
 	Readme      string                `json:"readme,omitempty"`
 	Versions    map[string]revision   `json:"versions"`
-	Attachments map[string]attachment `json:"_attachments"`
+	Attachments map[string]attachment `json:"_attachments,omitempty"`
 	DistTags    map[string]string     `json:"dist-tags"`
+	Time        map[string]string     `json:"time,omitempty"`
+	Maintainers []author              `json:"maintainers,omitempty"`
 }
 
 func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Email, error) {
 
 	// convert required fields
 
-	name, err := fields.RequiredStringFromString(m.Name)
+	name, err := fields.PackageNameFromString(m.Name)
 	if err != nil {
 		return nil, nil, &PackageAdapterManifestConvertFieldError{
 			Field:  "name",
@@ -119,7 +173,7 @@ func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Ema
 		}
 	}
 
-	version, err := fields.RequiredStringFromString(ver)
+	version, err := fields.VersionFromString(ver)
 	if err != nil {
 		return nil, nil, &PackageAdapterManifestConvertFieldError{
 			Field:  "version",
@@ -155,7 +209,14 @@ func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Ema
 		}
 	}
 
-	data, err := fields.RequiredStringFromString(m.Attachments[tarball].Data)
+	if m.Attachments[tarball].Data == "" {
+		return nil, nil, &PackageAdapterManifestConvertFieldError{
+			Field:  "data",
+			Reason: "tarball is missing",
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(m.Attachments[tarball].Data)
 	if err != nil {
 		return nil, nil, &PackageAdapterManifestConvertFieldError{
 			Field:  "data",
@@ -695,6 +756,21 @@ func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Ema
 		length = m.Attachments[tarball].Length
 	}
 
+	var distTags []fields.RequiredString
+	for tag, tagVersion := range m.DistTags {
+		if tagVersion != ver {
+			continue
+		}
+		t, err := fields.RequiredStringFromString(tag)
+		if err != nil {
+			return nil, nil, &PackageAdapterManifestConvertFieldError{
+				Field:  "dist-tags",
+				Reason: err.Error(),
+			}
+		}
+		distTags = append(distTags, t)
+	}
+
 	var readme *string
 
 	if m.Versions[ver].Readme != "" {
@@ -715,7 +791,7 @@ func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Ema
 		Integrity:       integrity,
 		SHASUM:          shasum,
 		ContentType:     contentType,
-		Data:            data,
+		Tarball:         data,
 		Length:          length,
 		Readme:          readme,
 		Contributors:    contributers,
@@ -739,9 +815,56 @@ func ManifestFromPackageJSON(m manifest) (*entities.PackageVersion, []fields.Ema
 		Private:              &private,
 		PublishConfig:        publishConfig,
 		Workspaces:           workspaces,
+		DistTags:             distTags,
 	}, contributersToCheck, nil
 }
 
+// PackumentUpdateFromPackageJSON converts a package document sent to update an existing package.
+func PackumentUpdateFromPackageJSON(m manifest) (*entities.PackumentUpdate, error) {
+	name, err := fields.PackageNameFromString(m.Name)
+	if err != nil {
+		return nil, &PackageAdapterManifestConvertFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	update := &entities.PackumentUpdate{
+		Name:     name,
+		Rev:      m.Rev,
+		Versions: make([]entities.PackumentUpdateVersion, 0, len(m.Versions)),
+		DistTags: make(map[fields.RequiredString]fields.RequiredString, len(m.DistTags)),
+		Publish:  len(m.Attachments) > 0,
+	}
+
+	for v, rev := range m.Versions {
+		ver, err := fields.VersionFromString(v)
+		if err != nil {
+			return nil, &PackageAdapterManifestConvertFieldError{
+				Field:  "versions",
+				Reason: err.Error(),
+			}
+		}
+		update.Versions = append(update.Versions, entities.PackumentUpdateVersion{
+			Version:    ver,
+			Deprecated: rev.Deprecated,
+		})
+	}
+
+	for tag, v := range m.DistTags {
+		t, err := fields.RequiredStringFromString(tag)
+		if err != nil {
+			return nil, &PackageAdapterManifestConvertFieldError{
+				Field:  "dist-tags",
+				Reason: err.Error(),
+			}
+		}
+		update.DistTags[t] = fields.RequiredString(v)
+	}
+
+	return update, nil
+}
+
 // errors
 
 type PackageAdapterManifestConvertFieldError struct {
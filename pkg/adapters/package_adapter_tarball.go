@@ -0,0 +1,131 @@
+package adapters
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	json "github.com/bytedance/sonic"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// maxTarballUnpackedSize limits the unpacked size of a tarball to protect against gzip bombs.
+const maxTarballUnpackedSize = 1 << 30
+
+// tarballPackageJSON is the part of the package.json inside a tarball that is cross-checked on publish.
+type tarballPackageJSON struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies"`
+	Bin          any               `json:"bin"`
+}
+
+func (a *PackageAdapter) ReadTarball(ctx context.Context, r io.Reader) (*entities.PackageTarball, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("failed to gunzip tarball: %w", err)}
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(&maxSizeReader{r: gz, remaining: maxTarballUnpackedSize})
+
+	var packageJSON *tarballPackageJSON
+	var files []string
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("failed to untar tarball: %w", err)}
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// every file is located below the root directory "package" like in tarballs created by npm
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		file, ok := strings.CutPrefix(name, "package/")
+		if !ok {
+			return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("file %s of tarball is not located below package/", header.Name)}
+		}
+		files = append(files, file)
+
+		if file == "package.json" {
+			// tar allows an entry to be repeated, the package.json which is checked must be the one that is installed
+			if packageJSON != nil {
+				return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("tarball has more than one package/package.json")}
+			}
+			packageJSON = &tarballPackageJSON{}
+			if err := json.ConfigDefault.NewDecoder(tr).Decode(packageJSON); err != nil {
+				return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("failed to parse package.json: %w", err)}
+			}
+		}
+	}
+
+	if packageJSON == nil {
+		return nil, &ports.PackageAdapterTarballError{Err: fmt.Errorf("tarball has no package/package.json")}
+	}
+
+	bin, err := binFromPackageJSON(packageJSON.Name, packageJSON.Bin)
+	if err != nil {
+		return nil, &ports.PackageAdapterTarballError{Err: err}
+	}
+
+	sort.Strings(files)
+
+	return &entities.PackageTarball{
+		Name:         packageJSON.Name,
+		Version:      packageJSON.Version,
+		Dependencies: packageJSON.Dependencies,
+		Bin:          bin,
+		Files:        files,
+	}, nil
+}
+
+// binFromPackageJSON converts the bin field, which is either a single path or a map of commands to paths, into a map.
+func binFromPackageJSON(packageName string, bin any) (map[string]string, error) {
+	switch b := bin.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return map[string]string{fields.PackageName(packageName).BareName(): b}, nil
+	case map[string]any:
+		m := make(map[string]string, len(b))
+		for k, v := range b {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("bin %s of package.json must be a string", k)
+			}
+			m[k] = s
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("bin of package.json must be a string or an object")
+}
+
+// maxSizeReader fails once more than remaining bytes have been read.
+type maxSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, fmt.Errorf("tarball exceeds the unpacked size limit of %d bytes", maxTarballUnpackedSize)
+	}
+	return n, err
+}
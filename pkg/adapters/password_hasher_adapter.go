@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params are the parameters of argon2id hashes. Memory is given in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP recommendation for argon2id.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2idHashPrefix = "$argon2id$"
+
+// PasswordHasherAdapter hashes passwords with argon2id in the PHC string format.
+// bcrypt hashes, e.g. imported from other registries, and legacy plaintext passwords can still be verified.
+type PasswordHasherAdapter struct {
+	params Argon2Params
+}
+
+var _ ports.PasswordHasherPort = (*PasswordHasherAdapter)(nil)
+
+func NewPasswordHasherAdapter(params Argon2Params) *PasswordHasherAdapter {
+	return &PasswordHasherAdapter{
+		params: params,
+	}
+}
+
+func (a *PasswordHasherAdapter) Hash(password fields.Password) (fields.PasswordHash, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", &ports.PasswordHasherHashError{
+			Err: err,
+		}
+	}
+
+	key := argon2.IDKey(password.Bytes(), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+
+	return fields.PasswordHash(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idHashPrefix,
+		argon2.Version,
+		a.params.Memory,
+		a.params.Iterations,
+		a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (a *PasswordHasherAdapter) Verify(password fields.Password, hash fields.PasswordHash) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash.String(), argon2idHashPrefix):
+		params, salt, key, err := decodeArgon2idHash(hash.String())
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey(password.Bytes(), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	case isBcryptHash(hash.String()):
+		err := bcrypt.CompareHashAndPassword([]byte(hash.String()), password.Bytes())
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		if err != nil {
+			return false, &ports.PasswordHasherInvalidHashError{
+				Reason: err.Error(),
+			}
+		}
+		return true, nil
+	case strings.HasPrefix(hash.String(), "$"):
+		return false, &ports.PasswordHasherInvalidHashError{
+			Reason: "unsupported hash algorithm",
+		}
+	default:
+		// legacy plaintext password
+		return subtle.ConstantTimeCompare([]byte(hash.String()), password.Bytes()) == 1, nil
+	}
+}
+
+func (a *PasswordHasherAdapter) NeedsRehash(hash fields.PasswordHash) bool {
+	if !strings.HasPrefix(hash.String(), argon2idHashPrefix) {
+		return true
+	}
+	params, _, _, err := decodeArgon2idHash(hash.String())
+	if err != nil {
+		return true
+	}
+	return params != a.params
+}
+
+// IsPasswordHash reports whether the stored password is a hash, as opposed to a legacy plaintext password.
+func IsPasswordHash(hash fields.PasswordHash) bool {
+	return strings.HasPrefix(hash.String(), argon2idHashPrefix) || isBcryptHash(hash.String())
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// decodeArgon2idHash parses "$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>".
+func decodeArgon2idHash(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, &ports.PasswordHasherInvalidHashError{
+			Reason: "argon2id hash must have 6 parts",
+		}
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, &ports.PasswordHasherInvalidHashError{
+			Reason: "unsupported argon2id version " + parts[2],
+		}
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, &ports.PasswordHasherInvalidHashError{
+			Reason: "invalid argon2id parameters " + parts[3],
+		}
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, &ports.PasswordHasherInvalidHashError{
+			Reason: "invalid argon2id salt",
+		}
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, &ports.PasswordHasherInvalidHashError{
+			Reason: "invalid argon2id key",
+		}
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
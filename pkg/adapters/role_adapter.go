@@ -29,6 +29,7 @@ func (r *RoleAdapter) CreateRole(ctx context.Context, createRole ports.CreateRol
 		SetName(createRole.Name.String()).
 		SetDescription(createRole.Description).
 		SetPermissions(createRole.Permissions).
+		SetRequireTwoFactor(createRole.RequireTwoFactor).
 		Save(ctx)
 
 	if err != nil {
@@ -98,6 +99,10 @@ func (r *RoleAdapter) UpdateRole(ctx context.Context, id fields.EntityID, update
 		query = query.SetDescription(*updateRole.Description)
 	}
 
+	if updateRole.RequireTwoFactor != nil {
+		query = query.SetRequireTwoFactor(*updateRole.RequireTwoFactor)
+	}
+
 	if updateRole.Permissions != nil {
 		query = query.SetPermissions(*updateRole.Permissions)
 	}
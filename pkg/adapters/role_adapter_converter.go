@@ -20,10 +20,11 @@ func RoleFromEntRole(role *ent.Role) (*entities.Role, error) {
 	}
 
 	return &entities.Role{
-		ID:          id,
-		Name:        roleName,
-		Description: role.Description,
-		Permissions: role.Permissions,
+		ID:               id,
+		Name:             roleName,
+		Description:      role.Description,
+		Permissions:      role.Permissions,
+		RequireTwoFactor: role.RequireTwoFactor,
 	}, nil
 }
 
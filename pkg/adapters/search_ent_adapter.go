@@ -0,0 +1,284 @@
+package adapters
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	entsql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqljson"
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/predicate"
+	"github.com/mrparano1d/noxite/ent/searchdocument"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// searchBoostExactName is added to the rank of a package for every search term matching its whole name.
+// The ranks of Postgres are below 1, so exact matches come first.
+const searchBoostExactName = 10
+
+// SearchEntAdapter is a search index stored in the database, so all instances of the registry share it and it
+// survives restarts. Documents are matched and ranked by the full-text search of Postgres, searching with
+// terms requires Postgres.
+type SearchEntAdapter struct {
+	entClient *ent.Client
+}
+
+var _ ports.SearchPort = (*SearchEntAdapter)(nil)
+
+func NewSearchEntAdapter(entClient *ent.Client) *SearchEntAdapter {
+	return &SearchEntAdapter{
+		entClient: entClient,
+	}
+}
+
+func (a *SearchEntAdapter) Index(ctx context.Context, doc *entities.SearchDocument) error {
+	names := []string{strings.ToLower(doc.Name.String())}
+	if doc.Name.IsScoped() {
+		names = append(names, strings.ToLower(doc.Name.BareName()))
+	}
+
+	keywords := make([]string, 0, len(doc.Keywords))
+	for _, keyword := range doc.Keywords {
+		keywords = append(keywords, strings.ToLower(keyword))
+	}
+
+	authors := tokenize(doc.Author)
+	if author := strings.ToLower(doc.Author); author != "" && !containsString(authors, author) {
+		authors = append(authors, author)
+	}
+	if doc.Publisher != nil {
+		authors = append(authors, strings.ToLower(doc.Publisher.Name.String()))
+	}
+
+	maintainers := make([]string, 0, len(doc.Maintainers))
+	for _, maintainer := range doc.Maintainers {
+		maintainers = append(maintainers, strings.ToLower(maintainer.Name.String()))
+	}
+
+	vector := searchVector(doc, keywords)
+
+	updated, err := a.entClient.SearchDocument.Update().
+		Where(searchdocument.NameEQ(doc.Name.String())).
+		SetScope(doc.Name.Scope()).
+		SetDocument(doc).
+		SetNames(names).
+		SetKeywords(keywords).
+		SetAuthors(authors).
+		SetMaintainers(maintainers).
+		SetDeprecated(doc.Deprecated).
+		SetSearchVector(vector).
+		Save(ctx)
+	if err != nil {
+		return &ports.SearchAdapterIndexError{Name: doc.Name, Err: err}
+	}
+	if updated > 0 {
+		return nil
+	}
+
+	err = a.entClient.SearchDocument.Create().
+		SetName(doc.Name.String()).
+		SetScope(doc.Name.Scope()).
+		SetDocument(doc).
+		SetNames(names).
+		SetKeywords(keywords).
+		SetAuthors(authors).
+		SetMaintainers(maintainers).
+		SetDeprecated(doc.Deprecated).
+		SetSearchVector(vector).
+		Exec(ctx)
+	if err != nil {
+		return &ports.SearchAdapterIndexError{Name: doc.Name, Err: err}
+	}
+
+	return nil
+}
+
+func (a *SearchEntAdapter) Remove(ctx context.Context, name fields.PackageName) error {
+	_, err := a.entClient.SearchDocument.Delete().Where(searchdocument.NameEQ(name.String())).Exec(ctx)
+	if err != nil {
+		return &ports.SearchAdapterIndexError{Name: name, Err: err}
+	}
+
+	return nil
+}
+
+func (a *SearchEntAdapter) Search(ctx context.Context, query *entities.SearchQuery) (*entities.SearchResult, error) {
+	terms := make([]string, 0, len(query.Terms))
+	var words []string
+	for _, term := range query.Terms {
+		terms = append(terms, strings.ToLower(term))
+		words = append(words, tokenize(term)...)
+	}
+
+	q := a.entClient.SearchDocument.Query().Where(searchQualifiers(query)...)
+	if len(words) > 0 {
+		q = q.Where(matchesSearchQuery(searchQuery(words)))
+	}
+
+	total, err := q.Clone().Count(ctx)
+	if err != nil {
+		return nil, &ports.SearchAdapterSearchError{Err: err}
+	}
+
+	result := &entities.SearchResult{
+		Total: total,
+		Hits:  make([]entities.SearchHit, 0),
+	}
+	if query.From >= total || query.Size <= 0 {
+		return result, nil
+	}
+
+	if len(words) > 0 {
+		q = q.Order(bySearchRank(searchQuery(words), terms))
+	}
+	docs, err := q.Order(ent.Asc(searchdocument.FieldName)).
+		Offset(query.From).
+		Limit(query.Size).
+		All(ctx)
+	if err != nil {
+		return nil, &ports.SearchAdapterSearchError{Err: err}
+	}
+
+	for _, doc := range docs {
+		score := 1.0
+		if len(words) > 0 {
+			rank, err := doc.Value(searchRankColumn)
+			if err != nil {
+				return nil, &ports.SearchAdapterSearchError{Err: err}
+			}
+			score, _ = rank.(float64)
+		}
+		result.Hits = append(result.Hits, entities.SearchHit{
+			Document: doc.Document,
+			Score:    score,
+		})
+	}
+
+	return result, nil
+}
+
+// searchQualifiers returns the predicates of the qualifiers and the package restriction of the query.
+func searchQualifiers(query *entities.SearchQuery) []predicate.SearchDocument {
+	var ps []predicate.SearchDocument
+
+	if query.Restriction.IsRestricted() {
+		packages := make([]string, 0, len(query.Restriction.Packages))
+		for _, pkg := range query.Restriction.Packages {
+			packages = append(packages, pkg.String())
+		}
+		scopes := make([]string, 0, len(query.Restriction.Scopes))
+		for _, scope := range query.Restriction.Scopes {
+			scopes = append(scopes, string(scope))
+		}
+		ps = append(ps, searchdocument.Or(searchdocument.NameIn(packages...), searchdocument.ScopeIn(scopes...)))
+	}
+
+	for _, keyword := range query.Keywords {
+		ps = append(ps, jsonContains(searchdocument.FieldKeywords, strings.ToLower(keyword)))
+	}
+
+	if query.Author != "" {
+		ps = append(ps, jsonContains(searchdocument.FieldAuthors, strings.ToLower(query.Author)))
+	}
+
+	if query.Maintainer != "" {
+		ps = append(ps, jsonContains(searchdocument.FieldMaintainers, strings.ToLower(query.Maintainer)))
+	}
+
+	if query.Scope != "" {
+		ps = append(ps, searchdocument.ScopeEqualFold("@"+query.Scope))
+	}
+
+	if query.Deprecated != nil {
+		ps = append(ps, searchdocument.DeprecatedEQ(*query.Deprecated))
+	}
+
+	return ps
+}
+
+// jsonContains matches documents whose list field holds the value.
+func jsonContains(field string, value string) predicate.SearchDocument {
+	return predicate.SearchDocument(func(s *entsql.Selector) {
+		s.Where(sqljson.ValueContains(field, value))
+	})
+}
+
+// searchRankColumn is the column the rank of a document is selected as.
+const searchRankColumn = "search_rank"
+
+// matchesSearchQuery matches documents whose search vector matches the text search query.
+func matchesSearchQuery(tsquery string) predicate.SearchDocument {
+	return predicate.SearchDocument(func(s *entsql.Selector) {
+		s.Where(entsql.P(func(b *entsql.Builder) {
+			b.Ident(s.C(searchdocument.FieldSearchVector)).WriteString(" @@ ").Arg(tsquery).WriteString("::tsquery")
+		}))
+	})
+}
+
+// bySearchRank selects the rank of documents for the text search query, boosted for every term matching the
+// whole name, and orders them by it.
+func bySearchRank(tsquery string, terms []string) searchdocument.OrderOption {
+	return func(s *entsql.Selector) {
+		rank := entsql.ExprFunc(func(b *entsql.Builder) {
+			b.WriteString("(ts_rank(").Ident(s.C(searchdocument.FieldSearchVector)).WriteString(", ").Arg(tsquery).WriteString("::tsquery)")
+			for _, term := range terms {
+				b.WriteString(" + CASE WHEN ").
+					Join(sqljson.ValueContains(s.C(searchdocument.FieldNames), term)).
+					WriteString(" THEN " + strconv.Itoa(searchBoostExactName) + " ELSE 0 END")
+			}
+			b.WriteString(")::float8")
+		})
+		s.AppendSelectExprAs(rank, searchRankColumn)
+		s.OrderBy(entsql.Desc(searchRankColumn))
+	}
+}
+
+// searchQuery builds a text search query requiring every word to be the prefix of a word of the name or a
+// whole word of any other field, like "'react':*A | 'react'".
+func searchQuery(words []string) string {
+	parts := make([]string, 0, len(words))
+	for _, word := range words {
+		lexeme := searchLexeme(word)
+		parts = append(parts, "("+lexeme+":*A | "+lexeme+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// searchVector builds the text search vector of the document, weighting the words of the name with A, the
+// keywords with B, the description and author with C and the readme with D.
+// The words are tokenized like the search terms instead of by Postgres, so the query matches them exactly.
+func searchVector(doc *entities.SearchDocument, keywords []string) string {
+	var lexemes []string
+	add := func(weight string, words []string) {
+		for _, word := range words {
+			if word != "" {
+				lexemes = append(lexemes, searchLexeme(word)+":"+strconv.Itoa(len(lexemes)+1)+weight)
+			}
+		}
+	}
+
+	add("A", tokenize(doc.Name.String()))
+	add("B", keywords)
+	add("C", setWords(tokenSet(doc.Description+" "+doc.Author)))
+	add("D", setWords(tokenSet(doc.Readme)))
+
+	return strings.Join(lexemes, " ")
+}
+
+// searchLexeme quotes a word as lexeme of a text search vector or query.
+func searchLexeme(word string) string {
+	return "'" + strings.NewReplacer(`'`, `''`, `\`, `\\`).Replace(word) + "'"
+}
+
+func setWords(set map[string]bool) []string {
+	words := make([]string, 0, len(set))
+	for word := range set {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
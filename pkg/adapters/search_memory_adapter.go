@@ -0,0 +1,239 @@
+package adapters
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// weights of the fields a search term matched in. A term matching the whole package name ranks highest.
+const (
+	searchWeightExactName   = 10
+	searchWeightName        = 4
+	searchWeightKeyword     = 3
+	searchWeightDescription = 2
+	searchWeightAuthor      = 1.5
+	searchWeightReadme      = 1
+)
+
+// SearchMemoryAdapter is an embedded search index held in memory. It is rebuilt from the storage on startup.
+type SearchMemoryAdapter struct {
+	mu        sync.RWMutex
+	documents map[fields.PackageName]*indexedSearchDocument
+}
+
+var _ ports.SearchPort = (*SearchMemoryAdapter)(nil)
+
+func NewSearchMemoryAdapter() *SearchMemoryAdapter {
+	return &SearchMemoryAdapter{
+		documents: make(map[fields.PackageName]*indexedSearchDocument),
+	}
+}
+
+// indexedSearchDocument holds the tokenized fields of a document.
+type indexedSearchDocument struct {
+	doc         *entities.SearchDocument
+	name        string
+	nameTokens  []string
+	keywords    map[string]bool
+	description map[string]bool
+	author      map[string]bool
+	readme      map[string]bool
+	maintainers []string
+}
+
+func (a *SearchMemoryAdapter) Index(ctx context.Context, doc *entities.SearchDocument) error {
+	keywords := make(map[string]bool, len(doc.Keywords))
+	for _, keyword := range doc.Keywords {
+		keywords[strings.ToLower(keyword)] = true
+	}
+
+	maintainers := make([]string, 0, len(doc.Maintainers))
+	for _, maintainer := range doc.Maintainers {
+		maintainers = append(maintainers, strings.ToLower(maintainer.Name.String()))
+	}
+
+	indexed := &indexedSearchDocument{
+		doc:         doc,
+		name:        strings.ToLower(doc.Name.String()),
+		nameTokens:  tokenize(doc.Name.String()),
+		keywords:    keywords,
+		description: tokenSet(doc.Description),
+		author:      tokenSet(doc.Author),
+		readme:      tokenSet(doc.Readme),
+		maintainers: maintainers,
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.documents[doc.Name] = indexed
+
+	return nil
+}
+
+func (a *SearchMemoryAdapter) Remove(ctx context.Context, name fields.PackageName) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.documents, name)
+
+	return nil
+}
+
+func (a *SearchMemoryAdapter) Search(ctx context.Context, query *entities.SearchQuery) (*entities.SearchResult, error) {
+	terms := make([]string, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		terms = append(terms, strings.ToLower(term))
+	}
+
+	a.mu.RLock()
+	hits := make([]entities.SearchHit, 0)
+	for _, indexed := range a.documents {
+		if !indexed.matchesQualifiers(query) {
+			continue
+		}
+		score, ok := indexed.score(terms)
+		if !ok {
+			continue
+		}
+		hits = append(hits, entities.SearchHit{
+			Document: indexed.doc,
+			Score:    score,
+		})
+	}
+	a.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Document.Name < hits[j].Document.Name
+	})
+
+	result := &entities.SearchResult{
+		Total: len(hits),
+	}
+
+	if query.From < len(hits) {
+		end := query.From + query.Size
+		if end > len(hits) {
+			end = len(hits)
+		}
+		result.Hits = hits[query.From:end]
+	}
+
+	return result, nil
+}
+
+func (d *indexedSearchDocument) matchesQualifiers(query *entities.SearchQuery) bool {
+	if !query.Restriction.Allows(d.doc.Name) {
+		return false
+	}
+
+	for _, keyword := range query.Keywords {
+		if !d.keywords[strings.ToLower(keyword)] {
+			return false
+		}
+	}
+
+	if query.Author != "" && !d.matchesAuthor(query.Author) {
+		return false
+	}
+
+	if query.Maintainer != "" && !containsString(d.maintainers, strings.ToLower(query.Maintainer)) {
+		return false
+	}
+
+	if query.Scope != "" && !strings.EqualFold(d.doc.Name.Scope(), "@"+query.Scope) {
+		return false
+	}
+
+	if query.Deprecated != nil && *query.Deprecated != d.doc.Deprecated {
+		return false
+	}
+
+	return true
+}
+
+// matchesAuthor reports whether the author or publisher of the package is the given name.
+func (d *indexedSearchDocument) matchesAuthor(name string) bool {
+	if d.author[strings.ToLower(name)] || strings.EqualFold(d.doc.Author, name) {
+		return true
+	}
+	return d.doc.Publisher != nil && strings.EqualFold(d.doc.Publisher.Name.String(), name)
+}
+
+// score sums the weights of the fields every term matched in. Terms like "react-dom" match the whole name
+// or each of their words. It reports false if a word matched nowhere.
+// Documents only filtered by qualifiers have a score of 1.
+func (d *indexedSearchDocument) score(terms []string) (float64, bool) {
+	if len(terms) == 0 {
+		return 1, true
+	}
+
+	score := 0.0
+	for _, term := range terms {
+		if d.name == term || strings.ToLower(d.doc.Name.BareName()) == term {
+			score += searchWeightExactName
+		}
+
+		for _, word := range tokenize(term) {
+			wordScore := 0.0
+			for _, token := range d.nameTokens {
+				if strings.HasPrefix(token, word) {
+					wordScore += searchWeightName
+					break
+				}
+			}
+			if d.keywords[word] {
+				wordScore += searchWeightKeyword
+			}
+			if d.description[word] {
+				wordScore += searchWeightDescription
+			}
+			if d.author[word] {
+				wordScore += searchWeightAuthor
+			}
+			if d.readme[word] {
+				wordScore += searchWeightReadme
+			}
+
+			if wordScore == 0 {
+				return 0, false
+			}
+			score += wordScore
+		}
+	}
+
+	return score, true
+}
+
+// tokenize splits text into lower case words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func tokenSet(text string) map[string]bool {
+	tokens := tokenize(text)
+	set := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		set[token] = true
+	}
+	return set
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
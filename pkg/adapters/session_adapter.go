@@ -6,58 +6,134 @@ import (
 
 	//	json "github.com/bytedance/sonic"
 	"encoding/json"
+	"fmt"
+	"strings"
 
-	"github.com/google/uuid"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
 	"github.com/mrparano1d/noxite/pkg/core/fields"
 	"github.com/mrparano1d/noxite/pkg/core/ports"
 	"github.com/redis/go-redis/v9"
 )
 
+// DefaultSessionTimeouts keep sessions for 30 days as long as they are used daily.
+var DefaultSessionTimeouts = entities.SessionTimeouts{
+	Absolute: 30 * 24 * time.Hour,
+	Idle:     24 * time.Hour,
+}
+
+const (
+	// sessionField holds the serialized session in the hash of a session, next to the values.
+	sessionField = "session"
+	// sessionUserField holds the ID of the linked user, so the link can be removed with the session.
+	sessionUserField = "session_user_id"
+	// legacyUserValue held the whole user of a session before sessions kept only the ID of their user in the
+	// userIDValue set by the session service.
+	legacyUserValue = "user"
+	userIDValue     = "user_id"
+	// legacySessionLifetime is the fixed lifetime of sessions stored before the session timeouts.
+	legacySessionLifetime = 24 * time.Hour
+)
+
+// setValueScript sets the value only if the session exists, HSET would otherwise create a hash without expiry.
+var setValueScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	redis.call("HSET", KEYS[1], ARGV[2], ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// migrateUserScript replaces the legacy user value by the ID of the user and the link to the user, only if the
+// session exists like setValueScript.
+var migrateUserScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	redis.call("HSET", KEYS[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5])
+	redis.call("HDEL", KEYS[1], ARGV[6])
+	return 1
+end
+return 0
+`)
+
+// touchScript replaces the session and its expiry only if the session exists, like setValueScript.
+var touchScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+	redis.call("PEXPIREAT", KEYS[1], ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// SessionAdapter stores sessions in Redis, which evicts them when they expire.
+// Sessions linked to a user are listed in a hash of the user, whose entries are removed lazily.
 type SessionAdapter struct {
-	client *redis.Client
-	prefix string
+	client   *redis.Client
+	prefix   string
+	timeouts entities.SessionTimeouts
 }
 
 var _ ports.SessionPort = &SessionAdapter{}
 
-func NewSessionAdapter(client *redis.Client) *SessionAdapter {
+func NewSessionAdapter(client *redis.Client, timeouts entities.SessionTimeouts) *SessionAdapter {
 	return &SessionAdapter{
-		client: client,
-		prefix: "session:",
+		client:   client,
+		prefix:   "session:",
+		timeouts: timeouts,
 	}
 }
 
 // CreateSession creates a new session.
 // It returns an error if the session could not be created.
-func (s *SessionAdapter) CreateSession(ctx context.Context) (*entities.Session, error) {
+func (s *SessionAdapter) CreateSession(ctx context.Context, client entities.SessionClient) (*entities.Session, error) {
 
-	tokenUuid, err := uuid.NewRandom()
+	token, err := fields.NewSessionToken()
 	if err != nil {
 		return nil, &ports.CreateSessionFailedError{Err: err}
 	}
-	token, err := fields.SessionTokenFromString(tokenUuid.String())
+
+	session := entities.NewSession(token, client, time.Now(), s.timeouts)
+
+	sessionB, err := s.Serialize(session)
 	if err != nil {
 		return nil, &ports.CreateSessionFailedError{Err: err}
 	}
 
-	expiry := time.Now().Add(24 * time.Hour)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.prefix+session.Key.String(), sessionField, sessionB)
+		pipe.PExpireAt(ctx, s.prefix+session.Key.String(), session.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return nil, &ports.CreateSessionFailedError{Err: err}
+	}
 
-	session := entities.NewSession(token, expiry)
+	return session, nil
+}
 
-	sessionB, err := s.Serialize(session)
+// TouchSession records a use of the session by the client and extends its expiry by the idle timeout.
+// It returns an error if the token is invalid or expired.
+func (s *SessionAdapter) TouchSession(ctx context.Context, tokenKey fields.SessionTokenKey, client entities.SessionClient) (*entities.Session, error) {
+	session, err := s.GetSession(ctx, tokenKey)
 	if err != nil {
-		return nil, &ports.CreateSessionFailedError{Err: err}
+		if _, ok := err.(*ports.SessionNotFoundError); ok {
+			return nil, err
+		}
+		return nil, &ports.TouchSessionFailedError{TokenKey: tokenKey, Err: err}
 	}
 
-	cmd := s.client.HSet(ctx, s.prefix+token.String(), "session", sessionB)
-	if cmd.Err() != nil {
-		return nil, &ports.CreateSessionFailedError{Err: cmd.Err()}
+	session.Touch(client, time.Now(), s.timeouts)
+
+	sessionB, err := s.Serialize(session)
+	if err != nil {
+		return nil, &ports.TouchSessionFailedError{TokenKey: tokenKey, Err: err}
 	}
 
-	cmdBool := s.client.ExpireAt(ctx, s.prefix+token.String(), expiry)
-	if cmdBool.Err() != nil {
-		return nil, &ports.CreateSessionFailedError{Err: cmdBool.Err()}
+	touched, err := touchScript.Run(ctx, s.client, []string{s.prefix + tokenKey.String()}, sessionField, sessionB, session.ExpiresAt.UnixMilli()).Int()
+	if err != nil {
+		return nil, &ports.TouchSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+	if touched == 0 {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
 	}
 
 	return session, nil
@@ -65,10 +141,23 @@ func (s *SessionAdapter) CreateSession(ctx context.Context) (*entities.Session,
 
 // LinkSessionToUser links a session to a user.
 // It returns an error if the session could not be linked to the user.
-func (s *SessionAdapter) LinkSessionToUser(ctx context.Context, token fields.SessionToken, userID fields.EntityID) error {
-	cmd := s.client.HSet(ctx, s.prefix+userID.String(), token.String(), true)
-	if cmd.Err() != nil {
-		return &ports.SessionAdapterLinkSessionToUserFailedError{Err: cmd.Err()}
+func (s *SessionAdapter) LinkSessionToUser(ctx context.Context, tokenKey fields.SessionTokenKey, userID fields.EntityID) error {
+	if err := s.ValidateToken(ctx, tokenKey); err != nil {
+		if _, ok := err.(*ports.SessionNotFoundError); ok {
+			return err
+		}
+		return &ports.SessionAdapterLinkSessionToUserFailedError{TokenKey: tokenKey, UserID: userID, Err: err}
+	}
+
+	// the link hash lives as long as the newest session of the user may
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.prefix+tokenKey.String(), sessionUserField, userID.String())
+		pipe.HSet(ctx, s.prefix+userID.String(), tokenKey.String(), true)
+		pipe.PExpire(ctx, s.prefix+userID.String(), s.timeouts.Absolute)
+		return nil
+	})
+	if err != nil {
+		return &ports.SessionAdapterLinkSessionToUserFailedError{TokenKey: tokenKey, UserID: userID, Err: err}
 	}
 
 	return nil
@@ -76,10 +165,21 @@ func (s *SessionAdapter) LinkSessionToUser(ctx context.Context, token fields.Ses
 
 // InvalidateSession invalidates a session.
 // It returns an error if the session could not be invalidated.
-func (s *SessionAdapter) InvalidateSession(ctx context.Context, token fields.SessionToken) error {
-	cmd := s.client.Del(ctx, s.prefix+token.String())
-	if cmd.Err() != nil {
-		return &ports.SessionAdapterInvalidateSessionFailedError{Err: cmd.Err()}
+func (s *SessionAdapter) InvalidateSession(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	userID, err := s.client.HGet(ctx, s.prefix+tokenKey.String(), sessionUserField).Result()
+	if err != nil && err != redis.Nil {
+		return &ports.SessionAdapterInvalidateSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.prefix+tokenKey.String())
+		if userID != "" {
+			pipe.HDel(ctx, s.prefix+userID, tokenKey.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return &ports.SessionAdapterInvalidateSessionFailedError{TokenKey: tokenKey, Err: err}
 	}
 
 	return nil
@@ -87,36 +187,57 @@ func (s *SessionAdapter) InvalidateSession(ctx context.Context, token fields.Ses
 
 // GetLinkedSessions returns all sessions linked to the given user.
 // It returns an error if the sessions could not be retrieved.
-func (s *SessionAdapter) GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionToken, error) {
-	tokens, err := s.client.HKeys(ctx, s.prefix+userID.String()).Result()
+func (s *SessionAdapter) GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionTokenKey, error) {
+	keys, err := s.client.HKeys(ctx, s.prefix+userID.String()).Result()
+	if err != nil {
+		return nil, &ports.SessionAdapterGetLinkedSessionsFailedError{UserID: userID, Err: err}
+	}
 
+	exists := make([]*redis.BoolCmd, len(keys))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			exists[i] = pipe.HExists(ctx, s.prefix+key, sessionField)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, &ports.SessionAdapterGetLinkedSessionsFailedError{Err: err}
+		return nil, &ports.SessionAdapterGetLinkedSessionsFailedError{UserID: userID, Err: err}
 	}
 
-	sessionTokens := make([]fields.SessionToken, len(tokens))
+	tokenKeys := make([]fields.SessionTokenKey, 0, len(keys))
+	var expired []string
 
-	for i, token := range tokens {
-		sessionToken, err := fields.SessionTokenFromString(token)
+	for i, key := range keys {
+		if !exists[i].Val() {
+			expired = append(expired, key)
+			continue
+		}
+		tokenKey, err := fields.SessionTokenKeyFromString(key)
 		if err != nil {
 			continue
 		}
-		sessionTokens[i] = sessionToken
+		tokenKeys = append(tokenKeys, tokenKey)
 	}
 
-	return sessionTokens, nil
+	if len(expired) > 0 {
+		if err := s.client.HDel(ctx, s.prefix+userID.String(), expired...).Err(); err != nil {
+			return nil, &ports.SessionAdapterGetLinkedSessionsFailedError{UserID: userID, Err: err}
+		}
+	}
+
+	return tokenKeys, nil
 }
 
 // ValidateToken validates a session token.
 // It returns an error if the token is invalid or expired.
-func (s *SessionAdapter) ValidateToken(ctx context.Context, token fields.SessionToken) error {
-	cmd := s.client.HExists(ctx, s.prefix+token.String(), "session")
+func (s *SessionAdapter) ValidateToken(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	cmd := s.client.HExists(ctx, s.prefix+tokenKey.String(), sessionField)
 	if cmd.Err() != nil {
-		return &ports.ValidateTokenFailedError{Err: cmd.Err()}
+		return &ports.ValidateTokenFailedError{TokenKey: tokenKey, Err: cmd.Err()}
 	}
 
 	if cmd.Val() == false {
-		return &ports.SessionNotFoundError{Token: token}
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
 	}
 
 	return nil
@@ -124,60 +245,110 @@ func (s *SessionAdapter) ValidateToken(ctx context.Context, token fields.Session
 
 // GetSession returns the session associated with the given token.
 // It returns an error if the token is invalid or expired.
-func (s *SessionAdapter) GetSession(ctx context.Context, token fields.SessionToken) (*entities.Session, error) {
-	sessionB, err := s.client.Get(ctx, s.prefix+token.String()).Bytes()
+func (s *SessionAdapter) GetSession(ctx context.Context, tokenKey fields.SessionTokenKey) (*entities.Session, error) {
+	sessionB, err := s.client.HGet(ctx, s.prefix+tokenKey.String(), sessionField).Bytes()
 	if err == redis.Nil {
-		return nil, &ports.SessionNotFoundError{Token: token}
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
 	} else if err != nil {
-		return nil, &ports.GetSessionFailedError{Err: err}
+		return nil, &ports.GetSessionFailedError{TokenKey: tokenKey, Err: err}
 	}
 
 	var session entities.Session
 	err = s.Deserialize(sessionB, &session)
 	if err != nil {
-		return nil, &ports.GetSessionFailedError{Err: err}
+		return nil, &ports.GetSessionFailedError{TokenKey: tokenKey, Err: err}
 	}
+	// sessions stored before tokens were hashed have no key
+	session.Key = tokenKey
 
 	return &session, nil
 }
 
 // SetValue sets a value for the given key in the session associated with the given token.
 // It returns an error if the token is invalid or expired.
-func (s *SessionAdapter) SetValue(ctx context.Context, token fields.SessionToken, key fields.RequiredString, value any) error {
+func (s *SessionAdapter) SetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString, value any) error {
 	valueB, err := s.Serialize(value)
 	if err != nil {
 		return &ports.SetValueFailedError{
-			Token: token,
-			Key:   key,
-			Value: value,
-			Err:   err,
+			TokenKey: tokenKey,
+			Key:      key,
+			Value:    value,
+			Err:      err,
 		}
 	}
 
-	cmd := s.client.HSet(ctx, s.prefix+token.String(), key.String(), valueB)
-	if cmd.Err() != nil {
+	set, err := setValueScript.Run(ctx, s.client, []string{s.prefix + tokenKey.String()}, sessionField, key.String(), valueB).Int()
+	if err != nil {
 		return &ports.SetValueFailedError{
-			Token: token,
-			Key:   key,
-			Value: value,
-			Err:   cmd.Err(),
+			TokenKey: tokenKey,
+			Key:      key,
+			Value:    value,
+			Err:      err,
 		}
 	}
+	if set == 0 {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
 
 	return nil
 }
 
 // GetValue returns the value associated with the given key in the session associated with the given token.
 // It returns an error if the token is invalid or expired or if the key does not exist.
-func (s *SessionAdapter) GetValue(ctx context.Context, token fields.SessionToken, key fields.RequiredString) ([]byte, error) {
-	valueB, err := s.client.HGet(ctx, s.prefix+token.String(), key.String()).Bytes()
-	if err == redis.Nil {
-		return nil, &ports.KeyNotFoundError{Token: token, Key: key}
-	} else if err != nil {
-		return nil, &ports.GetValueFailedError{Err: err}
+func (s *SessionAdapter) GetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString) ([]byte, error) {
+	values, err := s.client.HMGet(ctx, s.prefix+tokenKey.String(), sessionField, key.String()).Result()
+	if err != nil {
+		return nil, &ports.GetValueFailedError{TokenKey: tokenKey, Key: key, Err: err}
+	}
+
+	if values[0] == nil {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+	value, ok := values[1].(string)
+	if !ok {
+		return nil, &ports.KeyNotFoundError{TokenKey: tokenKey, Key: key}
 	}
 
-	return valueB, nil
+	return []byte(value), nil
+}
+
+// MigrateLegacySessionTokens renames the sessions that were stored under their token before tokens were hashed
+// to their keys and replaces their tokens in the sessions of their users, so the sessions stay valid.
+// It returns the number of migrated sessions and is safe to run on every startup.
+func (s *SessionAdapter) MigrateLegacySessionTokens(ctx context.Context) (int, error) {
+	var names []string
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		names = append(names, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to scan sessions: %w", err)
+	}
+
+	migrated := 0
+	for _, name := range names {
+		suffix := strings.TrimPrefix(name, s.prefix)
+		if _, err := fields.SessionTokenKeyFromString(suffix); err == nil {
+			continue
+		}
+
+		if userID, err := fields.EntityIDFromString(suffix); err == nil {
+			if err := s.migrateLegacyLinks(ctx, userID); err != nil {
+				return migrated, err
+			}
+			continue
+		}
+
+		ok, err := s.migrateLegacySession(ctx, fields.SessionToken(suffix))
+		if err != nil {
+			return migrated, err
+		}
+		if ok {
+			migrated++
+		}
+	}
+
+	return migrated, nil
 }
 
 func (s *SessionAdapter) Serialize(value any) ([]byte, error) {
@@ -187,3 +358,115 @@ func (s *SessionAdapter) Serialize(value any) ([]byte, error) {
 func (s *SessionAdapter) Deserialize(value []byte, target any) error {
 	return json.Unmarshal(value, target)
 }
+
+// helpers
+
+// migrateLegacySession renames the session stored under the token to its key, keeping its expiry, drops
+// the token from the stored session and replaces its legacy user value. It reports whether the session was migrated.
+func (s *SessionAdapter) migrateLegacySession(ctx context.Context, token fields.SessionToken) (bool, error) {
+	tokenKey := token.Key()
+
+	renamed, err := s.client.RenameNX(ctx, s.prefix+token.String(), s.prefix+tokenKey.String()).Result()
+	if err != nil {
+		// the session expired since the scan
+		if strings.Contains(err.Error(), "no such key") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to rename session %s: %w", tokenKey, err)
+	}
+	if !renamed {
+		// the session was already migrated, the leftover is a stale copy
+		if err := s.client.Del(ctx, s.prefix+token.String()).Err(); err != nil {
+			return false, fmt.Errorf("failed to delete migrated session %s: %w", tokenKey, err)
+		}
+		return false, nil
+	}
+
+	session, err := s.GetSession(ctx, tokenKey)
+	if err != nil {
+		if _, ok := err.(*ports.SessionNotFoundError); ok {
+			return true, nil
+		}
+		return true, fmt.Errorf("failed to read session %s: %w", tokenKey, err)
+	}
+	// legacy sessions have no creation time, which the timeouts would otherwise expire them by on their next use
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = session.ExpiresAt.Add(-legacySessionLifetime)
+		session.LastUsedAt = session.CreatedAt
+	}
+
+	sessionB, err := s.Serialize(session)
+	if err != nil {
+		return true, fmt.Errorf("failed to serialize session %s: %w", tokenKey, err)
+	}
+	if err := setValueScript.Run(ctx, s.client, []string{s.prefix + tokenKey.String()}, sessionField, sessionField, sessionB).Err(); err != nil {
+		return true, fmt.Errorf("failed to store session %s: %w", tokenKey, err)
+	}
+
+	if err := s.migrateLegacyUser(ctx, tokenKey); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// migrateLegacyUser replaces the whole user stored in the session, including its password hash, by the ID of
+// the user and links the session to the user like LinkSessionToUser.
+func (s *SessionAdapter) migrateLegacyUser(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	userB, err := s.client.HGet(ctx, s.prefix+tokenKey.String(), legacyUserValue).Bytes()
+	if err == redis.Nil {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read user of session %s: %w", tokenKey, err)
+	}
+
+	var user struct {
+		ID int
+	}
+	if err := s.Deserialize(userB, &user); err != nil {
+		// the session has no valid user and can't be used either way
+		return nil
+	}
+	userID, err := fields.EntityIDFromInt(user.ID)
+	if err != nil {
+		return nil
+	}
+
+	userIDB, err := s.Serialize(userID)
+	if err != nil {
+		return fmt.Errorf("failed to serialize user of session %s: %w", tokenKey, err)
+	}
+	err = migrateUserScript.Run(ctx, s.client, []string{s.prefix + tokenKey.String()},
+		sessionField, userIDValue, userIDB, sessionUserField, userID.String(), legacyUserValue).Err()
+	if err != nil {
+		return fmt.Errorf("failed to store user of session %s: %w", tokenKey, err)
+	}
+
+	return nil
+}
+
+// migrateLegacyLinks replaces the tokens among the sessions of the user by their keys.
+func (s *SessionAdapter) migrateLegacyLinks(ctx context.Context, userID fields.EntityID) error {
+	links, err := s.client.HKeys(ctx, s.prefix+userID.String()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get sessions of user %s: %w", userID, err)
+	}
+
+	for _, link := range links {
+		if _, err := fields.SessionTokenKeyFromString(link); err == nil {
+			continue
+		}
+
+		tokenKey := fields.SessionToken(link).Key()
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, s.prefix+userID.String(), link)
+			pipe.HSet(ctx, s.prefix+userID.String(), tokenKey.String(), true)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate session %s of user %s: %w", tokenKey, userID, err)
+		}
+	}
+
+	return nil
+}
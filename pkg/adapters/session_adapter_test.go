@@ -0,0 +1,111 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/mrparano1d/noxite/pkg/adapters/sessiontest"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return mr, client
+}
+
+func TestSessionAdapter(t *testing.T) {
+	var mr *miniredis.Miniredis
+
+	sessiontest.Run(t, sessiontest.Harness{
+		New: func(t *testing.T, timeouts entities.SessionTimeouts) ports.SessionPort {
+			var client *redis.Client
+			mr, client = newTestRedis(t)
+			return NewSessionAdapter(client, timeouts)
+		},
+		// miniredis expires keys only when its clock is advanced, the adapter compares expiries to the wall clock
+		Advance: func(t *testing.T, d time.Duration) {
+			time.Sleep(d)
+			mr.FastForward(d)
+		},
+	})
+}
+
+func TestSessionAdapterMigrateLegacySession(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedis(t)
+	adapter := NewSessionAdapter(client, DefaultSessionTimeouts)
+
+	// a session as stored before tokens were hashed: under its token, holding the whole user, without creation time
+	token := "0b7f6c4e-3f43-4c5c-9d8c-2e0f2a7e1b5d"
+	expiresAt := time.Now().Add(12 * time.Hour).UTC()
+	mr.HSet("session:"+token,
+		"session", fmt.Sprintf(`{"token":%q,"expires_at":%q}`, token, expiresAt.Format(time.RFC3339Nano)),
+		"user", `{"ID":7,"Role":null,"Username":"alice","Email":"alice@example.com","Password":"$2a$10$hash"}`,
+	)
+	mr.SetTTL("session:"+token, 12*time.Hour)
+	mr.HSet("session:7", token, "1")
+
+	migrated, err := adapter.MigrateLegacySessionTokens(ctx)
+	if err != nil {
+		t.Fatalf("failed to migrate sessions: %v", err)
+	}
+	if migrated != 1 {
+		t.Fatalf("expected 1 migrated session, got %d", migrated)
+	}
+
+	tokenKey := fields.SessionToken(token).Key()
+
+	// the session lasts until its legacy expiry, which the next use must not cut short
+	session, err := adapter.TouchSession(ctx, tokenKey, entities.SessionClient{})
+	if err != nil {
+		t.Fatalf("failed to touch migrated session: %v", err)
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expected migrated session to stay valid, expires at %s", session.ExpiresAt)
+	}
+
+	userID, err := ports.SessionValueFromAdapter[fields.EntityID](adapter, ctx, tokenKey, userIDValue)
+	if err != nil {
+		t.Fatalf("failed to get user of migrated session: %v", err)
+	}
+	if userID != 7 {
+		t.Fatalf("expected user 7, got %d", userID)
+	}
+
+	if _, err := adapter.GetValue(ctx, tokenKey, legacyUserValue); !isError[*ports.KeyNotFoundError](err) {
+		t.Fatalf("expected the legacy user to be deleted, got %v", err)
+	}
+
+	linked, err := adapter.GetLinkedSessions(ctx, 7)
+	if err != nil {
+		t.Fatalf("failed to get linked sessions: %v", err)
+	}
+	if len(linked) != 1 || linked[0] != tokenKey {
+		t.Fatalf("expected session %s linked to the user, got %v", tokenKey, linked)
+	}
+
+	// the session knows its user now, so invalidating it removes the link
+	if err := adapter.InvalidateSession(ctx, tokenKey); err != nil {
+		t.Fatalf("failed to invalidate session: %v", err)
+	}
+	if links, _ := mr.HKeys("session:7"); len(links) > 0 {
+		t.Fatalf("expected the link to be removed, got %v", links)
+	}
+
+	// migrating again finds nothing to do
+	if migrated, err := adapter.MigrateLegacySessionTokens(ctx); err != nil || migrated != 0 {
+		t.Fatalf("expected nothing to migrate, got %d, %v", migrated, err)
+	}
+}
@@ -0,0 +1,290 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/predicate"
+	"github.com/mrparano1d/noxite/ent/session"
+	"github.com/mrparano1d/noxite/ent/sessionvalue"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// SessionEntAdapter stores sessions in the database, so the registry runs without Redis.
+// Expired sessions are ignored by all queries and removed by DeleteExpiredSessions.
+type SessionEntAdapter struct {
+	entClient *ent.Client
+	timeouts  entities.SessionTimeouts
+}
+
+var _ ports.SessionPort = (*SessionEntAdapter)(nil)
+
+func NewSessionEntAdapter(entClient *ent.Client, timeouts entities.SessionTimeouts) *SessionEntAdapter {
+	return &SessionEntAdapter{
+		entClient: entClient,
+		timeouts:  timeouts,
+	}
+}
+
+func (a *SessionEntAdapter) CreateSession(ctx context.Context, client entities.SessionClient) (*entities.Session, error) {
+	token, err := fields.NewSessionToken()
+	if err != nil {
+		return nil, &ports.CreateSessionFailedError{Err: err}
+	}
+
+	created := entities.NewSession(token, client, time.Now(), a.timeouts)
+
+	err = a.entClient.Session.Create().
+		SetToken(created.Key.String()).
+		SetExpiresAt(created.ExpiresAt).
+		SetCreatedAt(created.CreatedAt).
+		SetLastUsedAt(created.LastUsedAt).
+		SetIP(client.IP).
+		SetUserAgent(client.UserAgent).
+		Exec(ctx)
+	if err != nil {
+		return nil, &ports.CreateSessionFailedError{Err: err}
+	}
+
+	return created, nil
+}
+
+func (a *SessionEntAdapter) TouchSession(ctx context.Context, tokenKey fields.SessionTokenKey, client entities.SessionClient) (*entities.Session, error) {
+	s, err := a.entClient.Session.Query().
+		Where(validSession(tokenKey)).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+		}
+		return nil, &ports.TouchSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+
+	touched := sessionFromEnt(tokenKey, s)
+	touched.Touch(client, time.Now(), a.timeouts)
+
+	// the session may expire or be invalidated in the meantime
+	updated, err := a.entClient.Session.Update().
+		Where(session.ID(s.ID), validSession(tokenKey)).
+		SetExpiresAt(touched.ExpiresAt).
+		SetLastUsedAt(touched.LastUsedAt).
+		SetIP(client.IP).
+		SetUserAgent(client.UserAgent).
+		Save(ctx)
+	if err != nil {
+		return nil, &ports.TouchSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+	if updated == 0 {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+
+	return touched, nil
+}
+
+func (a *SessionEntAdapter) LinkSessionToUser(ctx context.Context, tokenKey fields.SessionTokenKey, userID fields.EntityID) error {
+	updated, err := a.entClient.Session.Update().
+		Where(validSession(tokenKey)).
+		SetUserID(userID.Int()).
+		Save(ctx)
+	if err != nil {
+		return &ports.SessionAdapterLinkSessionToUserFailedError{TokenKey: tokenKey, UserID: userID, Err: err}
+	}
+	if updated == 0 {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+
+	return nil
+}
+
+func (a *SessionEntAdapter) InvalidateSession(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	// the values are deleted by the cascade of their foreign key
+	_, err := a.entClient.Session.Delete().
+		Where(session.TokenEQ(tokenKey.String())).
+		Exec(ctx)
+	if err != nil {
+		return &ports.SessionAdapterInvalidateSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+
+	return nil
+}
+
+func (a *SessionEntAdapter) GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionTokenKey, error) {
+	keys, err := a.entClient.Session.Query().
+		Where(session.UserIDEQ(userID.Int()), session.ExpiresAtGT(time.Now())).
+		Select(session.FieldToken).
+		Strings(ctx)
+	if err != nil {
+		return nil, &ports.SessionAdapterGetLinkedSessionsFailedError{UserID: userID, Err: err}
+	}
+
+	tokenKeys := make([]fields.SessionTokenKey, 0, len(keys))
+	for _, key := range keys {
+		tokenKey, err := fields.SessionTokenKeyFromString(key)
+		if err != nil {
+			continue
+		}
+		tokenKeys = append(tokenKeys, tokenKey)
+	}
+
+	return tokenKeys, nil
+}
+
+func (a *SessionEntAdapter) ValidateToken(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	exists, err := a.entClient.Session.Query().
+		Where(validSession(tokenKey)).
+		Exist(ctx)
+	if err != nil {
+		return &ports.ValidateTokenFailedError{TokenKey: tokenKey, Err: err}
+	}
+	if !exists {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+
+	return nil
+}
+
+func (a *SessionEntAdapter) GetSession(ctx context.Context, tokenKey fields.SessionTokenKey) (*entities.Session, error) {
+	s, err := a.entClient.Session.Query().
+		Where(validSession(tokenKey)).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+		}
+		return nil, &ports.GetSessionFailedError{TokenKey: tokenKey, Err: err}
+	}
+
+	return sessionFromEnt(tokenKey, s), nil
+}
+
+func (a *SessionEntAdapter) SetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString, value any) error {
+	valueB, err := a.Serialize(value)
+	if err != nil {
+		return &ports.SetValueFailedError{TokenKey: tokenKey, Key: key, Value: value, Err: err}
+	}
+
+	sessionID, err := a.entClient.Session.Query().
+		Where(validSession(tokenKey)).
+		OnlyID(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return &ports.SessionNotFoundError{TokenKey: tokenKey}
+		}
+		return &ports.SetValueFailedError{TokenKey: tokenKey, Key: key, Value: value, Err: err}
+	}
+
+	// a concurrent request may create the value between update and create, it is updated then
+	for attempt := 0; ; attempt++ {
+		updated, err := a.entClient.SessionValue.Update().
+			Where(sessionvalue.SessionIDEQ(sessionID), sessionvalue.KeyEQ(key.String())).
+			SetValue(valueB).
+			Save(ctx)
+		if err != nil {
+			return &ports.SetValueFailedError{TokenKey: tokenKey, Key: key, Value: value, Err: err}
+		}
+		if updated > 0 {
+			return nil
+		}
+
+		err = a.entClient.SessionValue.Create().
+			SetSessionID(sessionID).
+			SetKey(key.String()).
+			SetValue(valueB).
+			Exec(ctx)
+		if err == nil {
+			return nil
+		}
+		if !ent.IsConstraintError(err) || attempt > 0 {
+			return &ports.SetValueFailedError{TokenKey: tokenKey, Key: key, Value: value, Err: err}
+		}
+	}
+}
+
+func (a *SessionEntAdapter) GetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString) ([]byte, error) {
+	v, err := a.entClient.SessionValue.Query().
+		Where(
+			sessionvalue.KeyEQ(key.String()),
+			sessionvalue.HasSessionWith(validSession(tokenKey)),
+		).
+		Only(ctx)
+	if err == nil {
+		return v.Value, nil
+	}
+	if !ent.IsNotFound(err) {
+		return nil, &ports.GetValueFailedError{TokenKey: tokenKey, Key: key, Err: err}
+	}
+
+	if err := a.ValidateToken(ctx, tokenKey); err != nil {
+		return nil, err
+	}
+	return nil, &ports.KeyNotFoundError{TokenKey: tokenKey, Key: key}
+}
+
+// DeleteExpiredSessions deletes the expired sessions and their values and returns their number.
+func (a *SessionEntAdapter) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	return a.entClient.Session.Delete().
+		Where(session.ExpiresAtLTE(time.Now())).
+		Exec(ctx)
+}
+
+// MigrateLegacySessionTokens replaces the tokens of sessions that were stored before tokens were hashed
+// by their keys, so the sessions stay valid. It returns the number of migrated sessions and is safe to run
+// on every startup.
+func (a *SessionEntAdapter) MigrateLegacySessionTokens(ctx context.Context) (int, error) {
+	sessions, err := a.entClient.Session.Query().
+		Select(session.FieldID, session.FieldToken).
+		All(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sessions: %w", err)
+	}
+
+	migrated := 0
+	for _, s := range sessions {
+		if _, err := fields.SessionTokenKeyFromString(s.Token); err == nil {
+			continue
+		}
+
+		updated, err := a.entClient.Session.Update().
+			Where(session.ID(s.ID), session.TokenEQ(s.Token)).
+			SetToken(fields.SessionToken(s.Token).Key().String()).
+			Save(ctx)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to store token key of session %d: %w", s.ID, err)
+		}
+		migrated += updated
+	}
+
+	return migrated, nil
+}
+
+func (a *SessionEntAdapter) Serialize(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (a *SessionEntAdapter) Deserialize(value []byte, target any) error {
+	return json.Unmarshal(value, target)
+}
+
+// helpers
+
+func sessionFromEnt(tokenKey fields.SessionTokenKey, s *ent.Session) *entities.Session {
+	return &entities.Session{
+		Key:        tokenKey,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		Client: entities.SessionClient{
+			IP:        s.IP,
+			UserAgent: s.UserAgent,
+		},
+	}
+}
+
+func validSession(tokenKey fields.SessionTokenKey) predicate.Session {
+	return session.And(session.TokenEQ(tokenKey.String()), session.ExpiresAtGT(time.Now()))
+}
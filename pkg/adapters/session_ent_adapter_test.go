@@ -0,0 +1,27 @@
+package adapters
+
+import (
+	"fmt"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mrparano1d/noxite/ent/enttest"
+	"github.com/mrparano1d/noxite/pkg/adapters/sessiontest"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+func TestSessionEntAdapter(t *testing.T) {
+	databases := 0
+
+	sessiontest.Run(t, sessiontest.Harness{
+		New: func(t *testing.T, timeouts entities.SessionTimeouts) ports.SessionPort {
+			// every adapter gets its own in-memory database
+			databases++
+			client := enttest.Open(t, "sqlite3", fmt.Sprintf("file:sessions%d?mode=memory&cache=shared&_fk=1", databases))
+			t.Cleanup(func() { client.Close() })
+			return NewSessionEntAdapter(client, timeouts)
+		},
+	})
+}
@@ -0,0 +1,230 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// SessionMemoryAdapter holds sessions in memory, which suits single instance setups and development.
+// Sessions are lost on restart. Expired sessions are ignored and removed by DeleteExpiredSessions.
+type SessionMemoryAdapter struct {
+	timeouts entities.SessionTimeouts
+
+	mu       sync.Mutex
+	sessions map[fields.SessionTokenKey]*memorySession
+	links    map[fields.EntityID]map[fields.SessionTokenKey]bool
+}
+
+var _ ports.SessionPort = (*SessionMemoryAdapter)(nil)
+
+func NewSessionMemoryAdapter(timeouts entities.SessionTimeouts) *SessionMemoryAdapter {
+	return &SessionMemoryAdapter{
+		timeouts: timeouts,
+		sessions: make(map[fields.SessionTokenKey]*memorySession),
+		links:    make(map[fields.EntityID]map[fields.SessionTokenKey]bool),
+	}
+}
+
+type memorySession struct {
+	session *entities.Session
+	userID  *fields.EntityID
+	values  map[string][]byte
+}
+
+func (a *SessionMemoryAdapter) CreateSession(ctx context.Context, client entities.SessionClient) (*entities.Session, error) {
+	token, err := fields.NewSessionToken()
+	if err != nil {
+		return nil, &ports.CreateSessionFailedError{Err: err}
+	}
+
+	session := entities.NewSession(token, client, time.Now(), a.timeouts)
+
+	// the token is only returned to the client
+	stored := *session
+	stored.Token = ""
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessions[session.Key] = &memorySession{
+		session: &stored,
+		values:  make(map[string][]byte),
+	}
+
+	return session, nil
+}
+
+func (a *SessionMemoryAdapter) TouchSession(ctx context.Context, tokenKey fields.SessionTokenKey, client entities.SessionClient) (*entities.Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.validSession(tokenKey)
+	if !ok {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+	s.session.Touch(client, time.Now(), a.timeouts)
+
+	copied := *s.session
+	return &copied, nil
+}
+
+func (a *SessionMemoryAdapter) LinkSessionToUser(ctx context.Context, tokenKey fields.SessionTokenKey, userID fields.EntityID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.validSession(tokenKey)
+	if !ok {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+
+	if s.userID != nil {
+		a.unlink(*s.userID, tokenKey)
+	}
+	s.userID = &userID
+	if a.links[userID] == nil {
+		a.links[userID] = make(map[fields.SessionTokenKey]bool)
+	}
+	a.links[userID][tokenKey] = true
+
+	return nil
+}
+
+func (a *SessionMemoryAdapter) InvalidateSession(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.delete(tokenKey)
+	return nil
+}
+
+func (a *SessionMemoryAdapter) GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionTokenKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tokenKeys := make([]fields.SessionTokenKey, 0, len(a.links[userID]))
+	for tokenKey := range a.links[userID] {
+		if _, ok := a.validSession(tokenKey); ok {
+			tokenKeys = append(tokenKeys, tokenKey)
+		}
+	}
+
+	return tokenKeys, nil
+}
+
+func (a *SessionMemoryAdapter) ValidateToken(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.validSession(tokenKey); !ok {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+	return nil
+}
+
+func (a *SessionMemoryAdapter) GetSession(ctx context.Context, tokenKey fields.SessionTokenKey) (*entities.Session, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.validSession(tokenKey)
+	if !ok {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+
+	copied := *s.session
+	return &copied, nil
+}
+
+func (a *SessionMemoryAdapter) SetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString, value any) error {
+	valueB, err := a.Serialize(value)
+	if err != nil {
+		return &ports.SetValueFailedError{TokenKey: tokenKey, Key: key, Value: value, Err: err}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.validSession(tokenKey)
+	if !ok {
+		return &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+	s.values[key.String()] = valueB
+
+	return nil
+}
+
+func (a *SessionMemoryAdapter) GetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.validSession(tokenKey)
+	if !ok {
+		return nil, &ports.SessionNotFoundError{TokenKey: tokenKey}
+	}
+	value, ok := s.values[key.String()]
+	if !ok {
+		return nil, &ports.KeyNotFoundError{TokenKey: tokenKey, Key: key}
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+// DeleteExpiredSessions deletes the expired sessions and their values and returns their number.
+func (a *SessionMemoryAdapter) DeleteExpiredSessions(ctx context.Context) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	deleted := 0
+	for tokenKey, s := range a.sessions {
+		if !s.session.ExpiresAt.After(now) {
+			a.delete(tokenKey)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (a *SessionMemoryAdapter) Serialize(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (a *SessionMemoryAdapter) Deserialize(value []byte, target any) error {
+	return json.Unmarshal(value, target)
+}
+
+// helpers
+
+// validSession returns the session unless it does not exist or is expired. The caller holds mu.
+func (a *SessionMemoryAdapter) validSession(tokenKey fields.SessionTokenKey) (*memorySession, bool) {
+	s, ok := a.sessions[tokenKey]
+	if !ok || !s.session.ExpiresAt.After(time.Now()) {
+		return nil, false
+	}
+	return s, true
+}
+
+// delete removes the session and its link. The caller holds mu.
+func (a *SessionMemoryAdapter) delete(tokenKey fields.SessionTokenKey) {
+	s, ok := a.sessions[tokenKey]
+	if !ok {
+		return
+	}
+	if s.userID != nil {
+		a.unlink(*s.userID, tokenKey)
+	}
+	delete(a.sessions, tokenKey)
+}
+
+// unlink removes the session from the sessions of the user. The caller holds mu.
+func (a *SessionMemoryAdapter) unlink(userID fields.EntityID, tokenKey fields.SessionTokenKey) {
+	delete(a.links[userID], tokenKey)
+	if len(a.links[userID]) == 0 {
+		delete(a.links, userID)
+	}
+}
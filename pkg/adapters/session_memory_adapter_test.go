@@ -0,0 +1,17 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/mrparano1d/noxite/pkg/adapters/sessiontest"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+func TestSessionMemoryAdapter(t *testing.T) {
+	sessiontest.Run(t, sessiontest.Harness{
+		New: func(t *testing.T, timeouts entities.SessionTimeouts) ports.SessionPort {
+			return NewSessionMemoryAdapter(timeouts)
+		},
+	})
+}
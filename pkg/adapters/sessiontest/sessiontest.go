@@ -0,0 +1,429 @@
+// Package sessiontest is a conformance suite for implementations of ports.SessionPort.
+// Adapters run it from their tests, so every session store behaves the same, notably on expiry.
+package sessiontest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// TTL is the time to live of the sessions of the adapters under test.
+const TTL = 500 * time.Millisecond
+
+// Harness creates the adapter under test.
+type Harness struct {
+	// New returns an empty adapter whose sessions expire after the timeouts.
+	New func(t *testing.T, timeouts entities.SessionTimeouts) ports.SessionPort
+	// Advance lets d pass for the adapter. It sleeps if nil, fakes with their own clock like miniredis have to
+	// sleep as well, since adapters compute expiries from the current time.
+	Advance func(t *testing.T, d time.Duration)
+}
+
+// fixedTTL lets sessions last TTL regardless of their use.
+var fixedTTL = entities.SessionTimeouts{Absolute: TTL}
+
+var testClient = entities.SessionClient{IP: "192.0.2.1", UserAgent: "npm/10.2.4 node/v20.11.0 linux x64"}
+
+// Run runs the conformance suite as subtests of t.
+func Run(t *testing.T, h Harness) {
+	if h.Advance == nil {
+		h.Advance = func(t *testing.T, d time.Duration) {
+			time.Sleep(d)
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, h Harness)
+	}{
+		{"CreateSession", testCreateSession},
+		{"UnknownSession", testUnknownSession},
+		{"Values", testValues},
+		{"LinkedSessions", testLinkedSessions},
+		{"InvalidateSession", testInvalidateSession},
+		{"Expiry", testExpiry},
+		{"TouchSession", testTouchSession},
+		{"IdleTimeout", testIdleTimeout},
+		{"AbsoluteTimeout", testAbsoluteTimeout},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, h)
+		})
+	}
+}
+
+func testCreateSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, fixedTTL)
+
+	before := time.Now()
+	session := mustCreate(t, adapter)
+	if !strings.HasPrefix(session.Token.String(), fields.SessionTokenPrefix) || len(session.Token) != len(fields.SessionTokenPrefix)+64 {
+		t.Fatalf("CreateSession returned token %q, want %s followed by 64 hex characters", session.Token, fields.SessionTokenPrefix)
+	}
+	if session.Key != session.Token.Key() {
+		t.Errorf("CreateSession returned key %s, want the key of its token %s", session.Key, session.Token.Key())
+	}
+	if session.ExpiresAt.Before(before.Add(TTL).Add(-time.Second)) || session.ExpiresAt.After(time.Now().Add(TTL).Add(time.Second)) {
+		t.Errorf("CreateSession returned expiry %s, want about %s", session.ExpiresAt, before.Add(TTL))
+	}
+
+	other := mustCreate(t, adapter)
+	if other.Token == session.Token {
+		t.Error("CreateSession returned the same token twice")
+	}
+
+	if err := adapter.ValidateToken(ctx, session.Key); err != nil {
+		t.Errorf("ValidateToken: %v", err)
+	}
+
+	got, err := adapter.GetSession(ctx, session.Key)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	// only the client knows the token
+	if got.Token != "" {
+		t.Errorf("GetSession returned token %s, want none", got.Token)
+	}
+	if got.Key != session.Key {
+		t.Errorf("GetSession returned key %s, want %s", got.Key, session.Key)
+	}
+	if got.Client != testClient {
+		t.Errorf("GetSession returned client %+v, want %+v", got.Client, testClient)
+	}
+	if !sameTime(got.CreatedAt, session.CreatedAt) || !sameTime(got.LastUsedAt, session.CreatedAt) {
+		t.Errorf("GetSession returned created %s and last used %s, want %s", got.CreatedAt, got.LastUsedAt, session.CreatedAt)
+	}
+	if !sameTime(got.ExpiresAt, session.ExpiresAt) {
+		t.Errorf("GetSession returned expiry %s, want %s", got.ExpiresAt, session.ExpiresAt)
+	}
+}
+
+func testUnknownSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, fixedTTL)
+	tokenKey := fields.SessionToken("nox_unknown").Key()
+
+	assertSessionNotFound(t, "ValidateToken", adapter.ValidateToken(ctx, tokenKey))
+	_, err := adapter.GetSession(ctx, tokenKey)
+	assertSessionNotFound(t, "GetSession", err)
+	_, err = adapter.GetValue(ctx, tokenKey, key(t, "key"))
+	assertSessionNotFound(t, "GetValue", err)
+	assertSessionNotFound(t, "SetValue", adapter.SetValue(ctx, tokenKey, key(t, "key"), "value"))
+	assertSessionNotFound(t, "LinkSessionToUser", adapter.LinkSessionToUser(ctx, tokenKey, 1))
+	_, err = adapter.TouchSession(ctx, tokenKey, testClient)
+	assertSessionNotFound(t, "TouchSession", err)
+
+	// SetValue must not create the session
+	assertSessionNotFound(t, "ValidateToken after SetValue", adapter.ValidateToken(ctx, tokenKey))
+
+	if err := adapter.InvalidateSession(ctx, tokenKey); err != nil {
+		t.Errorf("InvalidateSession of an unknown session: %v", err)
+	}
+
+	tokens, err := adapter.GetLinkedSessions(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetLinkedSessions: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("GetLinkedSessions returned %v for a user without sessions", tokens)
+	}
+}
+
+func testValues(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, fixedTTL)
+	session := mustCreate(t, adapter)
+
+	_, err := adapter.GetValue(ctx, session.Key, key(t, "missing"))
+	if _, ok := err.(*ports.KeyNotFoundError); !ok {
+		t.Errorf("GetValue of a missing key returned %v, want *ports.KeyNotFoundError", err)
+	}
+
+	type value struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	if err := adapter.SetValue(ctx, session.Key, key(t, "value"), value{Name: "first", Count: 1}); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	if err := adapter.SetValue(ctx, session.Key, key(t, "value"), value{Name: "second", Count: 2}); err != nil {
+		t.Fatalf("SetValue of an existing key: %v", err)
+	}
+	if err := adapter.SetValue(ctx, session.Key, key(t, "flag"), true); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+
+	got, err := ports.SessionValueFromAdapter[value](adapter, ctx, session.Key, key(t, "value"))
+	if err != nil {
+		t.Fatalf("GetValue: %v", err)
+	}
+	if got != (value{Name: "second", Count: 2}) {
+		t.Errorf("GetValue returned %+v, want the second value", got)
+	}
+
+	flag, err := ports.SessionValueFromAdapter[bool](adapter, ctx, session.Key, key(t, "flag"))
+	if err != nil {
+		t.Fatalf("GetValue: %v", err)
+	}
+	if !flag {
+		t.Error("GetValue returned false, want true")
+	}
+
+	fallback, err := ports.SessionValueFromAdapter[string](adapter, ctx, session.Key, key(t, "missing"), "default")
+	if err != nil {
+		t.Fatalf("SessionValueFromAdapter with default: %v", err)
+	}
+	if fallback != "default" {
+		t.Errorf("SessionValueFromAdapter returned %q, want the default", fallback)
+	}
+
+	// values belong to their session
+	other := mustCreate(t, adapter)
+	_, err = adapter.GetValue(ctx, other.Key, key(t, "value"))
+	if _, ok := err.(*ports.KeyNotFoundError); !ok {
+		t.Errorf("GetValue of another session returned %v, want *ports.KeyNotFoundError", err)
+	}
+}
+
+func testLinkedSessions(t *testing.T, h Harness) {
+	adapter := h.New(t, fixedTTL)
+
+	first := mustCreate(t, adapter)
+	second := mustCreate(t, adapter)
+	foreign := mustCreate(t, adapter)
+
+	mustLink(t, adapter, first.Key, 1)
+	mustLink(t, adapter, second.Key, 1)
+	mustLink(t, adapter, foreign.Key, 2)
+
+	assertLinked(t, adapter, 1, first.Key, second.Key)
+	assertLinked(t, adapter, 2, foreign.Key)
+	assertLinked(t, adapter, 3)
+}
+
+func testInvalidateSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, fixedTTL)
+
+	session := mustCreate(t, adapter)
+	kept := mustCreate(t, adapter)
+	mustLink(t, adapter, session.Key, 1)
+	mustLink(t, adapter, kept.Key, 1)
+	if err := adapter.SetValue(ctx, session.Key, key(t, "key"), "value"); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+
+	if err := adapter.InvalidateSession(ctx, session.Key); err != nil {
+		t.Fatalf("InvalidateSession: %v", err)
+	}
+	if err := adapter.InvalidateSession(ctx, session.Key); err != nil {
+		t.Errorf("InvalidateSession of an invalidated session: %v", err)
+	}
+
+	assertSessionNotFound(t, "ValidateToken", adapter.ValidateToken(ctx, session.Key))
+	_, err := adapter.GetValue(ctx, session.Key, key(t, "key"))
+	assertSessionNotFound(t, "GetValue", err)
+	assertLinked(t, adapter, 1, kept.Key)
+}
+
+func testExpiry(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, fixedTTL)
+
+	expiring := mustCreate(t, adapter)
+	mustLink(t, adapter, expiring.Key, 1)
+	if err := adapter.SetValue(ctx, expiring.Key, key(t, "key"), "value"); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+
+	h.Advance(t, TTL/2)
+	fresh := mustCreate(t, adapter)
+	mustLink(t, adapter, fresh.Key, 1)
+
+	h.Advance(t, TTL/2+TTL/10)
+
+	assertSessionNotFound(t, "ValidateToken", adapter.ValidateToken(ctx, expiring.Key))
+	_, err := adapter.GetSession(ctx, expiring.Key)
+	assertSessionNotFound(t, "GetSession", err)
+	_, err = adapter.GetValue(ctx, expiring.Key, key(t, "key"))
+	assertSessionNotFound(t, "GetValue", err)
+	assertSessionNotFound(t, "SetValue", adapter.SetValue(ctx, expiring.Key, key(t, "key"), "value"))
+	assertSessionNotFound(t, "LinkSessionToUser", adapter.LinkSessionToUser(ctx, expiring.Key, 2))
+
+	if err := adapter.ValidateToken(ctx, fresh.Key); err != nil {
+		t.Errorf("ValidateToken of a session within its ttl: %v", err)
+	}
+	assertLinked(t, adapter, 1, fresh.Key)
+	assertLinked(t, adapter, 2)
+
+	if sweeper, ok := adapter.(interface {
+		DeleteExpiredSessions(ctx context.Context) (int, error)
+	}); ok {
+		deleted, err := sweeper.DeleteExpiredSessions(ctx)
+		if err != nil {
+			t.Fatalf("DeleteExpiredSessions: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("DeleteExpiredSessions deleted %d sessions, want 1", deleted)
+		}
+		assertLinked(t, adapter, 1, fresh.Key)
+	}
+}
+
+func testTouchSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, entities.SessionTimeouts{Absolute: time.Hour, Idle: TTL})
+	session := mustCreate(t, adapter)
+
+	h.Advance(t, TTL/5)
+
+	client := entities.SessionClient{IP: "2001:db8::1", UserAgent: "curl/8.5.0"}
+	touched, err := adapter.TouchSession(ctx, session.Key, client)
+	if err != nil {
+		t.Fatalf("TouchSession: %v", err)
+	}
+	if !touched.ExpiresAt.After(session.ExpiresAt) {
+		t.Errorf("TouchSession returned expiry %s, want later than %s", touched.ExpiresAt, session.ExpiresAt)
+	}
+
+	got, err := adapter.GetSession(ctx, session.Key)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.Client != client {
+		t.Errorf("GetSession returned client %+v, want %+v", got.Client, client)
+	}
+	if !got.LastUsedAt.After(got.CreatedAt) {
+		t.Errorf("GetSession returned last used %s, want later than created %s", got.LastUsedAt, got.CreatedAt)
+	}
+	if !sameTime(got.CreatedAt, session.CreatedAt) {
+		t.Errorf("GetSession returned created %s, want %s", got.CreatedAt, session.CreatedAt)
+	}
+	if !sameTime(got.ExpiresAt, touched.ExpiresAt) {
+		t.Errorf("GetSession returned expiry %s, want %s", got.ExpiresAt, touched.ExpiresAt)
+	}
+}
+
+func testIdleTimeout(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, entities.SessionTimeouts{Absolute: time.Hour, Idle: TTL})
+
+	used := mustCreate(t, adapter)
+	idle := mustCreate(t, adapter)
+	mustLink(t, adapter, used.Key, 1)
+	mustLink(t, adapter, idle.Key, 1)
+
+	// the used session outlives its idle timeout counted from its creation
+	for i := 0; i < 3; i++ {
+		h.Advance(t, TTL*6/10)
+		if _, err := adapter.TouchSession(ctx, used.Key, testClient); err != nil {
+			t.Fatalf("TouchSession %d: %v", i, err)
+		}
+	}
+
+	if err := adapter.ValidateToken(ctx, used.Key); err != nil {
+		t.Errorf("ValidateToken of a used session: %v", err)
+	}
+	assertSessionNotFound(t, "ValidateToken of an idle session", adapter.ValidateToken(ctx, idle.Key))
+	_, err := adapter.TouchSession(ctx, idle.Key, testClient)
+	assertSessionNotFound(t, "TouchSession of an idle session", err)
+	assertLinked(t, adapter, 1, used.Key)
+}
+
+func testAbsoluteTimeout(t *testing.T, h Harness) {
+	ctx := context.Background()
+	adapter := h.New(t, entities.SessionTimeouts{Absolute: TTL * 3 / 2, Idle: TTL})
+	session := mustCreate(t, adapter)
+
+	for i := 0; i < 2; i++ {
+		h.Advance(t, TTL*6/10)
+		touched, err := adapter.TouchSession(ctx, session.Key, testClient)
+		if err != nil {
+			t.Fatalf("TouchSession %d: %v", i, err)
+		}
+		if touched.ExpiresAt.After(session.CreatedAt.Add(TTL * 3 / 2)) {
+			t.Errorf("TouchSession extended the expiry to %s, beyond the absolute timeout", touched.ExpiresAt)
+		}
+	}
+
+	// the idle timeout would last until 2.2 TTL
+	h.Advance(t, TTL*4/10)
+	assertSessionNotFound(t, "ValidateToken after the absolute timeout", adapter.ValidateToken(ctx, session.Key))
+}
+
+// helpers
+
+// sameTime reports whether the times are equal up to the precision of the stores.
+func sameTime(a time.Time, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+func key(t *testing.T, s string) fields.RequiredString {
+	t.Helper()
+	k, err := fields.RequiredStringFromString(s)
+	if err != nil {
+		t.Fatalf("invalid key %q: %v", s, err)
+	}
+	return k
+}
+
+func mustCreate(t *testing.T, adapter ports.SessionPort) *entities.Session {
+	t.Helper()
+	session, err := adapter.CreateSession(context.Background(), testClient)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	return session
+}
+
+func mustLink(t *testing.T, adapter ports.SessionPort, tokenKey fields.SessionTokenKey, userID fields.EntityID) {
+	t.Helper()
+	if err := adapter.LinkSessionToUser(context.Background(), tokenKey, userID); err != nil {
+		t.Fatalf("LinkSessionToUser: %v", err)
+	}
+}
+
+func assertSessionNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	if _, ok := err.(*ports.SessionNotFoundError); !ok {
+		t.Errorf("%s returned %v, want *ports.SessionNotFoundError", op, err)
+	}
+}
+
+func assertLinked(t *testing.T, adapter ports.SessionPort, userID fields.EntityID, want ...fields.SessionTokenKey) {
+	t.Helper()
+	tokenKeys, err := adapter.GetLinkedSessions(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetLinkedSessions: %v", err)
+	}
+
+	got := make(map[fields.SessionTokenKey]bool, len(tokenKeys))
+	for _, tokenKey := range tokenKeys {
+		got[tokenKey] = true
+	}
+	if len(got) != len(tokenKeys) {
+		t.Errorf("GetLinkedSessions of user %s returned duplicates: %v", userID, tokenKeys)
+	}
+	if len(got) != len(want) {
+		t.Errorf("GetLinkedSessions of user %s returned %v, want %v", userID, tokenKeys, want)
+		return
+	}
+	for _, tokenKey := range want {
+		if !got[tokenKey] {
+			t.Errorf("GetLinkedSessions of user %s returned %v, want %v", userID, tokenKeys, want)
+			return
+		}
+	}
+}
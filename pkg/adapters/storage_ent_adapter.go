@@ -1,12 +1,14 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/disttag"
 	"github.com/mrparano1d/noxite/ent/repopackage"
 	"github.com/mrparano1d/noxite/ent/version"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
@@ -27,63 +29,39 @@ func NewStorageEntAdapter(entClient *ent.Client) *StorageEntAdapter {
 }
 
 func (s *StorageEntAdapter) createPackage(ctx context.Context, creatorID fields.EntityID, manifest *entities.PackageVersion) (*ent.RepoPackage, error) {
-	return s.entClient.RepoPackage.Create().SetName(manifest.Name.String()).SetCreatorID(creatorID.Int()).Save(ctx)
+	query := s.entClient.RepoPackage.Create().SetName(manifest.Name.String()).SetCreatorID(creatorID.Int())
+	if manifest.Name.IsScoped() {
+		query = query.SetScope(manifest.Name.Scope())
+	}
+	return query.Save(ctx)
 }
 
 func (s *StorageEntAdapter) reactivatePackage(ctx context.Context, creatorID fields.EntityID, pkg *ent.RepoPackage) error {
 	return s.entClient.RepoPackage.Update().SetCreatorID(creatorID.Int()).SetNillableDeletedAt(nil).Where(repopackage.IDEQ(pkg.ID)).Exec(ctx)
 }
 
-func isVersionNewer(latest, newVersion string) (bool, error) {
-	latestParts := strings.Split(latest, ".")
-	newParts := strings.Split(newVersion, ".")
-
-	isNewer := true
-	for i := 0; i < len(latestParts); i++ {
-		latestPart, err := strconv.Atoi(latestParts[i])
-		if err != nil {
-			return false, err
-		}
-
-		newPart, err := strconv.Atoi(newParts[i])
-		if err != nil {
-			return false, err
-		}
-
-		if newPart < latestPart {
-			isNewer = false
-			break
-		}
-	}
-
-	return isNewer, nil
-}
-
-func (s *StorageEntAdapter) isVersionNewer(ctx context.Context, pkg *ent.RepoPackage, newVersion fields.RequiredString) (bool, error) {
-	latestVersions, err := s.entClient.Version.Query().Where(version.PackageIDEQ(pkg.ID)).All(ctx)
+// versionExists reports whether the package has a version with the same precedence, ignoring build metadata.
+func (s *StorageEntAdapter) versionExists(ctx context.Context, pkg *ent.RepoPackage, newVersion fields.Version) (bool, error) {
+	versions, err := s.entClient.Version.Query().Where(version.PackageIDEQ(pkg.ID)).Select(version.FieldVersion).Strings(ctx)
 	if err != nil {
-		if ent.IsNotFound(err) {
-			return true, nil
-		}
 		return false, err
 	}
 
-	if len(latestVersions) == 0 {
-		return true, nil
-	}
-
-	latest := latestVersions[0].Version
-
-	for i := 1; i < len(latestVersions); i++ {
-		v := latestVersions[i]
-		if ok, err := isVersionNewer(latest, v.Version); err != nil {
-			return false, err
-		} else if ok {
-			latest = v.Version
+	for _, v := range versions {
+		existing, err := fields.VersionFromString(v)
+		if err != nil {
+			// versions stored before semver validation are compared literally
+			if v == newVersion.String() {
+				return true, nil
+			}
+			continue
+		}
+		if existing.Equal(newVersion) {
+			return true, nil
 		}
 	}
 
-	return isVersionNewer(latest, newVersion.String())
+	return false, nil
 }
 
 func (s *StorageEntAdapter) createVersion(ctx context.Context, publisherID fields.EntityID, pkg *ent.RepoPackage, manifest *entities.PackageVersion) (*ent.Version, error) {
@@ -140,12 +118,31 @@ func (s *StorageEntAdapter) createVersion(ctx context.Context, publisherID field
 		SetIntegrity(manifest.Integrity.String()).
 		SetShasum(manifest.SHASUM.String()).
 		SetLength(manifest.Length).
-		SetData(manifest.Data.String()).
+		SetTarballDigest(manifest.TarballDigest.String()).
+		SetRawManifest(string(manifest.RawManifest)).
 		SetPublisherID(publisherID.Int()).
 		Save(ctx)
 }
 
 func (s *StorageEntAdapter) PublishPackage(ctx context.Context, creatorID fields.EntityID, manifest *entities.PackageVersion) error {
+	err := s.withTx(ctx, func(tx *StorageEntAdapter) error {
+		return tx.publishPackage(ctx, creatorID, manifest)
+	})
+	if err != nil {
+		var publishErr *ports.StorageAdapterPublishPackageError
+		if errors.As(err, &publishErr) {
+			return publishErr
+		}
+		var alreadyExistsErr *ports.StorageAdapterVersionAlreadyExistsError
+		if errors.As(err, &alreadyExistsErr) {
+			return alreadyExistsErr
+		}
+		return &ports.StorageAdapterPublishPackageError{Err: err}
+	}
+	return nil
+}
+
+func (s *StorageEntAdapter) publishPackage(ctx context.Context, creatorID fields.EntityID, manifest *entities.PackageVersion) error {
 	var pkg *ent.RepoPackage
 	var err error
 
@@ -175,36 +172,67 @@ func (s *StorageEntAdapter) PublishPackage(ctx context.Context, creatorID fields
 		}
 	}
 
-	// if it does, check if the version is newer
+	// if it does, check that the version was never published before, deleted versions included
 
-	newer, err := s.isVersionNewer(ctx, pkg, manifest.Version)
+	exists, err := s.versionExists(ctx, pkg, manifest.Version)
 	if err != nil {
 		return &ports.StorageAdapterPublishPackageError{
-			Err: fmt.Errorf("failed to check if version is newer: %w", err),
+			Err: fmt.Errorf("failed to check if version exists: %w", err),
 		}
 	}
 
-	if !newer {
-		return &ports.StorageAdapterPublishPackageError{
-			Err: fmt.Errorf("package version is not newer"),
+	if exists {
+		return &ports.StorageAdapterVersionAlreadyExistsError{
+			Name:    manifest.Name,
+			Version: manifest.Version,
 		}
 	}
 
-	// if it is, create a new version
+	// if not, create a new version
 
-	if _, err := s.createVersion(ctx, creatorID, pkg, manifest); err != nil {
+	ver, err := s.createVersion(ctx, creatorID, pkg, manifest)
+	if err != nil {
 		return &ports.StorageAdapterPublishPackageError{
 			Err: fmt.Errorf("failed to create version: %w", err),
 		}
 	}
 
+	// point the requested dist-tags to the new version
+
+	for _, tag := range manifest.DistTags {
+		if err := s.setDistTag(ctx, pkg.ID, tag, ver.ID); err != nil {
+			return &ports.StorageAdapterPublishPackageError{
+				Err: fmt.Errorf("failed to set dist-tag %s: %w", tag, err),
+			}
+		}
+	}
+
+	// a package always needs a latest tag, even if the first version was published with another tag
+
+	hasLatest, err := s.entClient.DistTag.Query().Where(disttag.PackageIDEQ(pkg.ID), disttag.TagEQ("latest")).Exist(ctx)
+	if err != nil {
+		return &ports.StorageAdapterPublishPackageError{
+			Err: fmt.Errorf("failed to query latest dist-tag: %w", err),
+		}
+	}
+
+	if !hasLatest {
+		if err := s.setDistTag(ctx, pkg.ID, "latest", ver.ID); err != nil {
+			return &ports.StorageAdapterPublishPackageError{
+				Err: fmt.Errorf("failed to set dist-tag latest: %w", err),
+			}
+		}
+	}
+
 	return nil
 }
 
 func (s *StorageEntAdapter) GetPackage(ctx context.Context, name fields.PackageName, rev fields.RequiredString) (*entities.PackageVersion, error) {
 
 	pkg, err := s.entClient.RepoPackage.Query().WithVersions(func(vq *ent.VersionQuery) {
-		vq.Order(ent.Desc(version.FieldVersion))
+		vq.Where(version.DeletedAtIsNil()).WithPublisher().Order(ent.Desc(version.FieldCreatedAt))
+	}).WithDistTags(func(dq *ent.DistTagQuery) {
+		dq.Where(disttag.TagEQ(rev.String()))
 	}).Where(repopackage.NameEQ(name.String()), repopackage.DeletedAtIsNil()).First(ctx)
 	if err != nil {
 		if ent.IsNotFound(err) {
@@ -220,19 +248,137 @@ func (s *StorageEntAdapter) GetPackage(ctx context.Context, name fields.PackageN
 		}
 	}
 
-	if rev.String() == "latest" {
-		return packageVersionFromEntVersion(name.String(), pkg.Edges.Versions[0])
-	} else {
+	if len(pkg.Edges.Versions) == 0 {
+		return nil, &ports.StorageAdapterPackageNotFoundError{
+			Name:    name,
+			Version: rev,
+		}
+	}
+
+	// the revision is either a dist-tag or an exact version
+	for _, tag := range pkg.Edges.DistTags {
 		for _, v := range pkg.Edges.Versions {
-			if v.Version == rev.String() {
+			if v.ID == tag.VersionID {
 				return packageVersionFromEntVersion(name.String(), v)
 			}
 		}
 	}
 
+	for _, v := range pkg.Edges.Versions {
+		if v.Version == rev.String() {
+			return packageVersionFromEntVersion(name.String(), v)
+		}
+	}
+
+	// packages published before dist-tags were stored have no latest tag
+	if rev.String() == "latest" {
+		return packageVersionFromEntVersion(name.String(), pkg.Edges.Versions[0])
+	}
+
 	return nil, &ports.StorageAdapterPackageNotFoundError{
 		Name:    name,
 		Version: rev,
 	}
 
 }
+
+func (s *StorageEntAdapter) GetPackument(ctx context.Context, name fields.PackageName) (*entities.Package, error) {
+
+	pkg, err := s.entClient.RepoPackage.Query().
+		WithCreator().
+		WithVersions(func(vq *ent.VersionQuery) {
+			vq.Where(version.DeletedAtIsNil()).WithPublisher().Order(ent.Asc(version.FieldCreatedAt))
+		}).
+		WithDistTags().
+		Where(repopackage.NameEQ(name.String()), repopackage.DeletedAtIsNil()).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.StorageAdapterPackageNotFoundError{
+				Name: name,
+			}
+		}
+		return nil, &ports.StorageAdapterGetPackageError{
+			Name: name,
+			Err:  fmt.Errorf("failed to query package: %w", err),
+		}
+	}
+
+	if len(pkg.Edges.Versions) == 0 {
+		return nil, &ports.StorageAdapterPackageNotFoundError{
+			Name: name,
+		}
+	}
+
+	packument, err := packageFromEntRepoPackage(pkg)
+	if err != nil {
+		return nil, &ports.StorageAdapterGetPackageError{
+			Name: name,
+			Err:  fmt.Errorf("failed to convert package: %w", err),
+		}
+	}
+
+	return packument, nil
+}
+
+func (s *StorageEntAdapter) ListPackageNames(ctx context.Context) ([]fields.PackageName, error) {
+	names, err := s.entClient.RepoPackage.Query().
+		Where(repopackage.DeletedAtIsNil()).
+		Order(ent.Asc(repopackage.FieldName)).
+		Select(repopackage.FieldName).
+		Strings(ctx)
+	if err != nil {
+		return nil, &ports.StorageAdapterGetPackageError{
+			Err: fmt.Errorf("failed to query package names: %w", err),
+		}
+	}
+
+	packageNames := make([]fields.PackageName, len(names))
+	for i, name := range names {
+		packageNames[i] = fields.PackageName(name)
+	}
+
+	return packageNames, nil
+}
+
+// MigrateTarballsToBlobStorage moves the base64 encoded tarballs of versions published before the blob storage
+// existed into the blob storage and references them by digest. It returns the number of migrated versions.
+func (s *StorageEntAdapter) MigrateTarballsToBlobStorage(ctx context.Context, blobAdapter ports.BlobPort) (int, error) {
+	migrated := 0
+
+	for {
+		versions, err := s.entClient.Version.Query().
+			Where(
+				version.Or(version.TarballDigestIsNil(), version.TarballDigestEQ("")),
+				version.DataNotNil(),
+				version.DataNEQ(""),
+			).
+			Limit(50).
+			All(ctx)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to query versions to migrate: %w", err)
+		}
+
+		if len(versions) == 0 {
+			return migrated, nil
+		}
+
+		for _, v := range versions {
+			data, err := base64.StdEncoding.DecodeString(v.Data)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to decode tarball of version %d: %w", v.ID, err)
+			}
+
+			digest, err := blobAdapter.Put(ctx, bytes.NewReader(data))
+			if err != nil {
+				return migrated, fmt.Errorf("failed to store tarball of version %d: %w", v.ID, err)
+			}
+
+			if err := v.Update().SetTarballDigest(digest.String()).ClearData().Exec(ctx); err != nil {
+				return migrated, fmt.Errorf("failed to update version %d: %w", v.ID, err)
+			}
+
+			migrated++
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent/version"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+func (s *StorageEntAdapter) DeprecateVersion(ctx context.Context, name fields.PackageName, ver fields.Version, message string) error {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = s.withTx(ctx, func(tx *StorageEntAdapter) error {
+		update := tx.entClient.Version.Update().
+			Where(version.PackageIDEQ(pkg.ID), version.VersionEQ(ver.String()), version.DeletedAtIsNil()).
+			SetUpdatedAt(now)
+		if message == "" {
+			update.ClearDeprecated()
+		} else {
+			update.SetDeprecated(message)
+		}
+
+		updated, err := update.Save(ctx)
+		if err != nil {
+			return err
+		}
+
+		if updated == 0 {
+			return &ports.StorageAdapterPackageNotFoundError{
+				Name:    name,
+				Version: fields.RequiredString(ver.String()),
+			}
+		}
+
+		return tx.entClient.RepoPackage.UpdateOneID(pkg.ID).SetUpdatedAt(now).Exec(ctx)
+	})
+	if err != nil {
+		if notFoundErr, ok := err.(*ports.StorageAdapterPackageNotFoundError); ok {
+			return notFoundErr
+		}
+		return &ports.StorageAdapterUpdatePackageError{
+			Name:    name,
+			Version: fields.RequiredString(ver.String()),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
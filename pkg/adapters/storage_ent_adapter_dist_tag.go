@@ -0,0 +1,177 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/disttag"
+	"github.com/mrparano1d/noxite/ent/repopackage"
+	"github.com/mrparano1d/noxite/ent/version"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// withTx runs fn with an adapter bound to a new transaction and commits it if fn succeeds.
+func (s *StorageEntAdapter) withTx(ctx context.Context, fn func(tx *StorageEntAdapter) error) error {
+	tx, err := s.entClient.Tx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if err := fn(&StorageEntAdapter{entClient: tx.Client()}); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return fmt.Errorf("%w: failed to rollback transaction: %s", err, rerr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// setDistTag points the tag of a package to the version, creating the tag if it doesn't exist yet.
+func (s *StorageEntAdapter) setDistTag(ctx context.Context, packageID int, tag fields.RequiredString, versionID int) error {
+	existing, err := s.entClient.DistTag.Query().Where(disttag.PackageIDEQ(packageID), disttag.TagEQ(tag.String())).Only(ctx)
+	if err != nil && !ent.IsNotFound(err) {
+		return err
+	}
+
+	if existing != nil {
+		return existing.Update().SetVersionID(versionID).SetUpdatedAt(time.Now()).Exec(ctx)
+	}
+
+	return s.entClient.DistTag.Create().SetPackageID(packageID).SetTag(tag.String()).SetVersionID(versionID).Exec(ctx)
+}
+
+func (s *StorageEntAdapter) queryActivePackage(ctx context.Context, name fields.PackageName) (*ent.RepoPackage, error) {
+	pkg, err := s.entClient.RepoPackage.Query().Where(repopackage.NameEQ(name.String()), repopackage.DeletedAtIsNil()).Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.StorageAdapterPackageNotFoundError{
+				Name: name,
+			}
+		}
+		return nil, &ports.StorageAdapterGetPackageError{
+			Name: name,
+			Err:  fmt.Errorf("failed to query package: %w", err),
+		}
+	}
+	return pkg, nil
+}
+
+func (s *StorageEntAdapter) GetDistTags(ctx context.Context, name fields.PackageName) (map[fields.RequiredString]fields.RequiredString, error) {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.entClient.DistTag.Query().
+		Where(disttag.PackageIDEQ(pkg.ID), disttag.HasVersionWith(version.DeletedAtIsNil())).
+		WithVersion().
+		All(ctx)
+	if err != nil {
+		return nil, &ports.StorageAdapterDistTagError{
+			Name: name,
+			Err:  fmt.Errorf("failed to query dist-tags: %w", err),
+		}
+	}
+
+	distTags := make(map[fields.RequiredString]fields.RequiredString, len(tags))
+	for _, tag := range tags {
+		distTags[fields.RequiredString(tag.Tag)] = fields.RequiredString(tag.Edges.Version.Version)
+	}
+
+	return distTags, nil
+}
+
+func (s *StorageEntAdapter) SetDistTag(ctx context.Context, name fields.PackageName, tag fields.RequiredString, ver fields.Version) error {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	v, err := s.entClient.Version.Query().
+		Where(version.PackageIDEQ(pkg.ID), version.VersionEQ(ver.String()), version.DeletedAtIsNil()).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return &ports.StorageAdapterPackageNotFoundError{
+				Name:    name,
+				Version: fields.RequiredString(ver.String()),
+			}
+		}
+		return &ports.StorageAdapterDistTagError{
+			Name: name,
+			Tag:  tag,
+			Err:  fmt.Errorf("failed to query version: %w", err),
+		}
+	}
+
+	if err := s.setDistTag(ctx, pkg.ID, tag, v.ID); err != nil {
+		return &ports.StorageAdapterDistTagError{
+			Name: name,
+			Tag:  tag,
+			Err:  err,
+		}
+	}
+
+	return nil
+}
+
+func (s *StorageEntAdapter) RemoveDistTag(ctx context.Context, name fields.PackageName, tag fields.RequiredString) error {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.entClient.DistTag.Delete().Where(disttag.PackageIDEQ(pkg.ID), disttag.TagEQ(tag.String())).Exec(ctx)
+	if err != nil {
+		return &ports.StorageAdapterDistTagError{
+			Name: name,
+			Tag:  tag,
+			Err:  err,
+		}
+	}
+
+	if deleted == 0 {
+		return &ports.StorageAdapterDistTagNotFoundError{
+			Name: name,
+			Tag:  tag,
+		}
+	}
+
+	return nil
+}
+
+func (s *StorageEntAdapter) ReplaceDistTags(ctx context.Context, name fields.PackageName, tags map[fields.RequiredString]fields.Version) error {
+	return s.withTx(ctx, func(tx *StorageEntAdapter) error {
+		pkg, err := tx.queryActivePackage(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(tags)+1)
+		for tag, ver := range tags {
+			if err := tx.SetDistTag(ctx, name, tag, ver); err != nil {
+				return err
+			}
+			names = append(names, tag.String())
+		}
+		names = append(names, "latest")
+
+		_, err = tx.entClient.DistTag.Delete().Where(disttag.PackageIDEQ(pkg.ID), disttag.TagNotIn(names...)).Exec(ctx)
+		if err != nil {
+			return &ports.StorageAdapterDistTagError{
+				Name: name,
+				Err:  fmt.Errorf("failed to remove dist-tags: %w", err),
+			}
+		}
+
+		return nil
+	})
+}
@@ -1,8 +1,10 @@
 package adapters
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"log"
+	"time"
 
 	"github.com/mrparano1d/noxite/ent"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
@@ -13,10 +15,14 @@ func bugsFromFieldBugs(bgs *fields.Bugs) *bugs {
 	if bgs == nil {
 		return nil
 	}
-	return &bugs{
-		URL:   bgs.URL.String(),
-		Email: bgs.Email.String(),
+	b := &bugs{}
+	if bgs.URL != nil {
+		b.URL = bgs.URL.String()
 	}
+	if bgs.Email != nil {
+		b.Email = bgs.Email.String()
+	}
+	return b
 }
 
 func authorFromMixedAuthor(pkgAuthor *fields.MixedAuthor) *author {
@@ -51,10 +57,14 @@ func directoriesFromFieldDirectories(dirs *fields.Directories) *directories {
 		return nil
 	}
 
-	return &directories{
-		Bin: dirs.Bin.String(),
-		Man: dirs.Man.String(),
+	d := &directories{}
+	if dirs.Bin != nil {
+		d.Bin = dirs.Bin.String()
+	}
+	if dirs.Man != nil {
+		d.Man = dirs.Man.String()
 	}
+	return d
 }
 
 func repositoryFromFrieldRepository(repo *fields.Repository) *repository {
@@ -127,12 +137,52 @@ func fundingFromMixedFunding(funding []fields.UrlType) any {
 	return f
 }
 
-func manifestFromPackageVersion(packageName fields.RequiredString, ver *entities.PackageVersion) manifest {
+// registryTimeFormat is the timestamp layout used by the npm registry in the time map.
+const registryTimeFormat = "2006-01-02T15:04:05.000Z"
+
+func registryTime(t time.Time) string {
+	return t.UTC().Format(registryTimeFormat)
+}
+
+func authorFromMaintainer(m *entities.Maintainer) *author {
+	if m == nil {
+		return nil
+	}
+	return &author{
+		Name:  m.Name.String(),
+		Email: m.Email.String(),
+	}
+}
+
+func maintainersFromEntitiesMaintainers(maintainers []entities.Maintainer) []author {
+	if len(maintainers) == 0 {
+		return nil
+	}
+	authors := make([]author, len(maintainers))
+	for i := range maintainers {
+		authors[i] = *authorFromMaintainer(&maintainers[i])
+	}
+	return authors
+}
+
+func distFromPackageVersion(packageName fields.PackageName, ver *entities.PackageVersion) dist {
+	return dist{
+		Tarball:   "http://localhost:3000/" + packageName.String() + "/-/" + packageName.TarballName(ver.Version.String()),
+		Integrity: ver.Integrity.String(),
+		SHASUM:    ver.SHASUM.String(),
+	}
+}
+
+func revisionFromPackageVersion(packageName fields.PackageName, ver *entities.PackageVersion, maintainers []author) revision {
 
 	var description string
 	if ver.Description != nil {
 		description = *ver.Description
 	}
+	var homepage string
+	if ver.Homepage != nil {
+		homepage = ver.Homepage.String()
+	}
 	var license string
 	if ver.License != nil {
 		license = *ver.License
@@ -145,18 +195,26 @@ func manifestFromPackageVersion(packageName fields.RequiredString, ver *entities
 	if ver.Browser != nil {
 		browser = ver.Browser.String()
 	}
-	var readme string
-	if ver.Readme != nil {
-		readme = *ver.Readme
+	var deprecated string
+	if ver.Deprecated != nil {
+		deprecated = *ver.Deprecated
+	}
+
+	// versions published before raw manifests were stored are serialized from the typed fields only
+	var raw map[string]json.RawMessage
+	if len(ver.RawManifest) > 0 {
+		if err := json.Unmarshal(ver.RawManifest, &raw); err != nil {
+			raw = nil
+		}
 	}
 
-	versions := make(map[string]revision)
-	versions[ver.Version.String()] = revision{
+	return revision{
+		ID:                   packageName.String() + "@" + ver.Version.String(),
 		Name:                 packageName.String(),
 		Version:              ver.Version.String(),
 		Description:          description,
 		Keywords:             fields.StringsFromRequiredStrings(ver.Keywords),
-		Homepage:             ver.Homepage.String(),
+		Homepage:             homepage,
 		Bugs:                 bugsFromFieldBugs(ver.Bugs),
 		License:              license,
 		Author:               authorFromMixedAuthor(ver.Author),
@@ -183,100 +241,176 @@ func manifestFromPackageVersion(packageName fields.RequiredString, ver *entities
 		Private:              ver.Private,
 		PublishConfig:        mapAnyFromMapRequiredStringAny(ver.PublishConfig),
 		Workspaces:           fields.StringsFromRequiredStrings(ver.Workspaces),
-		Dist: dist{
-			Tarball:   "http://localhost:3000/" + url.QueryEscape(packageName.String()) + "/-/" + url.QueryEscape(packageName.String()) + "-" + ver.Version.String() + ".tgz",
-			Integrity: ver.Integrity.String(),
-			SHASUM:    ver.SHASUM.String(),
-		},
+		Maintainers:          maintainers,
+		NpmUser:              authorFromMaintainer(ver.Publisher),
+		Deprecated:           deprecated,
+		Dist:                 distFromPackageVersion(packageName, ver),
+		raw:                  raw,
 	}
+}
+
+func manifestFromPackage(pkg *entities.Package) manifest {
 
-	attachments := make(map[string]attachment)
-	attachments[ver.Version.String()] = attachment{
-		ContentType: ver.ContentType.String(),
-		Data:        ver.Data.String(),
-		Length:      ver.Length,
+	var description string
+	if pkg.Description != nil {
+		description = *pkg.Description
+	}
+	var readme string
+	if pkg.Readme != nil {
+		readme = *pkg.Readme
+	}
+
+	maintainers := maintainersFromEntitiesMaintainers(pkg.Maintainers)
+
+	versions := make(map[string]revision, len(pkg.Versions))
+	times := make(map[string]string, len(pkg.Versions)+2)
+	times["created"] = registryTime(pkg.CreatedAt)
+	times["modified"] = registryTime(pkg.ModifiedAt)
+
+	for _, ver := range pkg.Versions {
+		versions[ver.Version.String()] = revisionFromPackageVersion(pkg.Name, ver, maintainers)
+		times[ver.Version.String()] = registryTime(ver.CreatedAt)
 	}
-	m := manifest{
-		Name:        packageName.String(),
+
+	distTags := make(map[string]string, len(pkg.DistTags))
+	for tag, ver := range pkg.DistTags {
+		distTags[tag.String()] = ver.String()
+	}
+
+	return manifest{
+		ID:          pkg.Name.String(),
+		Rev:         pkg.Rev(),
+		Name:        pkg.Name.String(),
 		Description: description,
 		Readme:      readme,
 		Versions:    versions,
-		Attachments: attachments,
-		DistTags:    map[string]string{},
+		DistTags:    distTags,
+		Time:        times,
+		Maintainers: maintainers,
 	}
+}
 
-	return m
+func maintainerFromEntUser(user *ent.User) (*entities.Maintainer, error) {
+	if user == nil {
+		return nil, nil
+	}
 
+	name, err := fields.UsernameFromString(user.Name)
+	if err != nil {
+		return nil, &InvalidPackageVersionFieldErrror{Field: "maintainer.name", Rearson: err.Error()}
+	}
+
+	email, err := fields.EmailFromString(user.Email)
+	if err != nil {
+		return nil, &InvalidPackageVersionFieldErrror{Field: "maintainer.email", Rearson: err.Error()}
+	}
+
+	return &entities.Maintainer{
+		Name:  name,
+		Email: email,
+	}, nil
 }
 
-func manifestFromEntVersion(packageName string, ver *ent.Version) manifest {
-	versions := make(map[string]revision)
-	versions[ver.Version] = revision{
-		Name:                 packageName,
-		Version:              ver.Version,
-		Description:          ver.Description,
-		Keywords:             ver.Keywords,
-		Homepage:             ver.Homepage,
-		Bugs:                 bugsFromFieldBugs(ver.Bugs),
-		License:              ver.License,
-		Author:               authorFromMixedAuthor(ver.Author),
-		Contributors:         contributorsFromMixedAuthors(ver.Contributors),
-		Funding:              fundingFromMixedFunding(ver.Funding),
-		Files:                ver.Files,
-		Main:                 ver.Main,
-		Browser:              ver.Browser,
-		Bin:                  stringMapFromRequiredStringMap(ver.Bin),
-		Man:                  fields.StringsFromRequiredStrings(ver.Man),
-		Directories:          directoriesFromFieldDirectories(ver.Directories),
-		Repository:           repositoryFromFrieldRepository(ver.Repository),
-		Scripts:              stringMapFromRequiredStringMap(ver.Scripts),
-		Config:               stringMapFromRequiredStringMap(ver.Config),
-		Dependencies:         stringMapFromRequiredStringMap(ver.Dependencies),
-		DevDependencies:      stringMapFromRequiredStringMap(ver.DevDependencies),
-		PeerDependencies:     stringMapFromRequiredStringMap(ver.PeerDependencies),
-		PeerDependenciesMeta: peerDependenciesMetaFromFieldPeerDependenciesMeta(ver.PeerDependenciesMeta),
-		BundledDependencies:  ver.BundledDependencies,
-		OptionalDependencies: stringMapFromRequiredStringMap(ver.OptionalDependencies),
-		Engines:              stringMapFromRequiredStringMap(ver.Engines),
-		OS:                   ver.Os,
-		CPU:                  ver.CPU,
-		Private:              &ver.Private,
-		PublishConfig:        mapAnyFromMapRequiredStringAny(ver.PublishConfig),
-		Workspaces:           ver.Workspaces,
-		Dist: dist{
-			Tarball: "http://localhost:3000/" + url.QueryEscape(packageName) + "/-/" + url.QueryEscape(packageName) + "-" + ver.Version + ".tgz",
+// packageFromEntRepoPackage converts a package with its loaded versions (ordered by creation date),
+// dist-tags, creator and publishers into a packument.
+func packageFromEntRepoPackage(pkg *ent.RepoPackage) (*entities.Package, error) {
+
+	name, err := fields.PackageNameFromString(pkg.Name)
+	if err != nil {
+		return nil, &InvalidPackageVersionFieldErrror{Field: "name", Rearson: err.Error()}
+	}
 
-			Integrity: ver.Integrity,
-			SHASUM:    ver.Shasum,
-		},
+	packument := &entities.Package{
+		Name:       name,
+		Versions:   make([]*entities.PackageVersion, 0, len(pkg.Edges.Versions)),
+		DistTags:   make(map[fields.RequiredString]fields.RequiredString),
+		CreatedAt:  pkg.CreatedAt,
+		ModifiedAt: pkg.CreatedAt,
 	}
 
-	attachments := make(map[string]attachment)
-	attachments[ver.Version] = attachment{
-		ContentType: ver.ContentType,
-		Data:        ver.Data,
-		Length:      ver.Length,
+	if pkg.UpdatedAt != nil {
+		packument.ModifiedAt = *pkg.UpdatedAt
 	}
-	m := manifest{
-		Name:        packageName,
-		Description: ver.Description,
-		Readme:      ver.Readme,
-		Versions:    versions,
-		Attachments: attachments,
-		DistTags:    map[string]string{},
+
+	seenMaintainers := make(map[fields.Username]bool)
+	addMaintainer := func(user *ent.User) error {
+		maintainer, err := maintainerFromEntUser(user)
+		if err != nil || maintainer == nil {
+			return err
+		}
+		if !seenMaintainers[maintainer.Name] {
+			seenMaintainers[maintainer.Name] = true
+			packument.Maintainers = append(packument.Maintainers, *maintainer)
+		}
+		return nil
+	}
+
+	if err := addMaintainer(pkg.Edges.Creator); err != nil {
+		return nil, err
 	}
 
-	return m
+	var latest *entities.PackageVersion
+	versionsByID := make(map[int]*entities.PackageVersion, len(pkg.Edges.Versions))
+	for _, v := range pkg.Edges.Versions {
+		// versions stored before semver validation can't be installed, they must not hide the others
+		if _, err := fields.VersionFromString(v.Version); err != nil {
+			log.Printf("skipping invalid version %s of package %s: %v", v.Version, pkg.Name, err)
+			continue
+		}
+
+		ver, err := packageVersionFromEntVersion(pkg.Name, v)
+		if err != nil {
+			return nil, err
+		}
+		if err := addMaintainer(v.Edges.Publisher); err != nil {
+			return nil, err
+		}
+
+		if v.CreatedAt.After(packument.ModifiedAt) {
+			packument.ModifiedAt = v.CreatedAt
+		}
+		if v.UpdatedAt != nil && v.UpdatedAt.After(packument.ModifiedAt) {
+			packument.ModifiedAt = *v.UpdatedAt
+		}
+
+		packument.Versions = append(packument.Versions, ver)
+		versionsByID[v.ID] = ver
+		latest = ver
+	}
+
+	for _, tag := range pkg.Edges.DistTags {
+		// tags pointing to deleted versions are not part of the packument
+		ver, ok := versionsByID[tag.VersionID]
+		if !ok {
+			continue
+		}
+		t, err := fields.RequiredStringFromString(tag.Tag)
+		if err != nil {
+			return nil, &InvalidPackageVersionFieldErrror{Field: "dist-tags", Rearson: err.Error()}
+		}
+		packument.DistTags[t] = fields.RequiredString(ver.Version.String())
+		if t == "latest" {
+			latest = ver
+		}
+	}
+
+	if latest != nil {
+		packument.Description = latest.Description
+		packument.Readme = latest.Readme
+		packument.DistTags["latest"] = fields.RequiredString(latest.Version.String())
+	}
+
+	return packument, nil
 }
 
 func packageVersionFromEntVersion(packageName string, ver *ent.Version) (*entities.PackageVersion, error) {
 
-	name, err := fields.RequiredStringFromString(packageName)
+	name, err := fields.PackageNameFromString(packageName)
 	if err != nil {
 		return nil, &InvalidPackageVersionFieldErrror{Field: "name", Rearson: err.Error()}
 	}
 
-	version, err := fields.RequiredStringFromString(ver.Version)
+	version, err := fields.VersionFromString(ver.Version)
 	if err != nil {
 		return nil, &InvalidPackageVersionFieldErrror{Field: "version", Rearson: err.Error()}
 	}
@@ -320,6 +454,11 @@ func packageVersionFromEntVersion(packageName string, ver *ent.Version) (*entiti
 		readme = &ver.Readme
 	}
 
+	var deprecated *string
+	if ver.Deprecated != "" {
+		deprecated = &ver.Deprecated
+	}
+
 	var homepage fields.Website
 	if ver.Homepage != "" {
 		homepage, err = fields.WebsiteFromString(ver.Homepage)
@@ -383,9 +522,17 @@ func packageVersionFromEntVersion(packageName string, ver *ent.Version) (*entiti
 		return nil, &InvalidPackageVersionFieldErrror{Field: "contentType", Rearson: err.Error()}
 	}
 
-	data, err := fields.RequiredStringFromString(ver.Data)
+	var tarballDigest fields.Digest
+	if ver.TarballDigest != "" {
+		tarballDigest, err = fields.DigestFromString(ver.TarballDigest)
+		if err != nil {
+			return nil, &InvalidPackageVersionFieldErrror{Field: "tarball_digest", Rearson: err.Error()}
+		}
+	}
+
+	publisher, err := maintainerFromEntUser(ver.Edges.Publisher)
 	if err != nil {
-		return nil, &InvalidPackageVersionFieldErrror{Field: "data", Rearson: err.Error()}
+		return nil, err
 	}
 
 	return &entities.PackageVersion{
@@ -421,12 +568,17 @@ func packageVersionFromEntVersion(packageName string, ver *ent.Version) (*entiti
 		PublishConfig:        ver.PublishConfig,
 		Workspaces:           worspaces,
 
-		Integrity:   integrity,
-		SHASUM:      shasum,
-		ContentType: contentType,
-		Data:        data,
-		Length:      ver.Length,
-		Readme:      readme,
+		Integrity:     integrity,
+		SHASUM:        shasum,
+		ContentType:   contentType,
+		TarballDigest: tarballDigest,
+		RawManifest:   []byte(ver.RawManifest),
+		Length:        ver.Length,
+		Readme:        readme,
+		Deprecated:    deprecated,
+
+		Publisher: publisher,
+		CreatedAt: ver.CreatedAt,
 	}, nil
 
 }
@@ -0,0 +1,145 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/disttag"
+	"github.com/mrparano1d/noxite/ent/version"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+func (s *StorageEntAdapter) UnpublishVersion(ctx context.Context, name fields.PackageName, ver fields.Version) error {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = s.withTx(ctx, func(tx *StorageEntAdapter) error {
+		v, err := tx.entClient.Version.Query().
+			Where(version.PackageIDEQ(pkg.ID), version.VersionEQ(ver.String()), version.DeletedAtIsNil()).
+			Only(ctx)
+		if err != nil {
+			if ent.IsNotFound(err) {
+				return &ports.StorageAdapterPackageNotFoundError{
+					Name:    name,
+					Version: fields.RequiredString(ver.String()),
+				}
+			}
+			return err
+		}
+
+		if err := v.Update().SetDeletedAt(now).SetUpdatedAt(now).Exec(ctx); err != nil {
+			return err
+		}
+
+		remaining, err := tx.entClient.Version.Query().
+			Where(version.PackageIDEQ(pkg.ID), version.DeletedAtIsNil()).
+			All(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to query remaining versions: %w", err)
+		}
+
+		if len(remaining) == 0 {
+			return tx.deletePackage(ctx, pkg.ID, now)
+		}
+
+		if err := tx.moveDistTags(ctx, v.ID, remaining, now); err != nil {
+			return err
+		}
+
+		return tx.entClient.RepoPackage.UpdateOneID(pkg.ID).SetUpdatedAt(now).Exec(ctx)
+	})
+	if err != nil {
+		if notFoundErr, ok := err.(*ports.StorageAdapterPackageNotFoundError); ok {
+			return notFoundErr
+		}
+		return &ports.StorageAdapterUnpublishError{
+			Name:    name,
+			Version: fields.RequiredString(ver.String()),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// moveDistTags moves the latest tag of the deleted version to the highest remaining version and removes its other tags.
+func (s *StorageEntAdapter) moveDistTags(ctx context.Context, deletedID int, remaining []*ent.Version, now time.Time) error {
+	if _, err := s.entClient.DistTag.Delete().Where(disttag.VersionIDEQ(deletedID), disttag.TagNEQ("latest")).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete dist-tags: %w", err)
+	}
+
+	ids := make(map[string]int, len(remaining))
+	versions := make([]fields.Version, 0, len(remaining))
+	for _, v := range remaining {
+		parsed, err := fields.VersionFromString(v.Version)
+		if err != nil {
+			continue
+		}
+		ids[parsed.String()] = v.ID
+		versions = append(versions, parsed)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no valid version left to tag latest")
+	}
+
+	err := s.entClient.DistTag.Update().
+		Where(disttag.VersionIDEQ(deletedID), disttag.TagEQ("latest")).
+		SetVersionID(ids[fields.HighestVersion(versions).String()]).
+		SetUpdatedAt(now).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to move latest dist-tag: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StorageEntAdapter) UnpublishPackage(ctx context.Context, name fields.PackageName) error {
+	pkg, err := s.queryActivePackage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = s.withTx(ctx, func(tx *StorageEntAdapter) error {
+		return tx.deletePackage(ctx, pkg.ID, now)
+	})
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return &ports.StorageAdapterPackageNotFoundError{
+				Name: name,
+			}
+		}
+		return &ports.StorageAdapterUnpublishError{
+			Name: name,
+			Err:  err,
+		}
+	}
+
+	return nil
+}
+
+// deletePackage marks the package and all of its versions as deleted and removes its dist-tags.
+func (s *StorageEntAdapter) deletePackage(ctx context.Context, packageID int, now time.Time) error {
+	if _, err := s.entClient.DistTag.Delete().Where(disttag.PackageIDEQ(packageID)).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete dist-tags: %w", err)
+	}
+
+	if err := s.entClient.Version.Update().
+		Where(version.PackageIDEQ(packageID), version.DeletedAtIsNil()).
+		SetDeletedAt(now).
+		SetUpdatedAt(now).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete versions: %w", err)
+	}
+
+	return s.entClient.RepoPackage.UpdateOneID(packageID).SetDeletedAt(now).SetUpdatedAt(now).Exec(ctx)
+}
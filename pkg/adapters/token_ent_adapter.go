@@ -0,0 +1,234 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/accesstoken"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+type TokenEntAdapter struct {
+	entClient *ent.Client
+}
+
+var _ ports.TokenPort = (*TokenEntAdapter)(nil)
+
+func NewTokenEntAdapter(entClient *ent.Client) *TokenEntAdapter {
+	return &TokenEntAdapter{
+		entClient: entClient,
+	}
+}
+
+func (a *TokenEntAdapter) CreateToken(ctx context.Context, token *entities.AccessToken) (*entities.AccessToken, error) {
+	created, err := a.entClient.AccessToken.Create().
+		SetKey(token.Key.String()).
+		SetDisplayPrefix(token.DisplayPrefix).
+		SetReadonly(token.Readonly).
+		SetAutomation(token.Automation).
+		SetCidrWhitelist(stringsFromCIDRs(token.CIDRWhitelist)).
+		SetPackages(stringsFromPackageNames(token.Restriction.Packages)).
+		SetScopes(stringsFromPackageScopes(token.Restriction.Scopes)).
+		SetNillableExpiresAt(token.ExpiresAt).
+		SetUserID(token.UserID.Int()).
+		Save(ctx)
+	if err != nil {
+		return nil, &ports.TokenAdapterCreateTokenError{
+			UserID: token.UserID,
+			Err:    err,
+		}
+	}
+
+	result, err := accessTokenFromEntAccessToken(created)
+	if err != nil {
+		return nil, &ports.TokenAdapterCreateTokenError{
+			UserID: token.UserID,
+			Err:    err,
+		}
+	}
+
+	return result, nil
+}
+
+func (a *TokenEntAdapter) GetToken(ctx context.Context, key fields.AccessTokenKey) (*entities.AccessToken, error) {
+	token, err := a.entClient.AccessToken.Query().
+		Where(accesstoken.KeyEQ(key.String()), accesstoken.DeletedAtIsNil()).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.TokenAdapterTokenNotFoundError{
+				Key: key,
+			}
+		}
+		return nil, &ports.TokenAdapterGetTokenError{
+			Err: err,
+		}
+	}
+
+	result, err := accessTokenFromEntAccessToken(token)
+	if err != nil {
+		return nil, &ports.TokenAdapterGetTokenError{
+			Err: err,
+		}
+	}
+
+	return result, nil
+}
+
+func (a *TokenEntAdapter) ListTokens(ctx context.Context, userID fields.EntityID) ([]*entities.AccessToken, error) {
+	tokens, err := a.entClient.AccessToken.Query().
+		Where(accesstoken.UserIDEQ(userID.Int()), accesstoken.DeletedAtIsNil()).
+		Order(ent.Desc(accesstoken.FieldCreatedAt)).
+		All(ctx)
+	if err != nil {
+		return nil, &ports.TokenAdapterGetTokenError{
+			Err: err,
+		}
+	}
+
+	result := make([]*entities.AccessToken, 0, len(tokens))
+	for _, token := range tokens {
+		t, err := accessTokenFromEntAccessToken(token)
+		if err != nil {
+			return nil, &ports.TokenAdapterGetTokenError{
+				Err: err,
+			}
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func (a *TokenEntAdapter) RevokeToken(ctx context.Context, userID fields.EntityID, key fields.AccessTokenKey) error {
+	now := time.Now()
+
+	revoked, err := a.entClient.AccessToken.Update().
+		Where(accesstoken.UserIDEQ(userID.Int()), accesstoken.KeyEQ(key.String()), accesstoken.DeletedAtIsNil()).
+		SetDeletedAt(now).
+		SetUpdatedAt(now).
+		Save(ctx)
+	if err != nil {
+		return &ports.TokenAdapterRevokeTokenError{
+			Key: key,
+			Err: err,
+		}
+	}
+
+	if revoked == 0 {
+		return &ports.TokenAdapterTokenNotFoundError{
+			Key: key,
+		}
+	}
+
+	return nil
+}
+
+func (a *TokenEntAdapter) TouchToken(ctx context.Context, key fields.AccessTokenKey, usedAt time.Time) error {
+	updated, err := a.entClient.AccessToken.Update().
+		Where(accesstoken.KeyEQ(key.String()), accesstoken.DeletedAtIsNil()).
+		SetLastUsedAt(usedAt).
+		Save(ctx)
+	if err != nil {
+		return &ports.TokenAdapterUpdateTokenError{
+			Key: key,
+			Err: err,
+		}
+	}
+
+	if updated == 0 {
+		return &ports.TokenAdapterTokenNotFoundError{
+			Key: key,
+		}
+	}
+
+	return nil
+}
+
+func accessTokenFromEntAccessToken(token *ent.AccessToken) (*entities.AccessToken, error) {
+	id, err := fields.EntityIDFromInt(token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token id: %w", err)
+	}
+
+	userID, err := fields.EntityIDFromInt(token.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token user id: %w", err)
+	}
+
+	key, err := fields.AccessTokenKeyFromString(token.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token key: %w", err)
+	}
+
+	cidrs := make([]fields.CIDR, 0, len(token.CidrWhitelist))
+	for _, c := range token.CidrWhitelist {
+		cidr, err := fields.CIDRFromString(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access token cidr: %w", err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	restriction := entities.PackageRestriction{
+		Packages: make([]fields.PackageName, 0, len(token.Packages)),
+		Scopes:   make([]fields.PackageScope, 0, len(token.Scopes)),
+	}
+	for _, p := range token.Packages {
+		name, err := fields.PackageNameFromString(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access token package: %w", err)
+		}
+		restriction.Packages = append(restriction.Packages, name)
+	}
+	for _, s := range token.Scopes {
+		scope, err := fields.PackageScopeFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access token scope: %w", err)
+		}
+		restriction.Scopes = append(restriction.Scopes, scope)
+	}
+
+	return &entities.AccessToken{
+		ID:            id,
+		UserID:        userID,
+		Key:           key,
+		DisplayPrefix: token.DisplayPrefix,
+		Readonly:      token.Readonly,
+		Automation:    token.Automation,
+		CIDRWhitelist: cidrs,
+		Restriction:   restriction,
+		ExpiresAt:     token.ExpiresAt,
+		CreatedAt:     token.CreatedAt,
+		UpdatedAt:     token.UpdatedAt,
+		LastUsedAt:    token.LastUsedAt,
+	}, nil
+}
+
+func stringsFromCIDRs(cidrs []fields.CIDR) []string {
+	s := make([]string, len(cidrs))
+	for i, cidr := range cidrs {
+		s[i] = cidr.String()
+	}
+	return s
+}
+
+func stringsFromPackageNames(names []fields.PackageName) []string {
+	s := make([]string, len(names))
+	for i, name := range names {
+		s[i] = name.String()
+	}
+	return s
+}
+
+func stringsFromPackageScopes(scopes []fields.PackageScope) []string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = scope.String()
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/ent"
+	"github.com/mrparano1d/noxite/ent/twofactor"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+type TwoFactorEntAdapter struct {
+	entClient *ent.Client
+}
+
+var _ ports.TwoFactorPort = (*TwoFactorEntAdapter)(nil)
+
+func NewTwoFactorEntAdapter(entClient *ent.Client) *TwoFactorEntAdapter {
+	return &TwoFactorEntAdapter{
+		entClient: entClient,
+	}
+}
+
+func (a *TwoFactorEntAdapter) GetTwoFactor(ctx context.Context, userID fields.EntityID) (*entities.TwoFactor, error) {
+	tf, err := a.entClient.TwoFactor.Query().
+		Where(twofactor.UserIDEQ(userID.Int())).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.TwoFactorAdapterNotFoundError{
+				UserID: userID,
+			}
+		}
+		return nil, &ports.TwoFactorAdapterGetError{
+			UserID: userID,
+			Err:    err,
+		}
+	}
+
+	result, err := twoFactorFromEntTwoFactor(tf)
+	if err != nil {
+		return nil, &ports.TwoFactorAdapterGetError{
+			UserID: userID,
+			Err:    err,
+		}
+	}
+
+	return result, nil
+}
+
+func (a *TwoFactorEntAdapter) SaveTwoFactor(ctx context.Context, tf *entities.TwoFactor) error {
+	recoveryCodes := make([]string, len(tf.RecoveryCodes))
+	for i, code := range tf.RecoveryCodes {
+		recoveryCodes[i] = code.String()
+	}
+
+	updated, err := a.entClient.TwoFactor.Update().
+		Where(twofactor.UserIDEQ(tf.UserID.Int())).
+		SetSecret(tf.Secret.String()).
+		SetMode(tf.Mode.String()).
+		SetPending(tf.Pending).
+		SetRecoveryCodes(recoveryCodes).
+		SetLastUsedStep(tf.LastUsedStep).
+		SetUpdatedAt(time.Now()).
+		Save(ctx)
+	if err != nil {
+		return &ports.TwoFactorAdapterSaveError{
+			UserID: tf.UserID,
+			Err:    err,
+		}
+	}
+
+	if updated > 0 {
+		return nil
+	}
+
+	err = a.entClient.TwoFactor.Create().
+		SetUserID(tf.UserID.Int()).
+		SetSecret(tf.Secret.String()).
+		SetMode(tf.Mode.String()).
+		SetPending(tf.Pending).
+		SetRecoveryCodes(recoveryCodes).
+		SetLastUsedStep(tf.LastUsedStep).
+		Exec(ctx)
+	if err != nil {
+		return &ports.TwoFactorAdapterSaveError{
+			UserID: tf.UserID,
+			Err:    err,
+		}
+	}
+
+	return nil
+}
+
+func (a *TwoFactorEntAdapter) DeleteTwoFactor(ctx context.Context, userID fields.EntityID) error {
+	deleted, err := a.entClient.TwoFactor.Delete().
+		Where(twofactor.UserIDEQ(userID.Int())).
+		Exec(ctx)
+	if err != nil {
+		return &ports.TwoFactorAdapterSaveError{
+			UserID: userID,
+			Err:    err,
+		}
+	}
+
+	if deleted == 0 {
+		return &ports.TwoFactorAdapterNotFoundError{
+			UserID: userID,
+		}
+	}
+
+	return nil
+}
+
+func twoFactorFromEntTwoFactor(tf *ent.TwoFactor) (*entities.TwoFactor, error) {
+	userID, err := fields.EntityIDFromInt(tf.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid two-factor user id: %w", err)
+	}
+
+	mode, err := fields.TwoFactorModeFromString(tf.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]fields.RecoveryCodeKey, len(tf.RecoveryCodes))
+	for i, code := range tf.RecoveryCodes {
+		recoveryCodes[i] = fields.RecoveryCodeKey(code)
+	}
+
+	return &entities.TwoFactor{
+		UserID:        userID,
+		Secret:        fields.TOTPSecret(tf.Secret),
+		Mode:          mode,
+		Pending:       tf.Pending,
+		RecoveryCodes: recoveryCodes,
+		LastUsedStep:  tf.LastUsedStep,
+		CreatedAt:     tf.CreatedAt,
+		UpdatedAt:     tf.UpdatedAt,
+	}, nil
+}
@@ -14,24 +14,39 @@ import (
 
 type UserAdapter struct {
 	entClient *ent.Client
+	hasher    ports.PasswordHasherPort
 }
 
 var _ ports.UserPort = (*UserAdapter)(nil)
 
-func NewUserAdapter(entClient *ent.Client) *UserAdapter {
-	return &UserAdapter{entClient: entClient}
+func NewUserAdapter(entClient *ent.Client, hasher ports.PasswordHasherPort) *UserAdapter {
+	return &UserAdapter{entClient: entClient, hasher: hasher}
 }
 
 func (u *UserAdapter) CreateUser(ctx context.Context, createUser ports.CreateUserInput) (fields.EntityID, error) {
 
+	hash, err := u.hasher.Hash(createUser.Password)
+	if err != nil {
+		return fields.EntityID(0), &ports.UserAdapterCreateUserFailedError{
+			Err: err,
+		}
+	}
+
 	user, err := u.entClient.User.Create().
 		SetName(createUser.Username.String()).
 		SetEmail(createUser.Email.String()).
-		SetPassword(createUser.Password).
+		SetPassword([]byte(hash.String())).
 		SetRoleID(createUser.RoleID.Int()).
+		SetAuthSource(createUser.AuthSource).
 		Save(ctx)
 
 	if err != nil {
+		if ent.IsConstraintError(err) {
+			return fields.EntityID(0), &ports.UserAdapterUserAlreadyExistsError{
+				Username: createUser.Username,
+				Email:    createUser.Email,
+			}
+		}
 		return fields.EntityID(0), &ports.UserAdapterCreateUserFailedError{
 			Err: err,
 		}
@@ -49,9 +64,15 @@ func (u *UserAdapter) CreateUser(ctx context.Context, createUser ports.CreateUse
 
 func (u *UserAdapter) GetUserByID(ctx context.Context, userID fields.EntityID) (*entities.User, error) {
 
-	user, err := u.entClient.User.Query().WithRole().Where(user.DeletedAtIsNil()).Only(ctx)
+	user, err := u.entClient.User.Query().WithRole().Where(user.ID(userID.Int()), user.DeletedAtIsNil()).Only(ctx)
 	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.UserAdapterUserNotFoundError{
+				ID: userID,
+			}
+		}
 		return nil, &ports.UserAdapterGetUserByIDFailedError{
+			ID:  userID,
 			Err: err,
 		}
 	}
@@ -59,6 +80,24 @@ func (u *UserAdapter) GetUserByID(ctx context.Context, userID fields.EntityID) (
 	return UserFromEntUser(user)
 }
 
+func (u *UserAdapter) GetUserByUsername(ctx context.Context, username fields.Username) (*entities.User, error) {
+
+	usr, err := u.entClient.User.Query().WithRole().Where(user.Name(username.String()), user.DeletedAtIsNil()).Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			return nil, &ports.UserAdapterUsernameNotFoundError{
+				Username: username,
+			}
+		}
+		return nil, &ports.UserAdapterGetUserByUsernameFailedError{
+			Username: username,
+			Err:      err,
+		}
+	}
+
+	return UserFromEntUser(usr)
+}
+
 func (u *UserAdapter) GetAllUsers(ctx context.Context) ([]*entities.User, error) {
 
 	users, err := u.entClient.User.Query().WithRole().Where(user.DeletedAtIsNil()).All(ctx)
@@ -97,7 +136,14 @@ func (u *UserAdapter) UpdateUser(ctx context.Context, id fields.EntityID, update
 	}
 
 	if updateUser.Password != nil {
-		query = query.SetPassword(updateUser.Password.Bytes())
+		hash, err := u.hasher.Hash(*updateUser.Password)
+		if err != nil {
+			return &ports.UserAdapterUpdateUserFailedError{
+				ID:  id,
+				Err: err,
+			}
+		}
+		query = query.SetPassword([]byte(hash.String()))
 	}
 
 	if updateUser.RoleID != nil {
@@ -155,3 +201,38 @@ func (u *UserAdapter) FindUsersByEmailAddress(ctx context.Context, emails []fiel
 
 	return result, nil
 }
+
+// MigratePlaintextPasswords hashes the passwords of users that were stored before passwords were hashed.
+// It returns the number of migrated users and is safe to run on every startup.
+func (u *UserAdapter) MigratePlaintextPasswords(ctx context.Context) (int, error) {
+	users, err := u.entClient.User.Query().
+		Select(user.FieldID, user.FieldPassword).
+		All(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query users: %w", err)
+	}
+
+	migrated := 0
+	for _, usr := range users {
+		if IsPasswordHash(fields.PasswordHash(usr.Password)) {
+			continue
+		}
+
+		hash, err := u.hasher.Hash(fields.Password(usr.Password))
+		if err != nil {
+			return migrated, fmt.Errorf("failed to hash password of user %d: %w", usr.ID, err)
+		}
+
+		// the password condition keeps a concurrent password change from being overwritten
+		updated, err := u.entClient.User.Update().
+			Where(user.ID(usr.ID), user.Password(usr.Password)).
+			SetPassword([]byte(hash.String())).
+			Save(ctx)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to store password hash of user %d: %w", usr.ID, err)
+		}
+		migrated += updated
+	}
+
+	return migrated, nil
+}
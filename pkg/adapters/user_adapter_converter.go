@@ -17,11 +17,6 @@ func UserFromEntUser(user *ent.User) (*entities.User, error) {
 		return nil, err
 	}
 
-	password, err := fields.PasswordFromBytes(user.Password)
-	if err != nil {
-		return nil, err
-	}
-
 	id, err := fields.EntityIDFromInt(user.ID)
 	if err != nil {
 		return nil, err
@@ -33,13 +28,13 @@ func UserFromEntUser(user *ent.User) (*entities.User, error) {
 	}
 
 	return &entities.User{
-		ID:        id,
-		Username:  username,
-		Email:     email,
-		Password:  password,
-		Role:      role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		DeletedAt: user.DeletedAt,
+		ID:         id,
+		Username:   username,
+		Email:      email,
+		Role:       role,
+		AuthSource: user.AuthSource,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+		DeletedAt:  user.DeletedAt,
 	}, nil
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -12,6 +15,9 @@ import (
 	"github.com/mrparano1d/noxite/pkg/app/auth"
 	"github.com/mrparano1d/noxite/pkg/app/handler"
 	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+	"github.com/mrparano1d/noxite/pkg/core/services"
 	"github.com/mrparano1d/noxite/pkg/graphql"
 	"github.com/redis/go-redis/v9"
 
@@ -43,6 +49,394 @@ func EntClient() *ent.Client {
 	return entClient
 }
 
+// BlobAdapter creates the blob storage for tarballs selected by BLOB_STORAGE, which is either "fs" (default) or "s3".
+func BlobAdapter(ctx context.Context) (ports.BlobPort, error) {
+	switch storage := os.Getenv("BLOB_STORAGE"); storage {
+	case "", "fs":
+		root := os.Getenv("BLOB_FS_ROOT")
+		if root == "" {
+			root = "./data/blobs"
+		}
+		return adapters.NewBlobFSAdapter(root)
+	case "s3":
+		return adapters.NewBlobS3Adapter(ctx, adapters.BlobS3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") == "true",
+			Prefix:    os.Getenv("S3_PREFIX"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob storage %s", storage)
+	}
+}
+
+// SessionAdapter creates the session store selected by SESSION_STORE, which is "redis" (default), "database" or "memory".
+// Redis is reached at REDIS_ADDR. Sessions end SESSION_ABSOLUTE_TTL after the login or SESSION_IDLE_TTL after their
+// last use like "720h" and "24h", an idle ttl of "0" lets sessions last until the absolute ttl.
+func SessionAdapter(entClient *ent.Client) (ports.SessionPort, error) {
+	timeouts := adapters.DefaultSessionTimeouts
+	if value := os.Getenv("SESSION_ABSOLUTE_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid SESSION_ABSOLUTE_TTL %s", value)
+		}
+		timeouts.Absolute = ttl
+	}
+	if value := os.Getenv("SESSION_IDLE_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid SESSION_IDLE_TTL %s", value)
+		}
+		timeouts.Idle = ttl
+	}
+
+	switch store := os.Getenv("SESSION_STORE"); store {
+	case "", "redis":
+		return adapters.NewSessionAdapter(redisClient(), timeouts), nil
+	case "database":
+		return adapters.NewSessionEntAdapter(entClient, timeouts), nil
+	case "memory":
+		return adapters.NewSessionMemoryAdapter(timeouts), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %s", store)
+	}
+}
+
+// LoginThrottleAdapter creates the store of failed logins selected by LOGIN_THROTTLE_STORE, which is "redis" or
+// "memory". It defaults to Redis if sessions are stored in Redis, so all instances share the failures.
+func LoginThrottleAdapter() (ports.LoginThrottlePort, error) {
+	store := os.Getenv("LOGIN_THROTTLE_STORE")
+	if store == "" {
+		store = "memory"
+		if sessionStore := os.Getenv("SESSION_STORE"); sessionStore == "" || sessionStore == "redis" {
+			store = "redis"
+		}
+	}
+
+	switch store {
+	case "redis":
+		return adapters.NewLoginThrottleRedisAdapter(redisClient()), nil
+	case "memory":
+		return adapters.NewLoginThrottleMemoryAdapter(), nil
+	default:
+		return nil, fmt.Errorf("unknown login throttle store %s", store)
+	}
+}
+
+// LoginThrottleConfig reads the lockout of accounts after LOGIN_LOCKOUT_FAILURES failed logins and of IP addresses
+// after LOGIN_IP_LOCKOUT_FAILURES failed logins for LOGIN_LOCKOUT_DURATION like "15m".
+func LoginThrottleConfig() (services.LoginThrottleConfig, error) {
+	config := services.DefaultLoginThrottleConfig
+
+	if value := os.Getenv("LOGIN_LOCKOUT_FAILURES"); value != "" {
+		failures, err := strconv.Atoi(value)
+		if err != nil || failures <= config.FreeFailures {
+			return config, fmt.Errorf("invalid LOGIN_LOCKOUT_FAILURES %s, must be greater than %d", value, config.FreeFailures)
+		}
+		config.LockoutFailures = failures
+	}
+	if value := os.Getenv("LOGIN_IP_LOCKOUT_FAILURES"); value != "" {
+		failures, err := strconv.Atoi(value)
+		if err != nil || failures < 1 {
+			return config, fmt.Errorf("invalid LOGIN_IP_LOCKOUT_FAILURES %s", value)
+		}
+		config.IPLockoutFailures = failures
+	}
+	if value := os.Getenv("LOGIN_LOCKOUT_DURATION"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return config, fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION %s", value)
+		}
+		config.LockoutDuration = duration
+	}
+
+	return config, nil
+}
+
+// SearchAdapter creates the search index selected by SEARCH_INDEX, which is "database" (default) or "memory".
+// The database index uses the full-text search of Postgres and is shared by all instances, the memory index is
+// rebuilt on every start.
+func SearchAdapter(entClient *ent.Client) (ports.SearchPort, error) {
+	switch index := os.Getenv("SEARCH_INDEX"); index {
+	case "", "database":
+		return adapters.NewSearchEntAdapter(entClient), nil
+	case "memory":
+		return adapters.NewSearchMemoryAdapter(), nil
+	default:
+		return nil, fmt.Errorf("unknown search index %s", index)
+	}
+}
+
+// redisClient connects to the Redis at REDIS_ADDR.
+func redisClient() *redis.Client {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	return redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+}
+
+// expiredSessionsDeleter is implemented by session stores which do not evict expired sessions themselves.
+type expiredSessionsDeleter interface {
+	DeleteExpiredSessions(ctx context.Context) (int, error)
+}
+
+// legacySessionTokensMigrator is implemented by persistent session stores which may hold sessions that were
+// stored under their token before tokens were hashed.
+type legacySessionTokensMigrator interface {
+	MigrateLegacySessionTokens(ctx context.Context) (int, error)
+}
+
+// rebuildSearchIndex indexes all packages again.
+func rebuildSearchIndex(ctx context.Context, searchService *services.SearchService) {
+	indexed, err := searchService.RebuildIndex(ctx)
+	if err != nil {
+		log.Printf("failed to rebuild search index: %v", err)
+		return
+	}
+	log.Printf("indexed %d packages for search", indexed)
+}
+
+// indexPendingPackages indexes the packages whose search document could not be updated after a write every interval
+// until ctx is done.
+func indexPendingPackages(ctx context.Context, searchService *services.SearchService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			indexed, err := searchService.IndexPending(ctx)
+			if err != nil {
+				log.Printf("failed to index packages for search: %v", err)
+			}
+			if indexed > 0 {
+				log.Printf("indexed %d pending packages for search", indexed)
+			}
+		}
+	}
+}
+
+// deleteExpiredSessions deletes the expired sessions every interval until ctx is done.
+func deleteExpiredSessions(ctx context.Context, deleter expiredSessionsDeleter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := deleter.DeleteExpiredSessions(ctx)
+			if err != nil {
+				log.Printf("failed to delete expired sessions: %v", err)
+			} else if deleted > 0 {
+				log.Printf("deleted %d expired sessions", deleted)
+			}
+		}
+	}
+}
+
+// SignupConfig reads the signup policy from SIGNUP_POLICY, which is "disabled" (default), "open", "invite" or "domain".
+// New users get the role SIGNUP_DEFAULT_ROLE_ID, the "domain" policy accepts the comma separated SIGNUP_ALLOWED_DOMAINS.
+func SignupConfig() (services.SignupConfig, error) {
+	policy := fields.SignupPolicyDisabled
+	if value := os.Getenv("SIGNUP_POLICY"); value != "" {
+		var err error
+		if policy, err = fields.SignupPolicyFromString(value); err != nil {
+			return services.SignupConfig{}, err
+		}
+	}
+
+	config := services.SignupConfig{
+		Policy: policy,
+	}
+	if policy == fields.SignupPolicyDisabled {
+		return config, nil
+	}
+
+	roleID, err := fields.EntityIDFromString(os.Getenv("SIGNUP_DEFAULT_ROLE_ID"))
+	if err != nil {
+		return config, fmt.Errorf("SIGNUP_DEFAULT_ROLE_ID is required by signup policy %s: %w", policy, err)
+	}
+	config.DefaultRoleID = roleID
+
+	for _, domain := range strings.Split(os.Getenv("SIGNUP_ALLOWED_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			config.AllowedDomains = append(config.AllowedDomains, domain)
+		}
+	}
+	if policy == fields.SignupPolicyDomain && len(config.AllowedDomains) == 0 {
+		return config, fmt.Errorf("SIGNUP_ALLOWED_DOMAINS is required by signup policy %s", policy)
+	}
+
+	return config, nil
+}
+
+// ExternalAuthAdapters creates the directories users are authenticated against before their local credentials
+// and the directory new users are added to, which is nil unless AUTH_HTPASSWD_APPEND is "true".
+// LDAP is enabled by AUTH_LDAP_URL, AUTH_LDAP_ROLE_RULES maps groups to role IDs like "cn=admins,dc=example,dc=com:1;cn=devs,dc=example,dc=com:2".
+// An htpasswd file is enabled by AUTH_HTPASSWD_FILE, its users get the role AUTH_HTPASSWD_DEFAULT_ROLE_ID on their first login.
+func ExternalAuthAdapters() ([]ports.ExternalAuthPort, ports.ExternalRegistrationPort, error) {
+	var externalAuth []ports.ExternalAuthPort
+	var registration ports.ExternalRegistrationPort
+
+	if ldapURL := os.Getenv("AUTH_LDAP_URL"); ldapURL != "" {
+		config := adapters.LDAPConfig{
+			URL:                ldapURL,
+			StartTLS:           os.Getenv("AUTH_LDAP_START_TLS") == "true",
+			InsecureSkipVerify: os.Getenv("AUTH_LDAP_INSECURE_SKIP_VERIFY") == "true",
+			BindDN:             os.Getenv("AUTH_LDAP_BIND_DN"),
+			BindPassword:       os.Getenv("AUTH_LDAP_BIND_PASSWORD"),
+			BaseDN:             os.Getenv("AUTH_LDAP_BASE_DN"),
+			UserFilter:         os.Getenv("AUTH_LDAP_USER_FILTER"),
+			EmailAttribute:     os.Getenv("AUTH_LDAP_EMAIL_ATTRIBUTE"),
+			GroupAttribute:     os.Getenv("AUTH_LDAP_GROUP_ATTRIBUTE"),
+		}
+
+		if value := os.Getenv("AUTH_LDAP_DEFAULT_ROLE_ID"); value != "" {
+			roleID, err := fields.EntityIDFromString(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid AUTH_LDAP_DEFAULT_ROLE_ID: %w", err)
+			}
+			config.DefaultRoleID = roleID
+		}
+
+		rules, err := roleRules("AUTH_LDAP_ROLE_RULES")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, rule := range rules {
+			config.RoleRules = append(config.RoleRules, adapters.LDAPRoleRule{
+				GroupDN: rule.group,
+				RoleID:  rule.roleID,
+			})
+		}
+
+		externalAuth = append(externalAuth, adapters.NewLDAPAdapter(config))
+	}
+
+	if path := os.Getenv("AUTH_HTPASSWD_FILE"); path != "" {
+		roleID, err := fields.EntityIDFromString(os.Getenv("AUTH_HTPASSWD_DEFAULT_ROLE_ID"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("AUTH_HTPASSWD_DEFAULT_ROLE_ID is required by AUTH_HTPASSWD_FILE: %w", err)
+		}
+
+		htpasswd, err := adapters.NewHtpasswdAdapter(adapters.HtpasswdConfig{
+			Path:          path,
+			DefaultRoleID: roleID,
+			EmailDomain:   os.Getenv("AUTH_HTPASSWD_EMAIL_DOMAIN"),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		externalAuth = append(externalAuth, htpasswd)
+		if os.Getenv("AUTH_HTPASSWD_APPEND") == "true" {
+			registration = htpasswd
+		}
+	}
+
+	return externalAuth, registration, nil
+}
+
+// IdentityProvider creates the OpenID Connect provider of single sign-on, which is enabled by OIDC_ISSUER_URL.
+// It returns a nil provider if single sign-on is disabled. OIDC_ROLE_RULES maps groups to role IDs like "admins:1;devs:2".
+func IdentityProvider(ctx context.Context) (ports.IdentityProviderPort, services.SSOConfig, error) {
+	ssoConfig := services.SSOConfig{
+		WebRedirectURL: os.Getenv("OIDC_WEB_REDIRECT_URL"),
+	}
+
+	issuerURL := os.Getenv("OIDC_ISSUER_URL")
+	if issuerURL == "" {
+		return nil, ssoConfig, nil
+	}
+	if ssoConfig.WebRedirectURL == "" {
+		ssoConfig.WebRedirectURL = "/"
+	}
+
+	config := adapters.OIDCConfig{
+		IssuerURL:     issuerURL,
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		UsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+		EmailClaim:    os.Getenv("OIDC_EMAIL_CLAIM"),
+		GroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+	}
+	if config.RedirectURL == "" {
+		return nil, ssoConfig, fmt.Errorf("OIDC_REDIRECT_URL is required by OIDC_ISSUER_URL")
+	}
+
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		config.Scopes = strings.Fields(scopes)
+	}
+
+	if value := os.Getenv("OIDC_DEFAULT_ROLE_ID"); value != "" {
+		roleID, err := fields.EntityIDFromString(value)
+		if err != nil {
+			return nil, ssoConfig, fmt.Errorf("invalid OIDC_DEFAULT_ROLE_ID: %w", err)
+		}
+		config.DefaultRoleID = roleID
+	}
+
+	rules, err := roleRules("OIDC_ROLE_RULES")
+	if err != nil {
+		return nil, ssoConfig, err
+	}
+	for _, rule := range rules {
+		config.RoleRules = append(config.RoleRules, adapters.OIDCRoleRule{
+			Group:  rule.group,
+			RoleID: rule.roleID,
+		})
+	}
+
+	provider, err := adapters.NewOIDCAdapter(ctx, config)
+	if err != nil {
+		return nil, ssoConfig, err
+	}
+
+	return provider, ssoConfig, nil
+}
+
+type roleRule struct {
+	group  string
+	roleID fields.EntityID
+}
+
+// roleRules parses the environment variable name holding rules like "group:roleID;group:roleID".
+func roleRules(name string) ([]roleRule, error) {
+	var rules []roleRule
+
+	for _, rule := range strings.Split(os.Getenv(name), ";") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		// group DNs contain commas and equal signs, so the role ID follows the last colon
+		sep := strings.LastIndex(rule, ":")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid %s rule %s: missing role id", name, rule)
+		}
+		roleID, err := fields.EntityIDFromString(rule[sep+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rule %s: %w", name, rule, err)
+		}
+		rules = append(rules, roleRule{
+			group:  strings.TrimSpace(rule[:sep]),
+			roleID: roleID,
+		})
+	}
+
+	return rules, nil
+}
+
 func ServeApp() error {
 
 	err := godotenv.Load()
@@ -52,32 +446,122 @@ func ServeApp() error {
 
 	entClient := EntClient()
 
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
-
+	passwordHasher := adapters.NewPasswordHasherAdapter(adapters.DefaultArgon2Params)
 	authAdapter := adapters.NewAuthAdapter(entClient)
-	userAdapter := adapters.NewUserAdapter(entClient)
+	userAdapter := adapters.NewUserAdapter(entClient, passwordHasher)
 	packageAdapter := adapters.NewPackageAdapter(userAdapter)
 	storeAdapter := adapters.NewStorageEntAdapter(entClient)
-	sessionAdapter := adapters.NewSessionAdapter(redisClient)
 	roleAdapter := adapters.NewRoleAdapter(entClient)
 
-	app := core.NewCoreApp(sessionAdapter, authAdapter, packageAdapter, storeAdapter, userAdapter, roleAdapter)
+	sessionAdapter, err := SessionAdapter(entClient)
+	if err != nil {
+		return fmt.Errorf("invalid session store config: %w", err)
+	}
+	if migrator, ok := sessionAdapter.(legacySessionTokensMigrator); ok {
+		rekeyed, err := migrator.MigrateLegacySessionTokens(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to hash legacy session tokens: %w", err)
+		}
+		if rekeyed > 0 {
+			log.Printf("hashed the tokens of %d sessions", rekeyed)
+		}
+	}
+	if deleter, ok := sessionAdapter.(expiredSessionsDeleter); ok {
+		go deleteExpiredSessions(context.Background(), deleter, 10*time.Minute)
+	}
+
+	blobAdapter, err := BlobAdapter(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to create blob storage: %w", err)
+	}
+
+	migrated, err := storeAdapter.MigrateTarballsToBlobStorage(context.Background(), blobAdapter)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tarballs to blob storage: %w", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d tarballs to blob storage", migrated)
+	}
+
+	hashed, err := userAdapter.MigratePlaintextPasswords(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to hash plaintext passwords: %w", err)
+	}
+	if hashed > 0 {
+		log.Printf("hashed %d plaintext passwords", hashed)
+	}
+
+	searchAdapter, err := SearchAdapter(entClient)
+	if err != nil {
+		return fmt.Errorf("invalid search index config: %w", err)
+	}
+	tokenAdapter := adapters.NewTokenEntAdapter(entClient)
+	twoFactorAdapter := adapters.NewTwoFactorEntAdapter(entClient)
+	inviteAdapter := adapters.NewInviteEntAdapter(entClient)
+
+	signupConfig, err := SignupConfig()
+	if err != nil {
+		return fmt.Errorf("invalid signup config: %w", err)
+	}
+
+	externalAuth, externalRegistration, err := ExternalAuthAdapters()
+	if err != nil {
+		return fmt.Errorf("invalid external auth config: %w", err)
+	}
+
+	identityProvider, ssoConfig, err := IdentityProvider(context.Background())
+	if err != nil {
+		return fmt.Errorf("invalid single sign-on config: %w", err)
+	}
+
+	loginThrottleAdapter, err := LoginThrottleAdapter()
+	if err != nil {
+		return fmt.Errorf("invalid login throttle config: %w", err)
+	}
+	loginThrottleConfig, err := LoginThrottleConfig()
+	if err != nil {
+		return fmt.Errorf("invalid login throttle config: %w", err)
+	}
+	auditAdapter := adapters.NewAuditEntAdapter(entClient)
+
+	app := core.NewCoreApp(sessionAdapter, authAdapter, packageAdapter, storeAdapter, userAdapter, roleAdapter, blobAdapter, searchAdapter, tokenAdapter, passwordHasher, twoFactorAdapter, inviteAdapter, signupConfig, externalAuth, externalRegistration, identityProvider, ssoConfig, loginThrottleAdapter, auditAdapter, loginThrottleConfig)
+
+	if _, ok := searchAdapter.(*adapters.SearchMemoryAdapter); ok {
+		indexed, err := app.SearchService().RebuildIndex(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to build search index: %w", err)
+		}
+		log.Printf("indexed %d packages for search", indexed)
+	} else {
+		// the stored index already serves searches, rebuilding it catches up on writes whose indexing was lost
+		go rebuildSearchIndex(context.Background(), app.SearchService())
+	}
+	go indexPendingPackages(context.Background(), app.SearchService(), time.Minute)
+
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
+	r.Use(auth.OTPMiddleware)
+	r.Use(auth.SessionClientMiddleware)
 
 	handler.AuthHandler(r, app)
+	if app.SSOService() != nil {
+		handler.SSOHandler(r, app)
+	}
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth.AuthMiddleware(app))
 		handler.PackageHandler(r, app)
+		handler.DistTagHandler(r, app)
+		handler.SearchHandler(r, app)
+		handler.TokenHandler(r, app)
+		handler.ProfileHandler(r, app)
 	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(graphql.TokenMiddleware)
+		r.Use(graphql.RetryAfterMiddleware)
 		handler.GQLHandler(r, app, entClient)
 	})
 
@@ -3,11 +3,13 @@ package auth
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/mrparano1d/noxite/pkg/core"
 	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
 	"github.com/mrparano1d/noxite/pkg/core/services"
 )
 
@@ -32,16 +34,24 @@ func AuthMiddleware(coreApp *core.ApplicationCore) func(http.Handler) http.Handl
 				return
 			}
 
-			err := coreApp.SessionService().ValidateToken(ctx, token)
-			if err != nil {
-				log.Printf("invalid token: %s\n", err)
-				http.Error(w, err.Error(), http.StatusUnauthorized)
+			if fields.IsAccessToken(token) {
+				user, err := coreApp.TokenService().Authenticate(ctx, token, remoteIP(req))
+				if err != nil {
+					log.Printf("invalid access token: %s\n", err)
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+
+				ctx = context.WithValue(ctx, AuthContextSessionKey, token)
+				ctx = context.WithValue(ctx, AuthContextUserKey, user)
+
+				next.ServeHTTP(w, req.WithContext(ctx))
 				return
 			}
 
-			user, err := services.SessionValueFromService[entities.User](coreApp.SessionService(), ctx, token, "user")
+			user, err := coreApp.SessionService().GetSessionUser(ctx, token)
 			if err != nil {
-				log.Printf("failed to get user from session: %s\n", err)
+				log.Printf("invalid token: %s\n", err)
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
@@ -54,6 +64,44 @@ func AuthMiddleware(coreApp *core.ApplicationCore) func(http.Handler) http.Handl
 	}
 }
 
+// OTPMiddleware passes the one-time password of the npm-otp header to the services.
+func OTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if otp := req.Header.Get("npm-otp"); otp != "" {
+			req = req.WithContext(services.ContextWithOTP(req.Context(), otp))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// maxUserAgentLength bounds the user agent stored in sessions.
+const maxUserAgentLength = 255
+
+// SessionClientMiddleware passes the IP address and user agent of the client to the services, which record them in sessions.
+func SessionClientMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		userAgent := req.UserAgent()
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+		}
+		req = req.WithContext(services.ContextWithSessionClient(req.Context(), entities.SessionClient{
+			IP:        remoteIP(req),
+			UserAgent: userAgent,
+		}))
+		next.ServeHTTP(w, req)
+	})
+}
+
+// remoteIP returns the IP address of the client without its port.
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// GetSessionFromContext returns the session or access token the request was authenticated with.
 func GetSessionFromContext(ctx context.Context) string {
 	return ctx.Value(AuthContextSessionKey).(string)
 }
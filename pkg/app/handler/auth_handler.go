@@ -3,10 +3,12 @@ package handler
 import (
 	"log"
 	"net/http"
+	"strconv"
 
 	json "github.com/bytedance/sonic"
 	"github.com/go-chi/chi/v5"
 	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/services"
 )
 
 type loginReq struct {
@@ -30,8 +32,40 @@ func AuthHandler(r chi.Router, app *core.ApplicationCore) {
 			return
 		}
 
+		ok := "you are authenticated as " + loginReq.Name
+
+		// npm adduser sends an email, npm login does not. Existing users are logged in either way.
+		if loginReq.Email != "" {
+			_, err := app.SignupService().SignUp(r.Context(), services.CreateUserRequest{
+				Username: loginReq.Name,
+				Email:    loginReq.Email,
+				Password: loginReq.Password,
+			})
+			switch err.(type) {
+			case nil:
+				ok = "user " + loginReq.Name + " created"
+			case *services.SignupDisabledError, *services.UserServiceUserAlreadyExistsError:
+			default:
+				handleSignupServiceError(w, err)
+				return
+			}
+		}
+
 		session, err := app.AuthService().Login(r.Context(), loginReq.Name, loginReq.Password)
 		if err != nil {
+			switch e := err.(type) {
+			case *services.TwoFactorRequiredError, *services.TwoFactorInvalidCodeError:
+				writeOTPRequired(w, err)
+				return
+			case *services.AuthServiceLoginThrottledError:
+				writeTooManyRequests(w, e)
+				return
+			case *services.AuthServiceDirectoryUnavailableError:
+				// TODO replace log with proper logging
+				log.Println("login failed", err)
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
 			// TODO replace log with proper logging
 			log.Println("login failed", err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -41,7 +75,7 @@ func AuthHandler(r chi.Router, app *core.ApplicationCore) {
 		w.WriteHeader(http.StatusCreated)
 
 		json.ConfigDefault.NewEncoder(w).Encode(loginRes{
-			OK:    "user " + loginReq.Name + " created",
+			OK:    ok,
 			Token: session.Token.String(),
 		})
 
@@ -49,3 +83,22 @@ func AuthHandler(r chi.Router, app *core.ApplicationCore) {
 		log.Println("login success")
 	})
 }
+
+// writeTooManyRequests answers a throttled login with the seconds after which the client may try again.
+func writeTooManyRequests(w http.ResponseWriter, err *services.AuthServiceLoginThrottledError) {
+	w.Header().Set("Retry-After", strconv.Itoa(err.RetryAfterSeconds()))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
+
+func handleSignupServiceError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *services.SignupNotAllowedError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case *services.UserServiceRequestValidationError:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		// TODO replace log with proper logging
+		log.Println("signup failed: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
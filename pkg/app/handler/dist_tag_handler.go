@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrparano1d/noxite/pkg/app/auth"
+	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+
+	json "github.com/bytedance/sonic"
+)
+
+// DistTagHandler serves the dist-tag endpoints used by `npm dist-tag add/rm/ls`.
+func DistTagHandler(r chi.Router, app *core.ApplicationCore) {
+	for _, route := range packageRoutes {
+		distTagRoutesHandler(r, "/-/package"+route, app)
+	}
+}
+
+func distTagRoutesHandler(r chi.Router, route string, app *core.ApplicationCore) {
+
+	r.Get(route+"/dist-tags", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		writeDistTags(w, r.Context(), app, user, packageNameParam(r))
+	})
+
+	r.Put(route+"/dist-tags", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		packageName := packageNameParam(r)
+
+		var tags map[string]string
+		if err := json.ConfigDefault.NewDecoder(r.Body).Decode(&tags); err != nil {
+			http.Error(w, "invalid dist-tags: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := app.PackageService().ReplaceDistTags(r.Context(), user, packageName, tags); err != nil {
+			handlePackageServiceError(w, "dist-tags update failed: ", err)
+			return
+		}
+
+		writeDistTags(w, r.Context(), app, user, packageName)
+	})
+
+	r.Get(route+"/dist-tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		tags, err := app.PackageService().GetDistTags(r.Context(), user, packageNameParam(r))
+		if err != nil {
+			handlePackageServiceError(w, "dist-tags get failed: ", err)
+			return
+		}
+
+		for tag, version := range tags {
+			if tag.String() == chi.URLParam(r, "tag") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.ConfigDefault.NewEncoder(w).Encode(version.String())
+				return
+			}
+		}
+
+		http.Error(w, "dist-tag not found", http.StatusNotFound)
+	})
+
+	r.Put(route+"/dist-tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		packageName := packageNameParam(r)
+
+		// npm sends the version as a plain JSON string
+		var version string
+		if err := json.ConfigDefault.NewDecoder(r.Body).Decode(&version); err != nil {
+			http.Error(w, "invalid dist-tag version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := app.PackageService().SetDistTag(r.Context(), user, packageName, chi.URLParam(r, "tag"), version); err != nil {
+			handlePackageServiceError(w, "dist-tag set failed: ", err)
+			return
+		}
+
+		writeDistTags(w, r.Context(), app, user, packageName)
+	})
+
+	r.Delete(route+"/dist-tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		packageName := packageNameParam(r)
+
+		if err := app.PackageService().RemoveDistTag(r.Context(), user, packageName, chi.URLParam(r, "tag")); err != nil {
+			handlePackageServiceError(w, "dist-tag remove failed: ", err)
+			return
+		}
+
+		writeDistTags(w, r.Context(), app, user, packageName)
+	})
+}
+
+func writeDistTags(w http.ResponseWriter, ctx context.Context, app *core.ApplicationCore, user *entities.User, packageName string) {
+	tags, err := app.PackageService().GetDistTags(ctx, user, packageName)
+	if err != nil {
+		handlePackageServiceError(w, "dist-tags get failed: ", err)
+		return
+	}
+
+	res := make(map[string]string, len(tags))
+	for tag, version := range tags {
+		res[tag.String()] = version.String()
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		// TODO replace log with proper logging
+		log.Println("dist-tags serialize failed: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -1,16 +1,19 @@
 package handler
 
 import (
-	"encoding/base64"
-	"fmt"
+	"bytes"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mrparano1d/noxite/pkg/app/auth"
 	"github.com/mrparano1d/noxite/pkg/core"
 	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
 	"github.com/mrparano1d/noxite/pkg/core/services"
 
 	json "github.com/bytedance/sonic"
@@ -20,80 +23,105 @@ type publishRes struct {
 	OK string `json:"ok"`
 }
 
+// packageRoutes are the route patterns of a package. The first one matches unscoped and url-encoded
+// scoped names like "@scope%2fname", the second one unencoded scoped names like "@scope/name".
+var packageRoutes = []string{"/{packageName}", "/@{scope}/{packageName}"}
+
+// packageNameParam returns the package name of a request matched by one of the packageRoutes.
+func packageNameParam(r *http.Request) string {
+	name := chi.URLParam(r, "packageName")
+	if scope := chi.URLParam(r, "scope"); scope != "" {
+		return "@" + scope + "/" + name
+	}
+	return name
+}
+
+// abbreviatedManifestContentType is requested by package managers to get only the metadata needed for installs.
+const abbreviatedManifestContentType = "application/vnd.npm.install-v1+json"
+
+// acceptsAbbreviatedManifest reports whether the Accept header of the request lists the abbreviated manifest.
+func acceptsAbbreviatedManifest(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || mediaType != abbreviatedManifestContentType {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
 func PackageHandler(r chi.Router, app *core.ApplicationCore) {
+	for _, route := range packageRoutes {
+		packageRoutesHandler(r, route, app)
+	}
+}
 
-	r.Get("/{packageName}/-/{tarball}", func(w http.ResponseWriter, r *http.Request) {
-		tarball := chi.URLParam(r, "tarball")
+func packageRoutesHandler(r chi.Router, route string, app *core.ApplicationCore) {
 
-		lastIdx := strings.LastIndex(tarball, "-")
-		if lastIdx == -1 {
-			http.Error(w, "invalid package name", http.StatusBadRequest)
+	r.Get(route+"/-/{tarball}", func(w http.ResponseWriter, r *http.Request) {
+		packageName, err := fields.PackageNameFromString(packageNameParam(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		packageName := tarball[:lastIdx]
-		version := tarball[lastIdx+1 : len(tarball)-4]
+		version, err := packageName.VersionFromTarballName(chi.URLParam(r, "tarball"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		user := auth.GetUserFromContext(r.Context())
 
-		pkg, err := app.PackageService().GetPackage(r.Context(), user, packageName, version)
+		pkg, err := app.PackageService().GetPackage(r.Context(), user, packageName.String(), version)
 		if err != nil {
-			if _, ok := err.(*services.PackageServicePackageNotFoundError); ok {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			} else if _, ok := err.(*services.PackageServiceGetPackageError); ok {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			} else if _, ok := err.(*coreerrors.NotAllowedToGetPackageError); ok {
-				http.Error(w, err.Error(), http.StatusForbidden)
-				return
-			}
-			// TODO replace log with proper logging
-			log.Println("package get failed: ", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			handlePackageServiceError(w, "package get failed: ", err)
 			return
 		}
 
-		data, err := base64.StdEncoding.DecodeString(pkg.Data.String())
+		tarball, size, err := app.PackageService().GetTarball(r.Context(), user, pkg)
 		if err != nil {
-			// TODO replace log with proper logging
-			log.Println("package data decode failed: ", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			handlePackageServiceError(w, "tarball get failed: ", err)
 			return
 		}
+		defer tarball.Close()
 
-		w.WriteHeader(http.StatusOK)
 		w.Header().Set("Content-Type", pkg.ContentType.String())
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
 
-		w.Write(data)
+		if _, err := io.Copy(w, tarball); err != nil {
+			// TODO replace log with proper logging
+			log.Println("tarball stream failed: ", err)
+		}
 	})
 
-	r.Get("/{packageName}", func(w http.ResponseWriter, r *http.Request) {
+	r.Get(route, func(w http.ResponseWriter, r *http.Request) {
 
 		user := auth.GetUserFromContext(r.Context())
 
-		packageName := chi.URLParam(r, "packageName")
+		packageName := packageNameParam(r)
 
-		ver, err := app.PackageService().GetPackage(r.Context(), user, packageName, "latest")
+		pkg, err := app.PackageService().GetPackument(r.Context(), user, packageName)
 		if err != nil {
-			if _, ok := err.(*services.PackageServicePackageNotFoundError); ok {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			} else if _, ok := err.(*services.PackageServiceGetPackageError); ok {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			} else if _, ok := err.(*coreerrors.NotAllowedToGetPackageError); ok {
-				http.Error(w, err.Error(), http.StatusForbidden)
-				return
-			}
-			// TODO replace log with proper logging
-			log.Println("package get failed: ", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			handlePackageServiceError(w, "package get failed: ", err)
 			return
 		}
 
-		pkg, err := app.PackageService().SerializeManifest(r.Context(), user, ver)
+		contentType := "application/json"
+		serialize := app.PackageService().SerializeManifest
+		if acceptsAbbreviatedManifest(r) {
+			contentType = abbreviatedManifestContentType
+			serialize = app.PackageService().SerializeAbbreviatedManifest
+		}
+
+		data, err := serialize(r.Context(), user, pkg)
 		if err != nil {
 			// TODO replace log with proper logging
 			log.Println("manifest serialize failed: ", err)
@@ -101,15 +129,45 @@ func PackageHandler(r chi.Router, app *core.ApplicationCore) {
 			return
 		}
 
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Vary", "Accept")
 		w.WriteHeader(http.StatusOK)
-		w.Write(pkg)
+		w.Write(data)
 	})
 
-	r.Put("/{packageName}", func(w http.ResponseWriter, r *http.Request) {
+	r.Put(route, func(w http.ResponseWriter, r *http.Request) {
 
 		user := auth.GetUserFromContext(r.Context())
 
-		manifest, err := app.PackageService().ParseManifest(r.Context(), user, r.Body)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		update, err := app.PackageService().ParsePackument(r.Context(), user, bytes.NewReader(body))
+		if err != nil {
+			handlePackageServiceError(w, "package document parse failed: ", err)
+			return
+		}
+
+		// documents without tarballs change the metadata of existing versions, e.g. "npm deprecate", based on the
+		// revision in their "_rev"
+		if !update.Publish {
+			packageName := packageNameParam(r)
+			if err := app.PackageService().UpdatePackument(r.Context(), user, packageName, update.Rev, update); err != nil {
+				handlePackageServiceError(w, "package update failed: ", err)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.ConfigDefault.NewEncoder(w).Encode(publishRes{
+				OK: "package " + packageName + " updated",
+			})
+			return
+		}
+
+		manifest, err := app.PackageService().ParseManifest(r.Context(), user, bytes.NewReader(body))
 		if err != nil {
 			// TODO replace log with proper logging
 			log.Println("manifest parse failed", err)
@@ -118,9 +176,7 @@ func PackageHandler(r chi.Router, app *core.ApplicationCore) {
 		}
 
 		if err := app.PackageService().PublishPackage(r.Context(), user, manifest); err != nil {
-			// TODO replace log with proper logging
-			log.Println("package publish failed: ", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			handlePackageServiceError(w, "package publish failed: ", err)
 			return
 		}
 
@@ -129,4 +185,96 @@ func PackageHandler(r chi.Router, app *core.ApplicationCore) {
 			OK: "package " + manifest.Name.String() + " published",
 		})
 	})
+
+	// npm unpublish of a single version sends the package document without the version first
+	r.Put(route+"/-rev/{rev}", func(w http.ResponseWriter, r *http.Request) {
+
+		user := auth.GetUserFromContext(r.Context())
+
+		packageName := packageNameParam(r)
+
+		update, err := app.PackageService().ParsePackument(r.Context(), user, r.Body)
+		if err != nil {
+			handlePackageServiceError(w, "package document parse failed: ", err)
+			return
+		}
+
+		if err := app.PackageService().UpdatePackument(r.Context(), user, packageName, chi.URLParam(r, "rev"), update); err != nil {
+			handlePackageServiceError(w, "package update failed: ", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(publishRes{
+			OK: "package " + packageName + " updated",
+		})
+	})
+
+	// ... and deletes the tarball of the version afterwards with the new revision, which only confirms that it is gone
+	r.Delete(route+"/-/{tarball}/-rev/{rev}", func(w http.ResponseWriter, r *http.Request) {
+		packageName, err := fields.PackageNameFromString(packageNameParam(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		version, err := packageName.VersionFromTarballName(chi.URLParam(r, "tarball"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user := auth.GetUserFromContext(r.Context())
+
+		if err := app.PackageService().UnpublishVersion(r.Context(), user, packageName.String(), version, chi.URLParam(r, "rev")); err != nil {
+			handlePackageServiceError(w, "version unpublish failed: ", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(publishRes{
+			OK: "package " + packageName.String() + "@" + version + " unpublished",
+		})
+	})
+
+	r.Delete(route+"/-rev/{rev}", func(w http.ResponseWriter, r *http.Request) {
+
+		user := auth.GetUserFromContext(r.Context())
+
+		packageName := packageNameParam(r)
+
+		if err := app.PackageService().UnpublishPackage(r.Context(), user, packageName, chi.URLParam(r, "rev")); err != nil {
+			handlePackageServiceError(w, "package unpublish failed: ", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(publishRes{
+			OK: "package " + packageName + " unpublished",
+		})
+	})
+}
+
+// handlePackageServiceError writes the status code matching the package service error to the response.
+func handlePackageServiceError(w http.ResponseWriter, logPrefix string, err error) {
+	switch e := err.(type) {
+	case *services.PackageServicePackageNotFoundError, *services.PackageServiceDistTagNotFoundError, *services.PackageServiceTarballNotFoundError:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case *services.PackageServiceGetPackageError, *services.PackageServiceDistTagError, *services.PackageServiceTarballError, *services.PackageServiceUnpublishError, *services.PackageServiceUpdatePackageError, *services.PackageServiceUnknownError:
+		// TODO replace log with proper logging
+		log.Println(logPrefix, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	case *services.PackageServiceVersionAlreadyExistsError, *services.PackageServiceRevisionMismatchError, *services.PackageServiceRevisionRequiredError:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case *coreerrors.NotAllowedToGetPackageError, *coreerrors.NotAllowedToPublishPackageError, *coreerrors.NotAllowedToUpdatePackageError, *coreerrors.NotAllowedToUnpublishPackageError, *coreerrors.NotAllowedToAccessPackageError, *services.TwoFactorEnrollmentRequiredError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case *services.TwoFactorRequiredError, *services.TwoFactorInvalidCodeError:
+		writeOTPRequired(w, err)
+	case *services.AuthServiceLoginThrottledError:
+		writeTooManyRequests(w, e)
+	default:
+		// TODO replace log with proper logging
+		log.Println(logPrefix, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
 }
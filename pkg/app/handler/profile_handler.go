@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrparano1d/noxite/pkg/app/auth"
+	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/services"
+
+	json "github.com/bytedance/sonic"
+)
+
+// couchUserPrefix prefixes the names of user documents.
+const couchUserPrefix = "org.couchdb.user:"
+
+// tfaDisableMode is the mode `npm profile disable-2fa` sends.
+const tfaDisableMode = "disable"
+
+// profileRes is the profile shown by `npm profile get`. TFA is false without two-factor authentication.
+type profileRes struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	TFA           any    `json:"tfa"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+type profileTFARes struct {
+	Pending bool   `json:"pending"`
+	Mode    string `json:"mode"`
+}
+
+// couchUserRes is the user document of the CouchDB API npm was built against.
+type couchUserRes struct {
+	ID    string   `json:"_id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Type  string   `json:"type"`
+	Roles []string `json:"roles"`
+	Date  string   `json:"date"`
+}
+
+// profileUpdateReq is sent by `npm profile enable-2fa` and `npm profile disable-2fa`.
+// TFA is either an object with password and mode or a list holding the code that confirms the enrolment.
+type profileUpdateReq struct {
+	TFA any `json:"tfa"`
+}
+
+// ProfileHandler serves the endpoints of `npm profile`, of which only the two-factor settings can be changed.
+func ProfileHandler(r chi.Router, app *core.ApplicationCore) {
+
+	r.Get("/-/npm/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		tf, err := app.TwoFactorService().GetTwoFactor(r.Context(), user)
+		if err != nil {
+			handleTwoFactorServiceError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(profileResFromUser(user, tf))
+	})
+
+	r.Get("/-/user/{orgCouchDBUser}", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(chi.URLParam(r, "orgCouchDBUser"), couchUserPrefix)
+
+		user, err := app.UserService().GetUserByUsername(r.Context(), auth.GetUserFromContext(r.Context()), name)
+		if err != nil {
+			switch err.(type) {
+			case *coreerrors.NotAllowedToGetUserError:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case *services.UserServiceUsernameNotFoundError, *services.UserServiceRequestValidationError:
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				// TODO replace log with proper logging
+				log.Println("user request failed: ", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(couchUserRes{
+			ID:    couchUserPrefix + user.Username.String(),
+			Name:  user.Username.String(),
+			Email: user.Email.String(),
+			Type:  "user",
+			Roles: []string{},
+			Date:  user.CreatedAt.Format(time.RFC3339),
+		})
+	})
+
+	r.Post("/-/npm/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		var req profileUpdateReq
+		if err := json.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var res any
+		switch tfa := req.TFA.(type) {
+		case map[string]any:
+			password, _ := tfa["password"].(string)
+			mode, _ := tfa["mode"].(string)
+
+			if mode == tfaDisableMode {
+				if err := app.TwoFactorService().Disable(r.Context(), user, password); err != nil {
+					handleTwoFactorServiceError(w, err)
+					return
+				}
+				res = profileResFromUser(user, nil)
+				break
+			}
+
+			uri, err := app.TwoFactorService().Enroll(r.Context(), user, password, mode)
+			if err != nil {
+				handleTwoFactorServiceError(w, err)
+				return
+			}
+			// npm reports a changed mode if tfa is null
+			if uri == "" {
+				res = map[string]any{"tfa": nil}
+			} else {
+				res = map[string]any{"tfa": uri}
+			}
+		case []any:
+			if len(tfa) != 1 {
+				http.Error(w, "expected a single one-time password", http.StatusBadRequest)
+				return
+			}
+			otp, _ := tfa[0].(string)
+
+			codes, err := app.TwoFactorService().Confirm(r.Context(), user, otp)
+			if err != nil {
+				handleTwoFactorServiceError(w, err)
+				return
+			}
+			recoveryCodes := make([]string, len(codes))
+			for i, code := range codes {
+				recoveryCodes[i] = code.String()
+			}
+			res = map[string]any{"tfa": recoveryCodes}
+		default:
+			http.Error(w, "only two-factor authentication can be changed", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(res)
+	})
+}
+
+func profileResFromUser(user *entities.User, tf *entities.TwoFactor) profileRes {
+	res := profileRes{
+		Name:    user.Username.String(),
+		Email:   user.Email.String(),
+		TFA:     false,
+		Created: user.CreatedAt.Format(time.RFC3339),
+		Updated: user.CreatedAt.Format(time.RFC3339),
+	}
+	if user.UpdatedAt != nil {
+		res.Updated = user.UpdatedAt.Format(time.RFC3339)
+	}
+	if tf != nil {
+		res.TFA = profileTFARes{
+			Pending: tf.Pending,
+			Mode:    tf.Mode.String(),
+		}
+	}
+	return res
+}
+
+// writeOTPRequired answers with the challenge on which npm asks for a one-time password and repeats the request
+// with the npm-otp header.
+func writeOTPRequired(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", "OTP")
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+func handleTwoFactorServiceError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *services.TwoFactorRequiredError, *services.TwoFactorInvalidCodeError:
+		writeOTPRequired(w, err)
+	case *services.AuthServiceLoginThrottledError:
+		writeTooManyRequests(w, e)
+	case *services.AuthServiceLoginFailedError:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case *services.InvalidTwoFactorFieldError:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case *services.TwoFactorNotEnabledError, *services.TwoFactorAlreadyEnabledError:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		// TODO replace log with proper logging
+		log.Println("two-factor request failed: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrparano1d/noxite/pkg/app/auth"
+	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/services"
+
+	json "github.com/bytedance/sonic"
+)
+
+// searchRes is the response format of the npm registry search.
+// See https://github.com/npm/registry/blob/main/docs/REGISTRY-API.md#get-v1search
+type searchRes struct {
+	Objects []searchObject `json:"objects"`
+	Total   int            `json:"total"`
+	Time    string         `json:"time"`
+}
+
+type searchObject struct {
+	Package     searchPackage `json:"package"`
+	Score       searchScore   `json:"score"`
+	SearchScore float64       `json:"searchScore"`
+}
+
+type searchPackage struct {
+	Name        string             `json:"name"`
+	Scope       string             `json:"scope"`
+	Version     string             `json:"version"`
+	Description string             `json:"description,omitempty"`
+	Keywords    []string           `json:"keywords,omitempty"`
+	Date        string             `json:"date"`
+	Author      *searchAuthor      `json:"author,omitempty"`
+	Publisher   *searchMaintainer  `json:"publisher,omitempty"`
+	Maintainers []searchMaintainer `json:"maintainers"`
+}
+
+type searchAuthor struct {
+	Name string `json:"name"`
+}
+
+type searchMaintainer struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// searchScore is the score of a package. noxite does not track the quality, popularity and maintenance
+// of packages, so the final score is the relevance relative to the best match.
+type searchScore struct {
+	Final  float64            `json:"final"`
+	Detail map[string]float64 `json:"detail"`
+}
+
+// SearchHandler serves the search endpoint used by `npm search`.
+func SearchHandler(r chi.Router, app *core.ApplicationCore) {
+
+	r.Get("/-/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		size, err := intQueryParam(r, "size")
+		if err != nil {
+			http.Error(w, "invalid size: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := intQueryParam(r, "from")
+		if err != nil {
+			http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := app.SearchService().Search(r.Context(), user, r.URL.Query().Get("text"), size, from)
+		if err != nil {
+			switch err.(type) {
+			case *coreerrors.NotAllowedToGetPackageError:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case *services.InvalidSearchFieldError:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				// TODO replace log with proper logging
+				log.Println("search failed: ", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(searchResFromResult(result))
+	})
+}
+
+func intQueryParam(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func searchResFromResult(result *entities.SearchResult) searchRes {
+	res := searchRes{
+		Objects: make([]searchObject, 0, len(result.Hits)),
+		Total:   result.Total,
+		Time:    time.Now().UTC().Format(time.RFC1123),
+	}
+
+	maxScore := 0.0
+	for _, hit := range result.Hits {
+		if hit.Score > maxScore {
+			maxScore = hit.Score
+		}
+	}
+
+	for _, hit := range result.Hits {
+		doc := hit.Document
+
+		scope := "unscoped"
+		if doc.Name.IsScoped() {
+			scope = doc.Name.Scope()[1:]
+		}
+
+		pkg := searchPackage{
+			Name:        doc.Name.String(),
+			Scope:       scope,
+			Version:     doc.Version.String(),
+			Description: doc.Description,
+			Keywords:    doc.Keywords,
+			Date:        doc.Date.UTC().Format(time.RFC3339),
+			Maintainers: make([]searchMaintainer, 0, len(doc.Maintainers)),
+		}
+		if doc.Author != "" {
+			pkg.Author = &searchAuthor{Name: doc.Author}
+		}
+		if doc.Publisher != nil {
+			pkg.Publisher = &searchMaintainer{Username: doc.Publisher.Name.String(), Email: doc.Publisher.Email.String()}
+		}
+		for _, maintainer := range doc.Maintainers {
+			pkg.Maintainers = append(pkg.Maintainers, searchMaintainer{Username: maintainer.Name.String(), Email: maintainer.Email.String()})
+		}
+
+		final := 0.0
+		if maxScore > 0 {
+			final = hit.Score / maxScore
+		}
+
+		res.Objects = append(res.Objects, searchObject{
+			Package: pkg,
+			Score: searchScore{
+				Final: final,
+				Detail: map[string]float64{
+					"quality":     0,
+					"popularity":  0,
+					"maintenance": 0,
+				},
+			},
+			SearchScore: hit.Score,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	json "github.com/bytedance/sonic"
+	"github.com/go-chi/chi/v5"
+	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/services"
+)
+
+// webLoginPollInterval is the number of seconds npm waits before polling the doneUrl again.
+const webLoginPollInterval = "2"
+
+// ssoBindingCookie binds a login to the browser that began it, its value is the binding returned by BeginLogin.
+// Logins taking longer than ssoBindingMaxAge have to be started again.
+const (
+	ssoBindingCookie = "noxite_sso"
+	ssoBindingMaxAge = 15 * 60
+)
+
+// webLoginRes starts `npm login --auth-type=web`. npm opens LoginURL in the browser and polls DoneURL.
+type webLoginRes struct {
+	LoginURL string `json:"loginUrl"`
+	DoneURL  string `json:"doneUrl"`
+}
+
+type webLoginDoneRes struct {
+	Token string `json:"token"`
+}
+
+// SSOHandler serves the single sign-on login of the web UI at /-/v1/login/sso and the web login of npm.
+// See https://github.com/npm/cli/blob/latest/lib/utils/auth.js
+func SSOHandler(r chi.Router, app *core.ApplicationCore) {
+
+	r.Post("/-/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		login, err := app.SSOService().CreateWebLogin(r.Context())
+		if err != nil {
+			handleSSOServiceError(w, err)
+			return
+		}
+
+		baseURL := requestBaseURL(r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(webLoginRes{
+			LoginURL: baseURL + "/-/v1/login/sso/" + login.LoginToken,
+			DoneURL:  baseURL + "/-/v1/done/" + login.DoneToken,
+		})
+	})
+
+	r.Get("/-/v1/login/sso", func(w http.ResponseWriter, r *http.Request) {
+		authURL, binding, err := app.SSOService().BeginLogin(r.Context(), "")
+		if err != nil {
+			handleSSOServiceError(w, err)
+			return
+		}
+		setSSOBindingCookie(w, r, binding, ssoBindingMaxAge)
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+
+	r.Get("/-/v1/login/sso/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if providerErr := query.Get("error"); providerErr != "" {
+			http.Error(w, "login failed: "+providerErr+" "+query.Get("error_description"), http.StatusUnauthorized)
+			return
+		}
+
+		var binding string
+		if cookie, err := r.Cookie(ssoBindingCookie); err == nil {
+			binding = cookie.Value
+		}
+		// the state is single use, so is the cookie
+		setSSOBindingCookie(w, r, "", -1)
+
+		_, redirectURL, err := app.SSOService().CompleteLogin(r.Context(), query.Get("state"), query.Get("code"), binding)
+		if err != nil {
+			handleSSOServiceError(w, err)
+			return
+		}
+
+		if redirectURL != "" {
+			http.Redirect(w, r, redirectURL, http.StatusFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("you are logged in, return to your terminal and close this window"))
+	})
+
+	r.Get("/-/v1/login/sso/{login}", func(w http.ResponseWriter, r *http.Request) {
+		authURL, binding, err := app.SSOService().BeginLogin(r.Context(), chi.URLParam(r, "login"))
+		if err != nil {
+			handleSSOServiceError(w, err)
+			return
+		}
+		setSSOBindingCookie(w, r, binding, ssoBindingMaxAge)
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+
+	r.Get("/-/v1/done/{done}", func(w http.ResponseWriter, r *http.Request) {
+		token, done, err := app.SSOService().PollWebLogin(r.Context(), chi.URLParam(r, "done"))
+		if err != nil {
+			handleSSOServiceError(w, err)
+			return
+		}
+
+		if !done {
+			w.Header().Set("Retry-After", webLoginPollInterval)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(webLoginDoneRes{
+			Token: token,
+		})
+	})
+}
+
+// requestBaseURL returns the URL the client reached the registry at, honoring X-Forwarded-Proto of reverse proxies.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// setSSOBindingCookie sets the binding cookie for the callback, a negative maxAge deletes it.
+// It is sent with the redirect from the provider, which is a top-level navigation allowed by SameSite=Lax.
+func setSSOBindingCookie(w http.ResponseWriter, r *http.Request, binding string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoBindingCookie,
+		Value:    binding,
+		Path:     "/-/v1/login/sso",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(requestBaseURL(r), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func handleSSOServiceError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *services.SSOLoginNotFoundError:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case *services.SSOLoginFailedError, *services.AuthServiceLoginFailedError:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case *services.AuthServiceUserNotLinkedError, *services.SSOLoginBindingError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		// TODO replace log with proper logging
+		log.Println("sso request failed: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrparano1d/noxite/pkg/app/auth"
+	"github.com/mrparano1d/noxite/pkg/core"
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/services"
+
+	json "github.com/bytedance/sonic"
+)
+
+type whoamiRes struct {
+	Username string `json:"username"`
+}
+
+// createTokenReq is sent by `npm token create`. Packages, scopes, expires and automation are noxite extensions for
+// CI tokens, expires is the lifetime in days.
+type createTokenReq struct {
+	Password      string   `json:"password"`
+	Readonly      bool     `json:"readonly"`
+	Automation    bool     `json:"automation"`
+	CIDRWhitelist []string `json:"cidr_whitelist"`
+	Packages      []string `json:"packages"`
+	Scopes        []string `json:"scopes"`
+	Expires       int      `json:"expires"`
+}
+
+// tokenRes is an access token as listed by `npm token list`. Token only holds the full secret once it was created.
+// See https://github.com/npm/registry/blob/main/docs/user/authentication.md
+type tokenRes struct {
+	Token         string   `json:"token"`
+	Key           string   `json:"key"`
+	CIDRWhitelist []string `json:"cidr_whitelist"`
+	Readonly      bool     `json:"readonly"`
+	Automation    bool     `json:"automation,omitempty"`
+	Packages      []string `json:"packages,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	Expires       string   `json:"expires,omitempty"`
+	Created       string   `json:"created"`
+	Updated       string   `json:"updated"`
+	LastUsed      string   `json:"last_used,omitempty"`
+}
+
+type tokenListRes struct {
+	Objects []tokenRes        `json:"objects"`
+	Total   int               `json:"total"`
+	URLs    map[string]string `json:"urls"`
+}
+
+// TokenHandler serves the endpoints of `npm whoami`, `npm logout` and `npm token`.
+func TokenHandler(r chi.Router, app *core.ApplicationCore) {
+
+	r.Get("/-/whoami", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(whoamiRes{
+			Username: user.Username.String(),
+		})
+	})
+
+	r.Delete("/-/user/token/{token}", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+		token := chi.URLParam(r, "token")
+
+		if fields.IsAccessToken(token) {
+			if err := app.TokenService().RevokeTokenBySecret(r.Context(), user, token); err != nil {
+				handleTokenServiceError(w, err)
+				return
+			}
+		} else {
+			if token != auth.GetSessionFromContext(r.Context()) {
+				http.Error(w, "only the session of the request can be logged out", http.StatusForbidden)
+				return
+			}
+			if err := app.SessionService().InvalidateSession(r.Context(), token); err != nil {
+				// TODO replace log with proper logging
+				log.Println("failed to invalidate session: ", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	r.Get("/-/npm/v1/tokens", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		tokens, err := app.TokenService().ListTokens(r.Context(), user)
+		if err != nil {
+			handleTokenServiceError(w, err)
+			return
+		}
+
+		res := tokenListRes{
+			Objects: make([]tokenRes, 0, len(tokens)),
+			Total:   len(tokens),
+			URLs:    map[string]string{},
+		}
+		for _, token := range tokens {
+			res.Objects = append(res.Objects, tokenResFromToken(token, token.DisplayPrefix))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.ConfigDefault.NewEncoder(w).Encode(res)
+	})
+
+	r.Post("/-/npm/v1/tokens", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		var req createTokenReq
+		if err := json.ConfigDefault.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		token, secret, err := app.TokenService().CreateToken(r.Context(), user, services.CreateTokenRequest{
+			Password:      req.Password,
+			Readonly:      req.Readonly,
+			Automation:    req.Automation,
+			CIDRWhitelist: req.CIDRWhitelist,
+			Packages:      req.Packages,
+			Scopes:        req.Scopes,
+			ExpiresIn:     time.Duration(req.Expires) * 24 * time.Hour,
+		})
+		if err != nil {
+			handleTokenServiceError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.ConfigDefault.NewEncoder(w).Encode(tokenResFromToken(token, secret.String()))
+	})
+
+	r.Delete("/-/npm/v1/tokens/token/{key}", func(w http.ResponseWriter, r *http.Request) {
+		user := auth.GetUserFromContext(r.Context())
+
+		if err := app.TokenService().RevokeToken(r.Context(), user, chi.URLParam(r, "key")); err != nil {
+			handleTokenServiceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func tokenResFromToken(token *entities.AccessToken, displayed string) tokenRes {
+	cidrs := make([]string, 0, len(token.CIDRWhitelist))
+	for _, cidr := range token.CIDRWhitelist {
+		cidrs = append(cidrs, cidr.String())
+	}
+
+	res := tokenRes{
+		Token:         displayed,
+		Key:           token.Key.String(),
+		CIDRWhitelist: cidrs,
+		Readonly:      token.Readonly,
+		Automation:    token.Automation,
+		Created:       token.CreatedAt.UTC().Format(time.RFC3339),
+		Updated:       token.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if token.UpdatedAt != nil {
+		res.Updated = token.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if token.LastUsedAt != nil {
+		res.LastUsed = token.LastUsedAt.UTC().Format(time.RFC3339)
+	}
+	for _, pkg := range token.Restriction.Packages {
+		res.Packages = append(res.Packages, pkg.String())
+	}
+	for _, scope := range token.Restriction.Scopes {
+		res.Scopes = append(res.Scopes, scope.String())
+	}
+	if token.ExpiresAt != nil {
+		res.Expires = token.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return res
+}
+
+func handleTokenServiceError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *services.AuthServiceLoginFailedError:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case *services.TwoFactorRequiredError, *services.TwoFactorInvalidCodeError:
+		writeOTPRequired(w, err)
+	case *services.AuthServiceLoginThrottledError:
+		writeTooManyRequests(w, e)
+	case *services.InvalidTokenFieldError:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case *coreerrors.NotAllowedToManageTokensError, *services.TwoFactorEnrollmentRequiredError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case *services.TokenServiceTokenNotFoundError:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		// TODO replace log with proper logging
+		log.Println("token request failed: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -6,11 +6,16 @@ import (
 )
 
 type ApplicationCore struct {
-	authService    *services.AuthService
-	packageService *services.PackageService
-	sessionService *services.SessionService
-	userService    *services.UserService
-	roleService    *services.RoleService
+	authService      *services.AuthService
+	packageService   *services.PackageService
+	sessionService   *services.SessionService
+	userService      *services.UserService
+	roleService      *services.RoleService
+	searchService    *services.SearchService
+	tokenService     *services.TokenService
+	twoFactorService *services.TwoFactorService
+	signupService    *services.SignupService
+	ssoService       *services.SSOService
 }
 
 func NewCoreApp(
@@ -20,16 +25,44 @@ func NewCoreApp(
 	storageAdapter ports.StoragePort,
 	userAdapter ports.UserPort,
 	roleAdapter ports.RolePort,
+	blobAdapter ports.BlobPort,
+	searchAdapter ports.SearchPort,
+	tokenAdapter ports.TokenPort,
+	passwordHasher ports.PasswordHasherPort,
+	twoFactorAdapter ports.TwoFactorPort,
+	inviteAdapter ports.InvitePort,
+	signupConfig services.SignupConfig,
+	externalAuth []ports.ExternalAuthPort,
+	externalRegistration ports.ExternalRegistrationPort,
+	identityProvider ports.IdentityProviderPort,
+	ssoConfig services.SSOConfig,
+	loginThrottleAdapter ports.LoginThrottlePort,
+	auditAdapter ports.AuditPort,
+	loginThrottleConfig services.LoginThrottleConfig,
 ) *ApplicationCore {
 
-	sessService := services.NewSessionService(sessionAdapter)
+	sessService := services.NewSessionService(sessionAdapter, userAdapter)
+	authService := services.NewAuthService(authAdapter, passwordHasher, twoFactorAdapter, userAdapter, externalAuth, sessService, loginThrottleAdapter, auditAdapter, loginThrottleConfig)
+	userService := services.NewUserService(userAdapter, sessService)
+	searchService := services.NewSearchService(searchAdapter, storageAdapter)
+
+	// single sign-on is optional
+	var ssoService *services.SSOService
+	if identityProvider != nil {
+		ssoService = services.NewSSOService(ssoConfig, identityProvider, authService, sessService)
+	}
 
 	return &ApplicationCore{
-		authService:    services.NewAuthService(authAdapter, sessService),
-		packageService: services.NewPackageService(packageAdapter, storageAdapter),
-		sessionService: sessService,
-		userService:    services.NewUserService(userAdapter),
-		roleService:    services.NewRoleService(roleAdapter),
+		authService:      authService,
+		packageService:   services.NewPackageService(packageAdapter, storageAdapter, blobAdapter, searchService, authService),
+		sessionService:   sessService,
+		userService:      userService,
+		roleService:      services.NewRoleService(roleAdapter, sessService),
+		searchService:    searchService,
+		tokenService:     services.NewTokenService(tokenAdapter, userAdapter, authService),
+		twoFactorService: services.NewTwoFactorService(twoFactorAdapter, authService),
+		signupService:    services.NewSignupService(signupConfig, inviteAdapter, externalRegistration, userService),
+		ssoService:       ssoService,
 	}
 }
 
@@ -52,3 +85,24 @@ func (a *ApplicationCore) UserService() *services.UserService {
 func (a *ApplicationCore) RoleService() *services.RoleService {
 	return a.roleService
 }
+
+func (a *ApplicationCore) SearchService() *services.SearchService {
+	return a.searchService
+}
+
+func (a *ApplicationCore) TokenService() *services.TokenService {
+	return a.tokenService
+}
+
+func (a *ApplicationCore) TwoFactorService() *services.TwoFactorService {
+	return a.twoFactorService
+}
+
+func (a *ApplicationCore) SignupService() *services.SignupService {
+	return a.signupService
+}
+
+// SSOService returns nil if no identity provider is configured.
+func (a *ApplicationCore) SSOService() *services.SSOService {
+	return a.ssoService
+}
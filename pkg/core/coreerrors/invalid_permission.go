@@ -14,6 +14,29 @@ func (e *NotAllowedToGetPackageError) Error() string {
 	return "not allowed to get package"
 }
 
+type NotAllowedToUpdatePackageError struct {
+}
+
+func (e *NotAllowedToUpdatePackageError) Error() string {
+	return "not allowed to update package"
+}
+
+type NotAllowedToUnpublishPackageError struct {
+}
+
+func (e *NotAllowedToUnpublishPackageError) Error() string {
+	return "not allowed to unpublish package"
+}
+
+// NotAllowedToAccessPackageError is returned if the access token of the user is restricted to other packages.
+type NotAllowedToAccessPackageError struct {
+	Name string
+}
+
+func (e *NotAllowedToAccessPackageError) Error() string {
+	return "not allowed to access package " + e.Name
+}
+
 type NotAllowedToCreateUserError struct {
 }
 
@@ -69,3 +92,11 @@ type NotAllowedToDeleteRoleError struct {
 func (e *NotAllowedToDeleteRoleError) Error() string {
 	return "not allowed to delete role"
 }
+
+// NotAllowedToManageTokensError is returned if a readonly or restricted access token is used to manage access tokens.
+type NotAllowedToManageTokensError struct {
+}
+
+func (e *NotAllowedToManageTokensError) Error() string {
+	return "not allowed to manage access tokens"
+}
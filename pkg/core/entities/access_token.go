@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// AccessToken is a long-lived credential of a user, e.g. created by "npm token create" for CI.
+// Only the key of the token is stored, the token itself is shown once on creation.
+type AccessToken struct {
+	ID     fields.EntityID
+	UserID fields.EntityID
+	Key    fields.AccessTokenKey
+	// DisplayPrefix are the first characters of the token to tell tokens apart in listings.
+	DisplayPrefix string
+	// Readonly tokens may only read packages.
+	Readonly bool
+	// Automation tokens skip the one-time password of writes, e.g. for CI. They are only created while the user has
+	// two-factor authentication enabled, since creating them requires a one-time password then.
+	Automation bool
+	// CIDRWhitelist restricts the IP addresses the token may be used from. An empty list allows all addresses.
+	CIDRWhitelist []fields.CIDR
+	// Restriction limits the packages the token may be used for.
+	Restriction PackageRestriction
+	// ExpiresAt is the time the token stops working. Tokens without expiry are valid until they are revoked.
+	ExpiresAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// Expired reports whether the token is expired at the given time.
+func (t *AccessToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// PackageRestriction limits access to the listed packages and the packages of the listed scopes.
+// A restriction without packages and scopes allows all packages.
+type PackageRestriction struct {
+	Packages []fields.PackageName
+	Scopes   []fields.PackageScope
+}
+
+// IsRestricted reports whether the restriction limits access at all.
+func (r PackageRestriction) IsRestricted() bool {
+	return len(r.Packages) > 0 || len(r.Scopes) > 0
+}
+
+// Allows reports whether the package may be accessed.
+func (r PackageRestriction) Allows(name fields.PackageName) bool {
+	if !r.IsRestricted() {
+		return true
+	}
+	for _, pkg := range r.Packages {
+		if pkg == name {
+			return true
+		}
+	}
+	for _, scope := range r.Scopes {
+		if scope.Contains(name) {
+			return true
+		}
+	}
+	return false
+}
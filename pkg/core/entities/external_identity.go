@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// ExternalIdentity is a user authenticated by an external directory like LDAP.
+// Local users are created or updated from it on login.
+type ExternalIdentity struct {
+	Username fields.Username
+	Email    fields.Email
+	// RoleID is the role the groups of the user map to.
+	RoleID fields.EntityID
+	// Source names the directory, users created from the identity are managed by it, see User.AuthSource.
+	Source string
+	// ProvisionOnly uses Email and RoleID only to create the user, for directories like htpasswd files that do not manage them.
+	ProvisionOnly bool
+}
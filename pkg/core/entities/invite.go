@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// Invite allows an email address to sign up while the signup policy is invite-only.
+type Invite struct {
+	ID    fields.EntityID
+	Email fields.Email
+	// RoleID replaces the default role of the signup if set.
+	RoleID    *fields.EntityID
+	CreatedBy fields.EntityID
+	ExpiresAt *time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Usable reports whether the invite was not used yet and has not expired.
+func (i *Invite) Usable(now time.Time) bool {
+	return i.UsedAt == nil && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt))
+}
@@ -1,10 +1,14 @@
 package entities
 
-import "github.com/mrparano1d/noxite/pkg/core/fields"
+import (
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
 
 type PackageVersion struct {
-	Name                 fields.RequiredString
-	Version              fields.RequiredString
+	Name                 fields.PackageName
+	Version              fields.Version
 	Description          *string
 	Keywords             []fields.RequiredString
 	Homepage             *fields.Website
@@ -39,7 +43,21 @@ type PackageVersion struct {
 	Integrity   fields.RequiredString
 	SHASUM      fields.RequiredString
 	ContentType fields.RequiredString
-	Data        fields.RequiredString
 	Length      int
 	Readme      *string
+	// Deprecated is the message clients warn with when installing a deprecated version.
+	Deprecated *string
+
+	// RawManifest is the version manifest as it was published, including fields without a typed counterpart.
+	RawManifest []byte
+
+	// Tarball is only set while publishing and holds the decoded tarball of the version.
+	Tarball []byte
+	// TarballDigest addresses the tarball in the blob storage.
+	TarballDigest fields.Digest
+
+	// DistTags are the tags that should point to this version once it is published.
+	DistTags  []fields.RequiredString
+	Publisher *Maintainer
+	CreatedAt time.Time
 }
@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// Package is the registry document (packument) of a package.
+// It holds every published version together with the package wide metadata.
+type Package struct {
+	Name        fields.PackageName
+	Description *string
+	Readme      *string
+	Versions    []*PackageVersion
+	DistTags    map[fields.RequiredString]fields.RequiredString
+	Maintainers []Maintainer
+	CreatedAt   time.Time
+	ModifiedAt  time.Time
+}
+
+// Rev returns the revision of the package document, which changes whenever versions are published or unpublished.
+// Clients send it back on writes like unpublish to detect concurrent modifications.
+func (p *Package) Rev() string {
+	return fmt.Sprintf("%d-%x", len(p.Versions), p.ModifiedAt.UnixNano())
+}
+
+// PackumentUpdate is a package document sent by clients to change an existing package, e.g. to unpublish versions.
+type PackumentUpdate struct {
+	Name fields.PackageName
+	// Rev is the "_rev" of the package document the update is based on, empty if the client didn't send it.
+	Rev      string
+	Versions []PackumentUpdateVersion
+	DistTags map[fields.RequiredString]fields.RequiredString
+	// Publish is set if the document carries tarballs in "_attachments", i.e. it publishes a new version
+	// instead of changing the metadata of existing ones.
+	Publish bool
+}
+
+// PackumentUpdateVersion is a version listed in a PackumentUpdate.
+type PackumentUpdateVersion struct {
+	Version fields.Version
+	// Deprecated is the deprecation message of the version, empty if it is not deprecated.
+	Deprecated string
+}
+
+// Maintainer is a user that created or published a package.
+type Maintainer struct {
+	Name  fields.Username
+	Email fields.Email
+}
+
+// PackageTarball describes the content of a published tarball.
+// Name, Version, Dependencies and Bin are read from the package.json shipped inside of it.
+type PackageTarball struct {
+	Name         string
+	Version      string
+	Dependencies map[string]string
+	// Bin maps command names to file paths. A single bin path is keyed by the package name without scope.
+	Bin map[string]string
+	// Files are the paths of all regular files relative to the package root.
+	Files []string
+}
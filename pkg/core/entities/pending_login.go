@@ -0,0 +1,16 @@
+package entities
+
+// PendingLogin is a single sign-on login waiting for the callback of the identity provider.
+// It is stored in an anonymous session, whose token is the state of the authorization request.
+type PendingLogin struct {
+	// DoneToken is the session npm polls for the token of a web login, it is empty for browser logins.
+	DoneToken    string `json:"done_token"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// WebLogin is a login started by `npm login --auth-type=web`. The browser opens LoginToken, npm polls DoneToken.
+type WebLogin struct {
+	LoginToken string
+	DoneToken  string
+}
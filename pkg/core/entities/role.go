@@ -11,7 +11,9 @@ type Role struct {
 	Name        fields.RequiredString
 	Description string
 	Permissions Permissions
-	CreatedAt   time.Time
-	UpdatedAt   *time.Time
-	DeletedAt   *time.Time
+	// RequireTwoFactor blocks writes of users without two-factor authentication.
+	RequireTwoFactor bool
+	CreatedAt        time.Time
+	UpdatedAt        *time.Time
+	DeletedAt        *time.Time
 }
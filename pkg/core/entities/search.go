@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// SearchDocument is the searchable representation of a package, built from the version tagged latest.
+type SearchDocument struct {
+	Name        fields.PackageName
+	Version     fields.Version
+	Description string
+	Keywords    []string
+	Readme      string
+	// Author is the name of the package author, or of the publisher if the author is unknown.
+	Author      string
+	Publisher   *Maintainer
+	Maintainers []Maintainer
+	Deprecated  bool
+	Date        time.Time
+}
+
+// SearchQuery is a parsed search text like "react hooks keywords:state author:jane".
+type SearchQuery struct {
+	// Terms are the words of the text without qualifiers. Every term must match the name, description,
+	// keywords, readme or author of a package.
+	Terms      []string
+	Keywords   []string
+	Author     string
+	Maintainer string
+	// Scope restricts the search to packages of the scope, without "@".
+	Scope string
+	// Deprecated restricts the search to deprecated or not deprecated packages if set.
+	Deprecated *bool
+	// Restriction hides the packages the access token of the searching user may not access.
+	Restriction PackageRestriction
+	From        int
+	Size        int
+}
+
+// SearchResult holds a page of the packages matching a SearchQuery ordered by score.
+type SearchResult struct {
+	// Total is the number of all matching packages, not only those of the page.
+	Total int
+	Hits  []SearchHit
+}
+
+type SearchHit struct {
+	Document *SearchDocument
+	// Score is the relevance of the package for the query, higher is better.
+	Score float64
+}
@@ -7,13 +7,62 @@ import (
 )
 
 type Session struct {
-	Token     fields.SessionToken `json:"token"`
-	ExpiresAt time.Time           `json:"expires_at"`
+	// Token is only set by CreateSession, the store keeps the Key of the token.
+	Token     fields.SessionToken    `json:"-"`
+	Key       fields.SessionTokenKey `json:"key"`
+	ExpiresAt time.Time              `json:"expires_at"`
+	// CreatedAt bounds the session by the absolute timeout, LastUsedAt by the idle timeout.
+	CreatedAt  time.Time     `json:"created_at"`
+	LastUsedAt time.Time     `json:"last_used_at"`
+	Client     SessionClient `json:"client"`
 }
 
-func NewSession(token fields.SessionToken, expiresAt time.Time) *Session {
+// SessionClient describes the client which used a session last.
+type SessionClient struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// SessionTimeouts end sessions an absolute time after their creation or an idle time after their last use,
+// whichever comes first. An idle time of zero lets sessions last until the absolute timeout.
+type SessionTimeouts struct {
+	Absolute time.Duration
+	Idle     time.Duration
+}
+
+// ExpiresAt returns the expiry of a session created and last used at the given times.
+func (t SessionTimeouts) ExpiresAt(createdAt time.Time, lastUsedAt time.Time) time.Time {
+	expiresAt := createdAt.Add(t.Absolute)
+	if t.Idle > 0 {
+		if idleExpiresAt := lastUsedAt.Add(t.Idle); idleExpiresAt.Before(expiresAt) {
+			return idleExpiresAt
+		}
+	}
+	return expiresAt
+}
+
+func NewSession(token fields.SessionToken, client SessionClient, createdAt time.Time, timeouts SessionTimeouts) *Session {
 	return &Session{
-		Token:     token,
-		ExpiresAt: expiresAt,
+		Token:      token,
+		Key:        token.Key(),
+		ExpiresAt:  timeouts.ExpiresAt(createdAt, createdAt),
+		CreatedAt:  createdAt,
+		LastUsedAt: createdAt,
+		Client:     client,
 	}
 }
+
+// Touch records a use of the session by the client and extends its expiry by the idle timeout.
+func (s *Session) Touch(client SessionClient, now time.Time, timeouts SessionTimeouts) {
+	s.LastUsedAt = now
+	s.Client = client
+	s.ExpiresAt = timeouts.ExpiresAt(s.CreatedAt, now)
+}
+
+// ID identifies the session in listings of the sessions of a user. It is the first half of the key.
+func (s *Session) ID() string {
+	return s.Key.String()[:sessionIDLength]
+}
+
+// sessionIDLength is the length of session IDs, which are shortened keys.
+const sessionIDLength = 32
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// TwoFactor holds the TOTP settings of a user.
+type TwoFactor struct {
+	UserID fields.EntityID
+	Secret fields.TOTPSecret
+	Mode   fields.TwoFactorMode
+	// Pending is true until the user confirmed the enrolment with a first code.
+	Pending bool
+	// RecoveryCodes are the keys of the unused recovery codes.
+	RecoveryCodes []fields.RecoveryCodeKey
+	// LastUsedStep is the time step of the last accepted TOTP code. Codes of earlier steps are rejected.
+	LastUsedStep int64
+	CreatedAt    time.Time
+	UpdatedAt    *time.Time
+}
+
+// Enabled reports whether one-time passwords are required.
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && !t.Pending
+}
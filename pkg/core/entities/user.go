@@ -7,12 +7,24 @@ import (
 )
 
 type User struct {
-	ID        fields.EntityID
-	Role      *Role
-	Username  fields.Username
-	Email     fields.Email
-	Password  fields.Password
-	CreatedAt time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
+	ID       fields.EntityID
+	Role     *Role
+	Username fields.Username
+	Email    fields.Email
+	// AuthSource is the external directory managing the user, e.g. "ldap", and empty for local users.
+	AuthSource string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+	DeletedAt  *time.Time
+	// AccessToken is the token the user authenticated with. It is nil for login sessions.
+	AccessToken *AccessToken
+}
+
+// CanAccessPackage reports whether the credential of the user may be used for the package.
+// Role permissions are checked separately.
+func (u *User) CanAccessPackage(name fields.PackageName) bool {
+	if u.AccessToken == nil {
+		return true
+	}
+	return u.AccessToken.Restriction.Allows(name)
 }
@@ -0,0 +1,91 @@
+package fields
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// AccessTokenPrefix makes access tokens recognizable, e.g. by secret scanners.
+const AccessTokenPrefix = "noxat_"
+
+// accessTokenBytes is the entropy of an access token.
+const accessTokenBytes = 32
+
+// accessTokenDisplayLength is the number of characters of a token that are stored and displayed to tell tokens apart.
+const accessTokenDisplayLength = len(AccessTokenPrefix) + 6
+
+// AccessToken is the secret of a long-lived token like "noxat_<64 hex chars>". It is only known to the client,
+// the registry stores its AccessTokenKey.
+type AccessToken string
+
+func (t AccessToken) String() string {
+	return string(t)
+}
+
+// Key returns the SHA-256 of the token, which identifies the token in storage.
+func (t AccessToken) Key() AccessTokenKey {
+	sum := sha256.Sum256([]byte(t))
+	return AccessTokenKey(hex.EncodeToString(sum[:]))
+}
+
+// DisplayPrefix returns the first characters of the token, which are shown in token listings.
+func (t AccessToken) DisplayPrefix() string {
+	return string(t[:accessTokenDisplayLength])
+}
+
+// IsAccessToken reports whether the bearer token looks like an access token instead of a session token.
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// NewAccessToken generates a random access token.
+func NewAccessToken() (AccessToken, error) {
+	b := make([]byte, accessTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return AccessToken(AccessTokenPrefix + hex.EncodeToString(b)), nil
+}
+
+func AccessTokenFromString(token string) (AccessToken, error) {
+	if !IsAccessToken(token) || len(token) != len(AccessTokenPrefix)+2*accessTokenBytes {
+		return "", &InvalidAccessTokenError{}
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(token, AccessTokenPrefix)); err != nil {
+		return "", &InvalidAccessTokenError{}
+	}
+	return AccessToken(token), nil
+}
+
+// AccessTokenKey is the hex encoded SHA-256 of an AccessToken.
+type AccessTokenKey string
+
+func (k AccessTokenKey) String() string {
+	return string(k)
+}
+
+func AccessTokenKeyFromString(key string) (AccessTokenKey, error) {
+	b, err := hex.DecodeString(key)
+	if err != nil || len(b) != sha256.Size {
+		return "", &InvalidAccessTokenKeyError{Key: key}
+	}
+	return AccessTokenKey(strings.ToLower(key)), nil
+}
+
+// errors
+
+type InvalidAccessTokenError struct{}
+
+func (e InvalidAccessTokenError) Error() string {
+	return "access token is invalid"
+}
+
+type InvalidAccessTokenKeyError struct {
+	Key string
+}
+
+func (e InvalidAccessTokenKeyError) Error() string {
+	return "access token key " + e.Key + " is invalid"
+}
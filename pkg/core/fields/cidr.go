@@ -0,0 +1,40 @@
+package fields
+
+import (
+	"net/netip"
+)
+
+// CIDR is an IP network like "192.168.0.0/16" or "2001:db8::/32".
+type CIDR string
+
+func (c CIDR) String() string {
+	return string(c)
+}
+
+// Contains reports whether the IP address is part of the network.
+func (c CIDR) Contains(ip netip.Addr) bool {
+	prefix, err := netip.ParsePrefix(string(c))
+	if err != nil {
+		return false
+	}
+	return prefix.Contains(ip.Unmap())
+}
+
+func CIDRFromString(s string) (CIDR, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", &InvalidCIDRError{CIDR: s, Reason: err.Error()}
+	}
+	return CIDR(prefix.Masked().String()), nil
+}
+
+// errors
+
+type InvalidCIDRError struct {
+	CIDR   string
+	Reason string
+}
+
+func (e InvalidCIDRError) Error() string {
+	return "CIDR " + e.CIDR + " is invalid: " + e.Reason
+}
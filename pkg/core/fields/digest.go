@@ -0,0 +1,56 @@
+package fields
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+const digestAlgorithm = "sha256"
+
+// Digest addresses content by its hash in the form "sha256:<hex>".
+type Digest string
+
+func (d Digest) String() string {
+	return string(d)
+}
+
+// Hex returns the hex encoded hash without the algorithm prefix.
+func (d Digest) Hex() string {
+	return strings.TrimPrefix(string(d), digestAlgorithm+":")
+}
+
+// converters
+
+// DigestFromString validates the given string and returns a Digest.
+// If the string is invalid, an error is returned.
+func DigestFromString(s string) (Digest, error) {
+	algorithm, sum, ok := strings.Cut(s, ":")
+	if !ok || algorithm != digestAlgorithm {
+		return Digest(""), &InvalidDigestError{Digest: s, Reason: "digest must start with " + digestAlgorithm + ":"}
+	}
+	if len(sum) != 64 {
+		return Digest(""), &InvalidDigestError{Digest: s, Reason: "hash must be 64 characters long"}
+	}
+	if _, err := hex.DecodeString(sum); err != nil || strings.ToLower(sum) != sum {
+		return Digest(""), &InvalidDigestError{Digest: s, Reason: "hash must be lower case hex"}
+	}
+	return Digest(s), nil
+}
+
+// DigestFromHash returns the Digest of a sha256 hash that has been written to.
+func DigestFromHash(h hash.Hash) Digest {
+	return Digest(digestAlgorithm + ":" + hex.EncodeToString(h.Sum(nil)))
+}
+
+// errors
+
+type InvalidDigestError struct {
+	Digest string
+	Reason string
+}
+
+func (e *InvalidDigestError) Error() string {
+	return fmt.Sprintf("invalid digest %s: %s", e.Digest, e.Reason)
+}
@@ -3,6 +3,7 @@ package fields
 import (
 	"fmt"
 	"net/mail"
+	"strings"
 )
 
 type Email string
@@ -19,6 +20,16 @@ func EmailFromString(s string) (Email, error) {
 	return Email(s), nil
 }
 
+// Domain returns the lowercased domain of the email address.
+func (e Email) Domain() string {
+	addr, err := mail.ParseAddress(e.String())
+	if err != nil {
+		return ""
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	return strings.ToLower(addr.Address[at+1:])
+}
+
 // errors
 
 type InvalidEmailError struct {
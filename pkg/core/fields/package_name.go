@@ -6,14 +6,69 @@ import (
 	"strings"
 )
 
+// PackageName is the name of a npm package, either unscoped like "name" or scoped like "@scope/name".
+// The name must not be longer than 214 characters and each part must be url-safe and must not start with "." or "_".
 type PackageName string
 
 func (n PackageName) String() string {
 	return string(n)
 }
 
+// IsScoped reports whether the package name has a scope like "@scope/name".
+func (n PackageName) IsScoped() bool {
+	return strings.HasPrefix(string(n), "@")
+}
+
+// Scope returns the scope of the package including the "@", e.g. "@scope".
+// An empty string is returned for unscoped packages.
+func (n PackageName) Scope() string {
+	if !n.IsScoped() {
+		return ""
+	}
+	scope, _, _ := strings.Cut(string(n), "/")
+	return scope
+}
+
+// BareName returns the name of the package without its scope.
+func (n PackageName) BareName() string {
+	if !n.IsScoped() {
+		return string(n)
+	}
+	_, name, _ := strings.Cut(string(n), "/")
+	return name
+}
+
+// URLEncoded returns the name as a single path segment, e.g. "@scope%2fname".
+func (n PackageName) URLEncoded() string {
+	if !n.IsScoped() {
+		return string(n)
+	}
+	return n.Scope() + "%2f" + n.BareName()
+}
+
+// TarballName returns the file name of the tarball of the given version, e.g. "name-1.0.0.tgz".
+// Scoped packages use their bare name as npm does.
+func (n PackageName) TarballName(version string) string {
+	return n.BareName() + "-" + version + ".tgz"
+}
+
+// VersionFromTarballName returns the version of a tarball file name created by TarballName.
+func (n PackageName) VersionFromTarballName(tarball string) (string, error) {
+	prefix := n.BareName() + "-"
+	if !strings.HasPrefix(tarball, prefix) || !strings.HasSuffix(tarball, ".tgz") || len(tarball) <= len(prefix)+len(".tgz") {
+		return "", &InvalidTarballNameError{
+			Name:    n.String(),
+			Tarball: tarball,
+		}
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(tarball, prefix), ".tgz"), nil
+}
+
 // converters
 
+// PackageNameFromString unescapes and validates the given string and returns a PackageName.
+// Both "@scope/name" and the url-encoded "@scope%2fname" are accepted for scoped packages.
+// If the string is invalid, an error is returned.
 func PackageNameFromString(s string) (PackageName, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -31,9 +86,80 @@ func PackageNameFromString(s string) (PackageName, error) {
 		}
 	}
 
+	if len(name) > 214 {
+		return PackageName(""), &InvalidPackageNameError{
+			Name:   name,
+			Reason: "package name cannot be longer than 214 characters",
+		}
+	}
+
+	parts := []string{name}
+	if strings.HasPrefix(name, "@") {
+		scope, bare, ok := strings.Cut(name[1:], "/")
+		if !ok {
+			return PackageName(""), &InvalidPackageNameError{
+				Name:   name,
+				Reason: "scoped package name must be in the form @scope/name",
+			}
+		}
+		parts = []string{scope, bare}
+	}
+
+	for _, part := range parts {
+		if reason := invalidPackageNamePartReason(part); reason != "" {
+			return PackageName(""), &InvalidPackageNameError{
+				Name:   name,
+				Reason: reason,
+			}
+		}
+	}
+
 	return PackageName(name), nil
 }
 
+// PackageScope is the scope of packages including the "@", e.g. "@scope".
+type PackageScope string
+
+func (s PackageScope) String() string {
+	return string(s)
+}
+
+// Contains reports whether the package belongs to the scope.
+func (s PackageScope) Contains(name PackageName) bool {
+	return name.Scope() == string(s)
+}
+
+// PackageScopeFromString validates the given string and returns a PackageScope. The "@" may be omitted.
+// If the string is invalid, an error is returned.
+func PackageScopeFromString(s string) (PackageScope, error) {
+	scope := strings.TrimPrefix(strings.TrimSpace(s), "@")
+	if reason := invalidPackageNamePartReason(scope); reason != "" {
+		return PackageScope(""), &InvalidPackageNameError{
+			Name:   s,
+			Reason: reason,
+		}
+	}
+	return PackageScope("@" + scope), nil
+}
+
+// helpers
+
+func invalidPackageNamePartReason(part string) string {
+	if part == "" {
+		return "package name and scope cannot be empty"
+	}
+	if part[0] == '.' || part[0] == '_' {
+		return "package name and scope cannot start with . or _"
+	}
+	for _, c := range part {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			strings.ContainsRune("-._~!*'()", c)) {
+			return fmt.Sprintf("package name contains the invalid character %q", c)
+		}
+	}
+	return ""
+}
+
 // errors
 
 type InvalidPackageNameError struct {
@@ -44,3 +170,12 @@ type InvalidPackageNameError struct {
 func (e *InvalidPackageNameError) Error() string {
 	return fmt.Sprintf("invalid package name %s: %s", e.Name, e.Reason)
 }
+
+type InvalidTarballNameError struct {
+	Name    string
+	Tarball string
+}
+
+func (e *InvalidTarballNameError) Error() string {
+	return fmt.Sprintf("invalid tarball %s for package %s", e.Tarball, e.Name)
+}
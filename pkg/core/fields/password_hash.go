@@ -0,0 +1,9 @@
+package fields
+
+// PasswordHash is the stored form of a Password, e.g. "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>".
+// Rows created before passwords were hashed may still hold the plaintext password.
+type PasswordHash string
+
+func (h PasswordHash) String() string {
+	return string(h)
+}
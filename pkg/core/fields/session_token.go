@@ -1,6 +1,21 @@
 package fields
 
-// SessionToken is a string that represents a session token.
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SessionTokenPrefix makes session tokens recognizable, e.g. by secret scanners.
+const SessionTokenPrefix = "nox_"
+
+// sessionTokenBytes is the entropy of a session token.
+const sessionTokenBytes = 32
+
+// SessionToken is the secret of a session like "nox_<64 hex chars>". It is only known to the client,
+// the session store keeps its SessionTokenKey. Tokens of older sessions are UUIDs, which remain valid until
+// their sessions expire.
 // It must not be empty.
 type SessionToken string
 
@@ -8,6 +23,21 @@ func (t SessionToken) String() string {
 	return string(t)
 }
 
+// Key returns the SHA-256 of the token, which identifies the session in the session store.
+func (t SessionToken) Key() SessionTokenKey {
+	sum := sha256.Sum256([]byte(t))
+	return SessionTokenKey(hex.EncodeToString(sum[:]))
+}
+
+// NewSessionToken generates a random session token.
+func NewSessionToken() (SessionToken, error) {
+	b := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SessionToken(SessionTokenPrefix + hex.EncodeToString(b)), nil
+}
+
 func SessionTokenFromString(token string) (SessionToken, error) {
 	if token == "" {
 		return "", &EmptySessionTokenError{}
@@ -15,6 +45,21 @@ func SessionTokenFromString(token string) (SessionToken, error) {
 	return SessionToken(token), nil
 }
 
+// SessionTokenKey is the hex encoded SHA-256 of a SessionToken.
+type SessionTokenKey string
+
+func (k SessionTokenKey) String() string {
+	return string(k)
+}
+
+func SessionTokenKeyFromString(key string) (SessionTokenKey, error) {
+	b, err := hex.DecodeString(key)
+	if err != nil || len(b) != sha256.Size {
+		return "", &InvalidSessionTokenKeyError{Key: key}
+	}
+	return SessionTokenKey(strings.ToLower(key)), nil
+}
+
 // errors
 
 type EmptySessionTokenError struct{}
@@ -22,3 +67,11 @@ type EmptySessionTokenError struct{}
 func (e EmptySessionTokenError) Error() string {
 	return "SessionToken is empty"
 }
+
+type InvalidSessionTokenKeyError struct {
+	Key string
+}
+
+func (e InvalidSessionTokenKeyError) Error() string {
+	return "session token key " + e.Key + " is invalid"
+}
@@ -0,0 +1,43 @@
+package fields
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SignupPolicy defines who may create an account with `npm adduser`.
+type SignupPolicy string
+
+const (
+	// SignupPolicyDisabled only allows admins to create users.
+	SignupPolicyDisabled SignupPolicy = "disabled"
+	// SignupPolicyOpen allows everyone to sign up.
+	SignupPolicyOpen SignupPolicy = "open"
+	// SignupPolicyInvite allows email addresses with an unused invite to sign up.
+	SignupPolicyInvite SignupPolicy = "invite"
+	// SignupPolicyDomain allows email addresses of an allowlist of domains to sign up.
+	SignupPolicyDomain SignupPolicy = "domain"
+)
+
+func (p SignupPolicy) String() string {
+	return string(p)
+}
+
+func SignupPolicyFromString(s string) (SignupPolicy, error) {
+	switch policy := SignupPolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case SignupPolicyDisabled, SignupPolicyOpen, SignupPolicyInvite, SignupPolicyDomain:
+		return policy, nil
+	default:
+		return "", &InvalidSignupPolicyError{Policy: s}
+	}
+}
+
+// errors
+
+type InvalidSignupPolicyError struct {
+	Policy string
+}
+
+func (e *InvalidSignupPolicyError) Error() string {
+	return fmt.Sprintf("signup policy %s is invalid, must be disabled, open, invite or domain", e.Policy)
+}
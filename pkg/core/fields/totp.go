@@ -0,0 +1,200 @@
+package fields
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238 as supported by all authenticator apps.
+const (
+	totpPeriod     = 30
+	totpDigits     = 6
+	totpSecretSize = 20
+	// totpSkew is the number of periods a code may be early or late, to tolerate clock drift.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPSecret is the base32 encoded shared secret of a time-based one-time password.
+type TOTPSecret string
+
+func (s TOTPSecret) String() string {
+	return string(s)
+}
+
+// NewTOTPSecret generates a random secret.
+func NewTOTPSecret() (TOTPSecret, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return TOTPSecret(totpEncoding.EncodeToString(b)), nil
+}
+
+// URI returns the otpauth URI of the secret, which authenticator apps read from a QR code.
+func (s TOTPSecret) URI(issuer string, account string) string {
+	values := url.Values{}
+	values.Set("secret", s.String())
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + values.Encode()
+}
+
+// Code returns the one-time password of the given time step.
+func (s TOTPSecret) Code(step int64) (OTPCode, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(s.String()))
+	if err != nil {
+		return "", &InvalidTOTPSecretError{}
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return OTPCode(fmt.Sprintf("%0*d", totpDigits, value%1000000)), nil
+}
+
+// Verify checks the code against the time steps around now and returns the matching step.
+// Callers must reject steps that were already used to prevent replays.
+func (s TOTPSecret) Verify(code OTPCode, now time.Time) (int64, bool) {
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := s.Code(step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPStep returns the time step of a time.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// OTPCode is a one-time password sent by a client, either a TOTP code or a recovery code.
+type OTPCode string
+
+func (c OTPCode) String() string {
+	return string(c)
+}
+
+// IsTOTP reports whether the code looks like a TOTP code rather than a recovery code.
+func (c OTPCode) IsTOTP() bool {
+	if len(c) != totpDigits {
+		return false
+	}
+	for _, r := range c {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func OTPCodeFromString(s string) (OTPCode, error) {
+	code := strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if code == "" {
+		return "", &InvalidOTPCodeError{}
+	}
+	return OTPCode(code), nil
+}
+
+// recoveryCodeBytes is the entropy of a recovery code.
+const recoveryCodeBytes = 8
+
+// RecoveryCode is a single-use code that replaces a TOTP code if the authenticator is lost.
+type RecoveryCode string
+
+func (c RecoveryCode) String() string {
+	return string(c)
+}
+
+// Key returns the SHA-256 of the code, which is stored instead of the code.
+func (c RecoveryCode) Key() RecoveryCodeKey {
+	sum := sha256.Sum256([]byte(strings.ToLower(c.String())))
+	return RecoveryCodeKey(hex.EncodeToString(sum[:]))
+}
+
+// NewRecoveryCode generates a random recovery code like "1f2e3d4c5b6a7988".
+func NewRecoveryCode() (RecoveryCode, error) {
+	b := make([]byte, recoveryCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return RecoveryCode(hex.EncodeToString(b)), nil
+}
+
+// RecoveryCodeKey is the hex encoded SHA-256 of a RecoveryCode.
+type RecoveryCodeKey string
+
+func (k RecoveryCodeKey) String() string {
+	return string(k)
+}
+
+// TwoFactorMode defines which requests require a one-time password, following the modes of npm.
+type TwoFactorMode string
+
+const (
+	// TwoFactorModeAuthOnly requires a one-time password on login and for changes of the two-factor settings.
+	TwoFactorModeAuthOnly TwoFactorMode = "auth-only"
+	// TwoFactorModeAuthAndWrites additionally requires a one-time password to publish, unpublish and change dist-tags.
+	TwoFactorModeAuthAndWrites TwoFactorMode = "auth-and-writes"
+)
+
+func (m TwoFactorMode) String() string {
+	return string(m)
+}
+
+func TwoFactorModeFromString(s string) (TwoFactorMode, error) {
+	switch mode := TwoFactorMode(s); mode {
+	case TwoFactorModeAuthOnly, TwoFactorModeAuthAndWrites:
+		return mode, nil
+	default:
+		return "", &InvalidTwoFactorModeError{Mode: s}
+	}
+}
+
+// errors
+
+type InvalidTOTPSecretError struct{}
+
+func (e InvalidTOTPSecretError) Error() string {
+	return "totp secret is invalid"
+}
+
+type InvalidOTPCodeError struct{}
+
+func (e InvalidOTPCodeError) Error() string {
+	return "one-time password is empty"
+}
+
+type InvalidTwoFactorModeError struct {
+	Mode string
+}
+
+func (e InvalidTwoFactorModeError) Error() string {
+	return fmt.Sprintf("two-factor mode %s is invalid, must be auth-only or auth-and-writes", e.Mode)
+}
@@ -0,0 +1,225 @@
+package fields
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version as specified by SemVer 2.0 (https://semver.org).
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      []string
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// IsPrerelease reports whether the version has prerelease identifiers like "1.0.0-beta.1".
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1 if v has a lower precedence than o, 1 if it has a higher one and 0 if both are equal.
+// Build metadata is ignored as required by the specification.
+func (v Version) Compare(o Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// a version without prerelease has a higher precedence than one with prerelease
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := comparePrereleaseIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(o.Prerelease)))
+}
+
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+func (v Version) GreaterThan(o Version) bool {
+	return v.Compare(o) > 0
+}
+
+// HighestVersion returns the highest stable version, or the highest prerelease if there is no stable version.
+// It returns the zero version if versions is empty.
+func HighestVersion(versions []Version) Version {
+	var highest *Version
+	for i := range versions {
+		v := versions[i]
+		switch {
+		case highest == nil:
+			highest = &v
+		case highest.IsPrerelease() && !v.IsPrerelease():
+			highest = &v
+		case highest.IsPrerelease() == v.IsPrerelease() && v.GreaterThan(*highest):
+			highest = &v
+		}
+	}
+	if highest == nil {
+		return Version{}
+	}
+	return *highest
+}
+
+// converters
+
+// VersionFromString parses a strict SemVer 2.0 version like "1.2.3-beta.1+build.5".
+// If the string is invalid, an error is returned.
+func VersionFromString(s string) (Version, error) {
+	if s == "" {
+		return Version{}, &InvalidVersionError{Version: s, Reason: "version cannot be empty"}
+	}
+
+	core, build, hasBuild := strings.Cut(s, "+")
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, &InvalidVersionError{Version: s, Reason: "version must consist of major, minor and patch"}
+	}
+
+	var numbers [3]uint64
+	for i, part := range parts {
+		n, err := parseNumericIdentifier(part)
+		if err != nil {
+			return Version{}, &InvalidVersionError{Version: s, Reason: err.Error()}
+		}
+		numbers[i] = n
+	}
+
+	v := Version{
+		Major: numbers[0],
+		Minor: numbers[1],
+		Patch: numbers[2],
+	}
+
+	if hasPrerelease {
+		ids, err := parseIdentifiers(prerelease, true)
+		if err != nil {
+			return Version{}, &InvalidVersionError{Version: s, Reason: "prerelease: " + err.Error()}
+		}
+		v.Prerelease = ids
+	}
+
+	if hasBuild {
+		ids, err := parseIdentifiers(build, false)
+		if err != nil {
+			return Version{}, &InvalidVersionError{Version: s, Reason: "build: " + err.Error()}
+		}
+		v.Build = ids
+	}
+
+	return v, nil
+}
+
+// helpers
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrereleaseIdentifier compares numeric identifiers numerically and alphanumeric ones in ASCII order.
+// Numeric identifiers always have a lower precedence than alphanumeric ones.
+func comparePrereleaseIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func parseNumericIdentifier(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("numeric identifier cannot be empty")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("numeric identifier %s must not have leading zeros", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid numeric identifier %s", s)
+	}
+	return n, nil
+}
+
+func parseIdentifiers(s string, strictNumeric bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("identifier cannot be empty")
+		}
+		numeric := true
+		for _, c := range id {
+			if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-') {
+				return nil, fmt.Errorf("identifier %s contains invalid characters", id)
+			}
+			if c < '0' || c > '9' {
+				numeric = false
+			}
+		}
+		if strictNumeric && numeric && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("numeric identifier %s must not have leading zeros", id)
+		}
+	}
+	return ids, nil
+}
+
+// errors
+
+type InvalidVersionError struct {
+	Version string
+	Reason  string
+}
+
+func (e *InvalidVersionError) Error() string {
+	return fmt.Sprintf("invalid version %s: %s", e.Version, e.Reason)
+}
@@ -0,0 +1,336 @@
+package fields
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// VersionRange is a npm style version range like "^1.2.0 || >=2.0.0-beta <3".
+// It supports comparators, hyphen ranges, x-ranges as well as tilde and caret ranges.
+type VersionRange struct {
+	raw  string
+	sets [][]versionComparator
+}
+
+func (r VersionRange) String() string {
+	return r.raw
+}
+
+// Contains reports whether the version satisfies the range.
+// Prerelease versions only satisfy a range if one of its comparators has a prerelease on the same major, minor and patch.
+func (r VersionRange) Contains(v Version) bool {
+	for _, set := range r.sets {
+		if versionSetContains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxSatisfying returns the highest of the given versions that satisfies the range.
+// The boolean is false if no version satisfies the range.
+func (r VersionRange) MaxSatisfying(versions []Version) (Version, bool) {
+	var max Version
+	found := false
+	for _, v := range versions {
+		if r.Contains(v) && (!found || v.GreaterThan(max)) {
+			max = v
+			found = true
+		}
+	}
+	return max, found
+}
+
+// converters
+
+// VersionRangeFromString parses a npm style version range. An empty range matches every version.
+// If the string is invalid, an error is returned.
+func VersionRangeFromString(s string) (VersionRange, error) {
+	r := VersionRange{raw: s}
+
+	for _, rawSet := range strings.Split(s, "||") {
+		set, err := parseVersionSet(rawSet)
+		if err != nil {
+			return VersionRange{}, &InvalidVersionRangeError{Range: s, Reason: err.Error()}
+		}
+		r.sets = append(r.sets, set)
+	}
+
+	return r, nil
+}
+
+// comparators
+
+type versionComparator struct {
+	// op is one of "<", "<=", ">", ">=", "=" or "*" for a comparator matching every version
+	op      string
+	version Version
+}
+
+var anyVersionComparator = versionComparator{op: "*"}
+
+// noVersionComparator matches no version at all since 0.0.0-0 is the lowest possible version.
+var noVersionComparator = versionComparator{op: "<", version: Version{Prerelease: []string{"0"}}}
+
+func (c versionComparator) matches(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "*":
+		return true
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return cmp == 0
+}
+
+func versionSetContains(set []versionComparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+
+	if !v.IsPrerelease() {
+		return true
+	}
+
+	for _, c := range set {
+		if c.op == "*" || !c.version.IsPrerelease() {
+			continue
+		}
+		if c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsing
+
+var versionOperatorSpaces = regexp.MustCompile(`(<=|>=|<|>|=|~>|~|\^)\s+`)
+
+func parseVersionSet(s string) ([]versionComparator, error) {
+	tokens := strings.Fields(versionOperatorSpaces.ReplaceAllString(s, "$1"))
+
+	if len(tokens) == 0 {
+		return []versionComparator{anyVersionComparator}, nil
+	}
+
+	if len(tokens) == 3 && tokens[1] == "-" {
+		return parseHyphenRange(tokens[0], tokens[2])
+	}
+
+	var set []versionComparator
+	for _, token := range tokens {
+		comparators, err := parseVersionComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+
+	return set, nil
+}
+
+func parseVersionComparator(token string) ([]versionComparator, error) {
+	for _, op := range []string{"<=", ">=", "~>", "<", ">", "=", "~", "^"} {
+		if !strings.HasPrefix(token, op) {
+			continue
+		}
+
+		p, err := parsePartialVersion(strings.TrimPrefix(token, op))
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "~", "~>":
+			return tildeRange(p), nil
+		case "^":
+			return caretRange(p), nil
+		}
+		return xRange(op, p), nil
+	}
+
+	p, err := parsePartialVersion(token)
+	if err != nil {
+		return nil, err
+	}
+	return xRange("=", p), nil
+}
+
+// partialVersion is a version where only the first n of major, minor and patch are given, e.g. "1.2.x".
+type partialVersion struct {
+	version Version
+	n       int
+}
+
+func (p partialVersion) bump() Version {
+	if p.n == 1 {
+		return Version{Major: p.version.Major + 1, Prerelease: []string{"0"}}
+	}
+	return Version{Major: p.version.Major, Minor: p.version.Minor + 1, Prerelease: []string{"0"}}
+}
+
+func parsePartialVersion(s string) (partialVersion, error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return partialVersion{}, fmt.Errorf("missing version")
+	}
+
+	core, _, _ := strings.Cut(s, "+")
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return partialVersion{}, fmt.Errorf("version %s has too many parts", s)
+	}
+
+	var numbers [3]uint64
+	n := 0
+	for i, part := range parts {
+		// like npm the parts after a wildcard are ignored, "1.x.3" is "1.x"
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		number, err := parseNumericIdentifier(part)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("version %s: %s", s, err)
+		}
+		numbers[i] = number
+		n++
+	}
+
+	p := partialVersion{
+		version: Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]},
+		n:       n,
+	}
+
+	if hasPrerelease {
+		if n != 3 {
+			return partialVersion{}, fmt.Errorf("version %s has a prerelease without a patch", s)
+		}
+		ids, err := parseIdentifiers(prerelease, true)
+		if err != nil {
+			return partialVersion{}, fmt.Errorf("version %s: %s", s, err)
+		}
+		p.version.Prerelease = ids
+	}
+
+	return p, nil
+}
+
+func xRange(op string, p partialVersion) []versionComparator {
+	if p.n == 3 {
+		return []versionComparator{{op: op, version: p.version}}
+	}
+
+	switch op {
+	case ">":
+		if p.n == 0 {
+			return []versionComparator{noVersionComparator}
+		}
+		// unlike the upper bounds the lower bound excludes prereleases of the bumped version, like ">1" is ">=2.0.0"
+		lower := p.bump()
+		lower.Prerelease = nil
+		return []versionComparator{{op: ">=", version: lower}}
+	case ">=":
+		if p.n == 0 {
+			return []versionComparator{anyVersionComparator}
+		}
+		return []versionComparator{{op: ">=", version: p.version}}
+	case "<":
+		if p.n == 0 {
+			return []versionComparator{noVersionComparator}
+		}
+		lower := p.version
+		lower.Prerelease = []string{"0"}
+		return []versionComparator{{op: "<", version: lower}}
+	case "<=":
+		if p.n == 0 {
+			return []versionComparator{anyVersionComparator}
+		}
+		return []versionComparator{{op: "<", version: p.bump()}}
+	}
+
+	if p.n == 0 {
+		return []versionComparator{anyVersionComparator}
+	}
+	return []versionComparator{{op: ">=", version: p.version}, {op: "<", version: p.bump()}}
+}
+
+// tildeRange allows patch-level changes if a minor version is given and minor-level changes if not.
+func tildeRange(p partialVersion) []versionComparator {
+	switch p.n {
+	case 0:
+		return []versionComparator{anyVersionComparator}
+	case 1:
+		return []versionComparator{{op: ">=", version: p.version}, {op: "<", version: p.bump()}}
+	}
+	upper := partialVersion{version: p.version, n: 2}.bump()
+	return []versionComparator{{op: ">=", version: p.version}, {op: "<", version: upper}}
+}
+
+// caretRange allows changes that do not modify the left-most non-zero of major, minor and patch.
+func caretRange(p partialVersion) []versionComparator {
+	if p.n == 0 {
+		return []versionComparator{anyVersionComparator}
+	}
+
+	var upper Version
+	switch {
+	case p.n == 1 || p.version.Major > 0:
+		upper = partialVersion{version: p.version, n: 1}.bump()
+	case p.n == 2 || p.version.Minor > 0:
+		upper = partialVersion{version: p.version, n: 2}.bump()
+	default:
+		upper = Version{Minor: p.version.Minor, Patch: p.version.Patch + 1, Prerelease: []string{"0"}}
+	}
+
+	return []versionComparator{{op: ">=", version: p.version}, {op: "<", version: upper}}
+}
+
+func parseHyphenRange(from, to string) ([]versionComparator, error) {
+	lower, err := parsePartialVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parsePartialVersion(to)
+	if err != nil {
+		return nil, err
+	}
+
+	set := []versionComparator{anyVersionComparator}
+	if lower.n > 0 {
+		set = append(set, versionComparator{op: ">=", version: lower.version})
+	}
+
+	switch upper.n {
+	case 0:
+	case 3:
+		set = append(set, versionComparator{op: "<=", version: upper.version})
+	default:
+		set = append(set, versionComparator{op: "<", version: upper.bump()})
+	}
+
+	return set, nil
+}
+
+// errors
+
+type InvalidVersionRangeError struct {
+	Range  string
+	Reason string
+}
+
+func (e *InvalidVersionRangeError) Error() string {
+	return fmt.Sprintf("invalid version range %s: %s", e.Range, e.Reason)
+}
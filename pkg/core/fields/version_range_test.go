@@ -0,0 +1,169 @@
+package fields
+
+import (
+	"testing"
+)
+
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		version      string
+		want         bool
+	}{
+		// exact versions and comparators
+		{versionRange: "1.2.3", version: "1.2.3", want: true},
+		{versionRange: "=1.2.3", version: "1.2.4", want: false},
+		{versionRange: "v1.2.3", version: "1.2.3", want: true},
+		{versionRange: ">=1.2.3 <2", version: "1.9.9", want: true},
+		{versionRange: ">= 1.2.3 < 2", version: "2.0.0", want: false},
+		{versionRange: "<=1.2", version: "1.2.9", want: true},
+		{versionRange: "<=1.2", version: "1.3.0", want: false},
+		{versionRange: "<1.2", version: "1.1.9", want: true},
+		{versionRange: "<1.2", version: "1.2.0", want: false},
+		{versionRange: "", version: "3.0.0", want: true},
+
+		// caret ranges keep the left-most non-zero part
+		{versionRange: "^1.2.3", version: "1.9.0", want: true},
+		{versionRange: "^1.2.3", version: "1.2.2", want: false},
+		{versionRange: "^1.2.3", version: "2.0.0", want: false},
+		{versionRange: "^0.2.3", version: "0.2.9", want: true},
+		{versionRange: "^0.2.3", version: "0.3.0", want: false},
+		{versionRange: "^0.0.3", version: "0.0.3", want: true},
+		{versionRange: "^0.0.3", version: "0.0.4", want: false},
+		{versionRange: "^0.0", version: "0.0.9", want: true},
+		{versionRange: "^0.0", version: "0.1.0", want: false},
+		{versionRange: "^1", version: "1.99.0", want: true},
+		{versionRange: "^ 1.2", version: "1.3.0", want: true},
+
+		// tilde ranges allow patch changes, or minor changes without a minor version
+		{versionRange: "~1.2.3", version: "1.2.9", want: true},
+		{versionRange: "~1.2.3", version: "1.3.0", want: false},
+		{versionRange: "~1.2", version: "1.2.0", want: true},
+		{versionRange: "~1", version: "1.9.0", want: true},
+		{versionRange: "~1", version: "2.0.0", want: false},
+		{versionRange: "~>1.2.3", version: "1.2.5", want: true},
+
+		// x-ranges
+		{versionRange: "*", version: "0.0.1", want: true},
+		{versionRange: "x", version: "5.0.0", want: true},
+		{versionRange: "1.x", version: "1.5.0", want: true},
+		{versionRange: "1.x", version: "2.0.0", want: false},
+		{versionRange: "1.2.*", version: "1.2.7", want: true},
+		{versionRange: "1.2.X", version: "1.3.0", want: false},
+		{versionRange: "1.x.3", version: "1.5.0", want: true},
+		{versionRange: "1", version: "1.0.0", want: true},
+		{versionRange: ">1", version: "1.9.9", want: false},
+		{versionRange: ">1", version: "2.0.0", want: true},
+		{versionRange: ">1.2", version: "1.3.0", want: true},
+		{versionRange: ">*", version: "1.0.0", want: false},
+		{versionRange: "<*", version: "1.0.0", want: false},
+
+		// hyphen ranges include both ends, partial upper ends are x-ranges
+		{versionRange: "1.2.3 - 2.3.4", version: "1.2.3", want: true},
+		{versionRange: "1.2.3 - 2.3.4", version: "2.3.4", want: true},
+		{versionRange: "1.2.3 - 2.3.4", version: "2.3.5", want: false},
+		{versionRange: "1.2 - 2.3", version: "2.3.9", want: true},
+		{versionRange: "1.2 - 2.3", version: "2.4.0", want: false},
+		{versionRange: "1.2 - 2.3", version: "1.1.9", want: false},
+		{versionRange: "1.2.3 - *", version: "9.0.0", want: true},
+
+		// any set of || may match
+		{versionRange: "1.x || >=2.5.0 || 5.0.0 - 7.2.3", version: "1.3.0", want: true},
+		{versionRange: "1.x || >=2.5.0 || 5.0.0 - 7.2.3", version: "2.4.0", want: false},
+		{versionRange: "1.x || >=2.5.0 || 5.0.0 - 7.2.3", version: "2.5.0", want: true},
+		{versionRange: "^1.0.0 || ^3.0.0", version: "2.0.0", want: false},
+		{versionRange: "^1.0.0 || ^3.0.0", version: "3.1.0", want: true},
+
+		// prereleases only match comparators with a prerelease of the same major, minor and patch
+		{versionRange: ">=1.2.3-beta.2", version: "1.2.3-beta.3", want: true},
+		{versionRange: ">=1.2.3-beta.2", version: "1.2.3-beta.1", want: false},
+		{versionRange: ">=1.2.3-beta.2", version: "1.2.4-beta.3", want: false},
+		{versionRange: ">=1.2.3-beta.2", version: "1.2.4", want: true},
+		{versionRange: "^1.2.3-beta.2", version: "1.2.3-rc.1", want: true},
+		{versionRange: "^1.2.3-beta.2", version: "1.3.0-beta.1", want: false},
+		{versionRange: "^1.2.3", version: "1.3.0-beta.1", want: false},
+		{versionRange: "*", version: "1.0.0-beta.1", want: false},
+		{versionRange: "<2", version: "2.0.0-beta.1", want: false},
+		{versionRange: "1.2.3 - 2.0.0-rc.1", version: "2.0.0-beta.1", want: true},
+
+		// ">" x-ranges exclude the prereleases of the bumped version
+		{versionRange: ">1", version: "2.0.0-0", want: false},
+		{versionRange: ">1", version: "2.0.0-beta.1", want: false},
+		{versionRange: ">1.2", version: "1.3.0-beta.1", want: false},
+		{versionRange: ">1.2.3-beta.1", version: "1.2.3-beta.2", want: true},
+
+		// build metadata is ignored
+		{versionRange: "1.2.3", version: "1.2.3+build.5", want: true},
+		{versionRange: "^1.2.3+build.1", version: "1.2.4", want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.versionRange+" "+test.version, func(t *testing.T) {
+			r, err := VersionRangeFromString(test.versionRange)
+			if err != nil {
+				t.Fatalf("failed to parse range: %v", err)
+			}
+
+			if got := r.Contains(mustVersion(t, test.version)); got != test.want {
+				t.Fatalf("expected %s to contain %s to be %v", test.versionRange, test.version, test.want)
+			}
+		})
+	}
+}
+
+func TestVersionRangeFromStringInvalid(t *testing.T) {
+	for _, versionRange := range []string{
+		"1.2.3.4",
+		"1.2-beta",
+		"^01.2.3",
+		">=a.b.c",
+		"1.2.3 - ",
+		"1.2.3 || ~",
+	} {
+		versionRange := versionRange
+		t.Run(versionRange, func(t *testing.T) {
+			_, err := VersionRangeFromString(versionRange)
+			if _, ok := err.(*InvalidVersionRangeError); !ok {
+				t.Fatalf("expected InvalidVersionRangeError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVersionRangeMaxSatisfying(t *testing.T) {
+	versions := []Version{}
+	for _, v := range []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0-beta.1", "2.0.0", "2.1.0-rc.1"} {
+		versions = append(versions, mustVersion(t, v))
+	}
+
+	tests := []struct {
+		versionRange string
+		want         string
+		found        bool
+	}{
+		{versionRange: "^1.0.0", want: "1.10.0", found: true},
+		{versionRange: "~1.2", want: "1.2.0", found: true},
+		{versionRange: "*", want: "2.0.0", found: true},
+		{versionRange: ">=2.1.0-rc.0", want: "2.1.0-rc.1", found: true},
+		{versionRange: "^3", found: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.versionRange, func(t *testing.T) {
+			r, err := VersionRangeFromString(test.versionRange)
+			if err != nil {
+				t.Fatalf("failed to parse range: %v", err)
+			}
+
+			got, found := r.MaxSatisfying(versions)
+			if found != test.found {
+				t.Fatalf("expected found to be %v, got %v", test.found, found)
+			}
+			if found && got.String() != test.want {
+				t.Fatalf("expected %s, got %s", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package fields
+
+import (
+	"testing"
+)
+
+func mustVersion(t *testing.T, s string) Version {
+	t.Helper()
+
+	v, err := VersionFromString(s)
+	if err != nil {
+		t.Fatalf("failed to parse version %s: %v", s, err)
+	}
+	return v
+}
+
+func TestVersionFromString(t *testing.T) {
+	tests := []struct {
+		version string
+		valid   bool
+		want    string
+	}{
+		{version: "1.2.3", valid: true, want: "1.2.3"},
+		{version: "0.0.0", valid: true, want: "0.0.0"},
+		{version: "1.2.3-beta.1", valid: true, want: "1.2.3-beta.1"},
+		{version: "1.2.3-0.3.7", valid: true, want: "1.2.3-0.3.7"},
+		{version: "1.2.3-x-y.z", valid: true, want: "1.2.3-x-y.z"},
+		{version: "1.2.3+build.5", valid: true, want: "1.2.3+build.5"},
+		{version: "1.2.3-rc.1+001", valid: true, want: "1.2.3-rc.1+001"},
+		{version: "", valid: false},
+		{version: "1.2", valid: false},
+		{version: "1.2.3.4", valid: false},
+		{version: "v1.2.3", valid: false},
+		{version: "01.2.3", valid: false},
+		{version: "1.2.3-01", valid: false},
+		{version: "1.2.3-", valid: false},
+		{version: "1.2.3-beta..1", valid: false},
+		{version: "1.2.3+", valid: false},
+		{version: "1.2.3-beta_1", valid: false},
+		{version: "1.x.3", valid: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.version, func(t *testing.T) {
+			v, err := VersionFromString(test.version)
+			if !test.valid {
+				if _, ok := err.(*InvalidVersionError); !ok {
+					t.Fatalf("expected InvalidVersionError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v.String() != test.want {
+				t.Fatalf("expected %s, got %s", test.want, v)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		// precedence of major, minor and patch
+		{a: "1.0.0", b: "2.0.0", want: -1},
+		{a: "2.0.0", b: "2.1.0", want: -1},
+		{a: "2.1.0", b: "2.1.1", want: -1},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		// prereleases are lower than the release
+		{a: "1.0.0-alpha", b: "1.0.0", want: -1},
+		{a: "1.0.0", b: "1.0.0-rc.1", want: 1},
+		// prerelease ordering of the specification
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{a: "1.0.0-alpha.beta", b: "1.0.0-beta", want: -1},
+		{a: "1.0.0-beta", b: "1.0.0-beta.2", want: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{a: "1.0.0-beta.11", b: "1.0.0-rc.1", want: -1},
+		{a: "1.0.0-1", b: "1.0.0-alpha", want: -1},
+		{a: "1.0.0-rc.1", b: "1.0.0-rc.1", want: 0},
+		// build metadata is ignored
+		{a: "1.0.0+build.1", b: "1.0.0+build.2", want: 0},
+		{a: "1.0.0+build", b: "1.0.0", want: 0},
+		{a: "1.0.0-rc.1+build", b: "1.0.0-rc.1", want: 0},
+		{a: "1.0.0+build", b: "1.0.1", want: -1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			a, b := mustVersion(t, test.a), mustVersion(t, test.b)
+
+			if got := a.Compare(b); got != test.want {
+				t.Fatalf("expected %s compared to %s to be %d, got %d", test.a, test.b, test.want, got)
+			}
+			if got := b.Compare(a); got != -test.want {
+				t.Fatalf("expected %s compared to %s to be %d, got %d", test.b, test.a, -test.want, got)
+			}
+			if a.Equal(b) != (test.want == 0) || a.LessThan(b) != (test.want < 0) || a.GreaterThan(b) != (test.want > 0) {
+				t.Fatalf("Equal, LessThan and GreaterThan of %s and %s disagree with Compare", test.a, test.b)
+			}
+		})
+	}
+}
+
+func TestHighestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+	}{
+		{name: "empty", versions: nil, want: "0.0.0"},
+		{name: "highest stable", versions: []string{"1.0.0", "1.10.0", "1.9.0"}, want: "1.10.0"},
+		{name: "stable before higher prerelease", versions: []string{"1.0.0", "2.0.0-beta.1"}, want: "1.0.0"},
+		{name: "highest prerelease without stable", versions: []string{"2.0.0-beta.2", "2.0.0-beta.10", "1.0.0-rc.1"}, want: "2.0.0-beta.10"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			versions := make([]Version, 0, len(test.versions))
+			for _, v := range test.versions {
+				versions = append(versions, mustVersion(t, v))
+			}
+
+			if got := HighestVersion(versions); got.String() != test.want {
+				t.Fatalf("expected %s, got %s", test.want, got)
+			}
+		})
+	}
+}
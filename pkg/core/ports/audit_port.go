@@ -0,0 +1,46 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// AuditAction names the kind of an audit entry.
+type AuditAction string
+
+const (
+	// AuditActionLoginLockout is recorded when failed logins lock an account or IP address.
+	AuditActionLoginLockout AuditAction = "login.lockout"
+	// AuditActionLoginUnlock is recorded when an administrator unlocks the logins of a user.
+	AuditActionLoginUnlock AuditAction = "login.unlock"
+)
+
+// RecordAuditEntryInput contains the fields of a new audit entry.
+type RecordAuditEntryInput struct {
+	Action AuditAction
+	// ActorID is the user who caused the entry, it is nil for anonymous clients.
+	ActorID *fields.EntityID
+	// Subject is what the entry is about, e.g. the locked account.
+	Subject string
+	IP      string
+	Details string
+}
+
+type AuditPort interface {
+	// RecordAuditEntry stores a new audit entry.
+	// Returns AuditAdapterRecordError if the entry could not be stored.
+	RecordAuditEntry(ctx context.Context, input RecordAuditEntryInput) error
+}
+
+// errors
+
+type AuditAdapterRecordError struct {
+	Action AuditAction
+	Err    error
+}
+
+func (e *AuditAdapterRecordError) Error() string {
+	return fmt.Sprintf("audit adapter failed to record %s: %s", e.Action, e.Err)
+}
@@ -8,9 +8,19 @@ import (
 	"github.com/mrparano1d/noxite/pkg/core/fields"
 )
 
+// AuthPort provides the stored credentials of local users. Passwords are verified by the AuthService.
 type AuthPort interface {
-	Login(ctx context.Context, username fields.Username, password fields.Password) (*entities.User, error)
-	LoginByEmail(ctx context.Context, email fields.Email, password fields.Password) (*entities.User, error)
+	// GetCredentials returns the user with the given name together with its password hash.
+	// Returns AuthAdapterUserNotFoundError if there is no such user.
+	// Returns AuthAdapterLoginFailedError if the user could not be loaded.
+	GetCredentials(ctx context.Context, username fields.Username) (*entities.User, fields.PasswordHash, error)
+	// GetCredentialsByEmail returns the user with the given email together with its password hash.
+	// Returns AuthAdapterUserNotFoundError if there is no such user.
+	// Returns AuthAdapterLoginFailedError if the user could not be loaded.
+	GetCredentialsByEmail(ctx context.Context, email fields.Email) (*entities.User, fields.PasswordHash, error)
+	// UpdatePasswordHash replaces the password hash of the user, e.g. when an outdated hash is upgraded on login.
+	// Returns AuthAdapterUpdatePasswordHashError if the hash could not be stored.
+	UpdatePasswordHash(ctx context.Context, userID fields.EntityID, hash fields.PasswordHash) error
 }
 
 // errors
@@ -39,3 +49,12 @@ type AuthAdapterInvalidCredentialsError struct {
 func (e *AuthAdapterInvalidCredentialsError) Error() string {
 	return fmt.Sprintf("invalid credentials for user %s", e.Username)
 }
+
+type AuthAdapterUpdatePasswordHashError struct {
+	UserID fields.EntityID
+	Err    error
+}
+
+func (e *AuthAdapterUpdatePasswordHashError) Error() string {
+	return fmt.Sprintf("failed to update password hash of user %s: %s", e.UserID, e.Err)
+}
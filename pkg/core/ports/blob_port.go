@@ -0,0 +1,61 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// BlobPort stores binary content like package tarballs addressed by the sha256 digest of the content.
+type BlobPort interface {
+	// Put stores the content of the reader and returns its digest.
+	// Storing content that already exists is not an error.
+	// Returns BlobAdapterPutError if the content could not be stored.
+	Put(ctx context.Context, r io.Reader) (fields.Digest, error)
+	// Get returns a reader for the content of the digest and its size in bytes. The reader must be closed by the caller.
+	// Returns BlobAdapterNotFoundError if no content with the digest exists.
+	// Returns BlobAdapterGetError if the content could not be read.
+	Get(ctx context.Context, digest fields.Digest) (io.ReadCloser, int64, error)
+	// Delete removes the content of the digest.
+	// Returns BlobAdapterNotFoundError if no content with the digest exists.
+	// Returns BlobAdapterDeleteError if the content could not be removed.
+	Delete(ctx context.Context, digest fields.Digest) error
+}
+
+// errors
+
+type BlobAdapterNotFoundError struct {
+	Digest fields.Digest
+}
+
+func (e *BlobAdapterNotFoundError) Error() string {
+	return fmt.Sprintf("blob adapter didn't find blob %s", e.Digest)
+}
+
+type BlobAdapterPutError struct {
+	Err error
+}
+
+func (e *BlobAdapterPutError) Error() string {
+	return fmt.Sprintf("blob adapter failed to put blob: %s", e.Err)
+}
+
+type BlobAdapterGetError struct {
+	Digest fields.Digest
+	Err    error
+}
+
+func (e *BlobAdapterGetError) Error() string {
+	return fmt.Sprintf("blob adapter failed to get blob %s: %s", e.Digest, e.Err)
+}
+
+type BlobAdapterDeleteError struct {
+	Digest fields.Digest
+	Err    error
+}
+
+func (e *BlobAdapterDeleteError) Error() string {
+	return fmt.Sprintf("blob adapter failed to delete blob %s: %s", e.Digest, e.Err)
+}
@@ -0,0 +1,72 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// ExternalAuthPort verifies credentials against an external directory instead of the password hashes of the AuthPort.
+// The password is passed unvalidated, since the directory has its own password rules.
+type ExternalAuthPort interface {
+	// Authenticate verifies the password of the user and returns the identity of the directory.
+	// Returns ExternalAuthUserNotFoundError if the directory does not know the user, local credentials are checked then.
+	// Returns ExternalAuthInvalidCredentialsError if the password is wrong.
+	// Returns ExternalAuthNotAllowedError if the user may not use the registry, e.g. because no group maps to a role.
+	// Returns ExternalAuthError if the directory could not be queried.
+	Authenticate(ctx context.Context, username fields.Username, password string) (*entities.ExternalIdentity, error)
+}
+
+// ExternalRegistrationPort is an external directory that users signing up are added to.
+type ExternalRegistrationPort interface {
+	// Register adds the user with the password to the directory.
+	// Returns ExternalAuthUserExistsError if the directory knows the user already.
+	// Returns ExternalAuthError if the user could not be added.
+	Register(ctx context.Context, username fields.Username, password string) error
+}
+
+// errors
+
+type ExternalAuthUserNotFoundError struct {
+	Username fields.Username
+}
+
+func (e *ExternalAuthUserNotFoundError) Error() string {
+	return fmt.Sprintf("user %s not found in directory", e.Username)
+}
+
+type ExternalAuthUserExistsError struct {
+	Username fields.Username
+}
+
+func (e *ExternalAuthUserExistsError) Error() string {
+	return fmt.Sprintf("user %s exists in directory already", e.Username)
+}
+
+type ExternalAuthInvalidCredentialsError struct {
+	Username fields.Username
+}
+
+func (e *ExternalAuthInvalidCredentialsError) Error() string {
+	return fmt.Sprintf("invalid directory credentials for user %s", e.Username)
+}
+
+type ExternalAuthNotAllowedError struct {
+	Username fields.Username
+	Reason   string
+}
+
+func (e *ExternalAuthNotAllowedError) Error() string {
+	return fmt.Sprintf("user %s is not allowed to log in: %s", e.Username, e.Reason)
+}
+
+type ExternalAuthError struct {
+	Username fields.Username
+	Err      error
+}
+
+func (e *ExternalAuthError) Error() string {
+	return fmt.Sprintf("directory authentication of user %s failed: %s", e.Username, e.Err)
+}
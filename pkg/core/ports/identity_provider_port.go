@@ -0,0 +1,29 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+)
+
+// IdentityProviderPort is an OpenID Connect provider users log in with through the browser.
+type IdentityProviderPort interface {
+	// AuthCodeURL returns the authorization URL of the provider for the state, the nonce of the ID token
+	// and the S256 PKCE challenge.
+	AuthCodeURL(state string, nonce string, codeChallenge string) string
+	// Exchange redeems the authorization code with the PKCE verifier, verifies the ID token and returns the identity of its claims.
+	// Returns IdentityProviderExchangeError if the code could not be redeemed or the ID token is invalid.
+	// Returns ExternalAuthNotAllowedError if the claims do not identify an allowed user.
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*entities.ExternalIdentity, error)
+}
+
+// errors
+
+type IdentityProviderExchangeError struct {
+	Err error
+}
+
+func (e *IdentityProviderExchangeError) Error() string {
+	return fmt.Sprintf("identity provider failed to exchange code: %s", e.Err)
+}
@@ -0,0 +1,69 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// CreateInviteInput contains the fields required to create a new invite.
+type CreateInviteInput struct {
+	Email     fields.Email
+	RoleID    *fields.EntityID
+	CreatedBy fields.EntityID
+	ExpiresAt *time.Time
+}
+
+type InvitePort interface {
+	// CreateInvite creates a new invite.
+	// Returns the ID of the created invite.
+	// Returns InviteAdapterCreateInviteError if the invite could not be stored.
+	CreateInvite(ctx context.Context, input CreateInviteInput) (fields.EntityID, error)
+	// GetInvitesByEmail returns all invites of the email address, including used and expired ones.
+	// Returns InviteAdapterGetInvitesError if the invites could not be loaded.
+	GetInvitesByEmail(ctx context.Context, email fields.Email) ([]*entities.Invite, error)
+	// UseInvite marks the invite as used by the user.
+	// Returns InviteAdapterInviteNotFoundError if the invite does not exist or was used already.
+	// Returns InviteAdapterUseInviteError if the invite could not be updated.
+	UseInvite(ctx context.Context, inviteID fields.EntityID, userID fields.EntityID) error
+}
+
+// errors
+
+type InviteAdapterCreateInviteError struct {
+	Email fields.Email
+	Err   error
+}
+
+func (e *InviteAdapterCreateInviteError) Error() string {
+	return fmt.Sprintf("invite adapter failed to create invite for %s: %s", e.Email, e.Err)
+}
+
+type InviteAdapterGetInvitesError struct {
+	Email fields.Email
+	Err   error
+}
+
+func (e *InviteAdapterGetInvitesError) Error() string {
+	return fmt.Sprintf("invite adapter failed to get invites of %s: %s", e.Email, e.Err)
+}
+
+type InviteAdapterInviteNotFoundError struct {
+	ID fields.EntityID
+}
+
+func (e *InviteAdapterInviteNotFoundError) Error() string {
+	return fmt.Sprintf("invite %s not found or used already", e.ID)
+}
+
+type InviteAdapterUseInviteError struct {
+	ID  fields.EntityID
+	Err error
+}
+
+func (e *InviteAdapterUseInviteError) Error() string {
+	return fmt.Sprintf("invite adapter failed to use invite %s: %s", e.ID, e.Err)
+}
@@ -0,0 +1,63 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoginThrottlePort counts failed logins and blocks further logins, e.g. of an account or from an IP address.
+// Keys are chosen by the caller and name what is throttled.
+type LoginThrottlePort interface {
+	// RegisterFailure counts a failed login of the key and returns the number of failures. The failures are
+	// forgotten once window passed without another failure.
+	// Returns LoginThrottleAdapterRegisterFailureError if the failure could not be counted.
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Block rejects logins of the key until the given time.
+	// Returns LoginThrottleAdapterBlockError if the block could not be stored.
+	Block(ctx context.Context, key string, until time.Time) error
+	// BlockedUntil returns the time until which logins of the key are rejected, it is zero if they are not.
+	// Returns LoginThrottleAdapterGetBlockError if the block could not be loaded.
+	BlockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets the failures and the block of the key.
+	// Returns LoginThrottleAdapterResetError if they could not be deleted.
+	Reset(ctx context.Context, key string) error
+}
+
+// errors
+
+type LoginThrottleAdapterRegisterFailureError struct {
+	Key string
+	Err error
+}
+
+func (e *LoginThrottleAdapterRegisterFailureError) Error() string {
+	return fmt.Sprintf("login throttle adapter failed to register failure of %s: %s", e.Key, e.Err)
+}
+
+type LoginThrottleAdapterBlockError struct {
+	Key string
+	Err error
+}
+
+func (e *LoginThrottleAdapterBlockError) Error() string {
+	return fmt.Sprintf("login throttle adapter failed to block %s: %s", e.Key, e.Err)
+}
+
+type LoginThrottleAdapterGetBlockError struct {
+	Key string
+	Err error
+}
+
+func (e *LoginThrottleAdapterGetBlockError) Error() string {
+	return fmt.Sprintf("login throttle adapter failed to get block of %s: %s", e.Key, e.Err)
+}
+
+type LoginThrottleAdapterResetError struct {
+	Key string
+	Err error
+}
+
+func (e *LoginThrottleAdapterResetError) Error() string {
+	return fmt.Sprintf("login throttle adapter failed to reset %s: %s", e.Key, e.Err)
+}
@@ -10,7 +10,18 @@ import (
 
 type PackagePort interface {
 	ParseManifest(ctx context.Context, r io.Reader) (*entities.PackageVersion, error)
-	SerializeManifest(ctx context.Context, manifest *entities.PackageVersion) ([]byte, error)
+	// SerializeManifest encodes the package as a full registry document including all versions,
+	// dist-tags, maintainers and the time map. Tarball data is never included.
+	SerializeManifest(ctx context.Context, pkg *entities.Package) ([]byte, error)
+	// SerializeAbbreviatedManifest encodes the package as an abbreviated install document
+	// (application/vnd.npm.install-v1+json), which only holds the fields needed to resolve dependencies.
+	SerializeAbbreviatedManifest(ctx context.Context, pkg *entities.Package) ([]byte, error)
+	// ParsePackument decodes a package document sent by a client to update an existing package.
+	// Returns PackageAdapterManifestParseError if the document is invalid.
+	ParsePackument(ctx context.Context, r io.Reader) (*entities.PackumentUpdate, error)
+	// ReadTarball unpacks a gzipped package tarball and reads the package.json and file list of it.
+	// Returns PackageAdapterTarballError if the tarball is invalid or has no package.json.
+	ReadTarball(ctx context.Context, r io.Reader) (*entities.PackageTarball, error)
 }
 
 // errors
@@ -30,3 +41,11 @@ type PackageAdapterManifestConvertError struct {
 func (e *PackageAdapterManifestConvertError) Error() string {
 	return fmt.Sprintf("package adapter failed to convert manifest: %s", e.Err)
 }
+
+type PackageAdapterTarballError struct {
+	Err error
+}
+
+func (e *PackageAdapterTarballError) Error() string {
+	return fmt.Sprintf("package adapter failed to read tarball: %s", e.Err)
+}
@@ -0,0 +1,38 @@
+package ports
+
+import (
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// PasswordHasherPort hashes passwords before they are stored and verifies passwords against stored hashes.
+type PasswordHasherPort interface {
+	// Hash returns the hash of the password using the current algorithm and parameters.
+	// Returns PasswordHasherHashError if the password could not be hashed.
+	Hash(password fields.Password) (fields.PasswordHash, error)
+	// Verify reports whether the password matches the hash. The comparison takes constant time.
+	// Hashes of older algorithms and legacy plaintext passwords are verified as well.
+	// Returns PasswordHasherInvalidHashError if the hash is malformed.
+	Verify(password fields.Password, hash fields.PasswordHash) (bool, error)
+	// NeedsRehash reports whether the hash was created with another algorithm or other parameters than Hash uses.
+	NeedsRehash(hash fields.PasswordHash) bool
+}
+
+// errors
+
+type PasswordHasherHashError struct {
+	Err error
+}
+
+func (e *PasswordHasherHashError) Error() string {
+	return fmt.Sprintf("password hasher failed to hash password: %s", e.Err)
+}
+
+type PasswordHasherInvalidHashError struct {
+	Reason string
+}
+
+func (e *PasswordHasherInvalidHashError) Error() string {
+	return fmt.Sprintf("password hash is invalid: %s", e.Reason)
+}
@@ -34,15 +34,17 @@ type RolePort interface {
 // inputs
 
 type CreateRoleInput struct {
-	Name        fields.RequiredString
-	Description string
-	Permissions entities.Permissions
+	Name             fields.RequiredString
+	Description      string
+	Permissions      entities.Permissions
+	RequireTwoFactor bool
 }
 
 type UpdateRoleInput struct {
-	Name        *fields.RequiredString
-	Permissions *entities.Permissions
-	Description *string
+	Name             *fields.RequiredString
+	Permissions      *entities.Permissions
+	Description      *string
+	RequireTwoFactor *bool
 }
 
 // errors
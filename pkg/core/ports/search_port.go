@@ -0,0 +1,40 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+type SearchPort interface {
+	// Index adds the package document to the index or replaces the existing document of the package.
+	// Returns SearchAdapterIndexError if the document could not be indexed.
+	Index(ctx context.Context, doc *entities.SearchDocument) error
+	// Remove drops the package from the index. Removing a package that is not indexed is not an error.
+	// Returns SearchAdapterIndexError if the document could not be removed.
+	Remove(ctx context.Context, name fields.PackageName) error
+	// Search returns the page of packages matching the query.
+	// Returns SearchAdapterSearchError if the index could not be queried.
+	Search(ctx context.Context, query *entities.SearchQuery) (*entities.SearchResult, error)
+}
+
+// errors
+
+type SearchAdapterIndexError struct {
+	Name fields.PackageName
+	Err  error
+}
+
+func (e *SearchAdapterIndexError) Error() string {
+	return fmt.Sprintf("search adapter failed to index package %s: %s", e.Name, e.Err)
+}
+
+type SearchAdapterSearchError struct {
+	Err error
+}
+
+func (e *SearchAdapterSearchError) Error() string {
+	return fmt.Sprintf("search adapter failed to search: %s", e.Err)
+}
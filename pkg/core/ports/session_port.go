@@ -8,41 +8,49 @@ import (
 	"github.com/mrparano1d/noxite/pkg/core/fields"
 )
 
+// SessionPort stores sessions, which expire as defined by the entities.SessionTimeouts of the adapter.
+// Expired sessions behave like sessions that never existed.
+// Sessions are identified by the SHA-256 of their token, the token itself is never stored.
 type SessionPort interface {
-	// CreateSession creates a new session.
+	// CreateSession creates a new session used by the client. The returned session holds the new token.
 	// It returns an error if the session could not be created.
-	CreateSession(ctx context.Context) (*entities.Session, error)
+	CreateSession(ctx context.Context, client entities.SessionClient) (*entities.Session, error)
+	// TouchSession records a use of the session by the client and extends its expiry by the idle timeout,
+	// up to the absolute timeout.
+	// It returns SessionNotFoundError if the session does not exist or is expired.
+	TouchSession(ctx context.Context, tokenKey fields.SessionTokenKey, client entities.SessionClient) (*entities.Session, error)
 	// LinkSessionToUser links a session to a user.
+	// It returns SessionNotFoundError if the session does not exist or is expired.
 	// It returns an error if the session could not be linked to the user.
-	LinkSessionToUser(ctx context.Context, token fields.SessionToken, userID fields.EntityID) error
-	// InvalidateSession invalidates a session.
+	LinkSessionToUser(ctx context.Context, tokenKey fields.SessionTokenKey, userID fields.EntityID) error
+	// InvalidateSession invalidates a session. Invalidating an unknown session is not an error.
 	// It returns an error if the session could not be invalidated.
-	InvalidateSession(ctx context.Context, token fields.SessionToken) error
-	// GetLinkedSessions returns all sessions linked to the given user.
+	InvalidateSession(ctx context.Context, tokenKey fields.SessionTokenKey) error
+	// GetLinkedSessions returns the keys of all valid sessions linked to the given user.
 	// It returns an error if the sessions could not be retrieved.
-	GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionToken, error)
-	// ValidateToken validates a session token.
-	// It returns an error if the token is invalid or expired.
-	ValidateToken(ctx context.Context, token fields.SessionToken) error
-	// GetSession returns the session associated with the given token.
-	// It returns an error if the token is invalid or expired.
-	GetSession(ctx context.Context, token fields.SessionToken) (*entities.Session, error)
-	// SetValue sets a value for the given key in the session associated with the given token.
-	// It returns an error if the token is invalid or expired.
-	SetValue(ctx context.Context, token fields.SessionToken, key fields.RequiredString, value any) error
-	// GetValue returns the value associated with the given key in the session associated with the given token.
-	// It returns an error if the token is invalid or expired or if the key does not exist.
-	GetValue(ctx context.Context, token fields.SessionToken, key fields.RequiredString) ([]byte, error)
+	GetLinkedSessions(ctx context.Context, userID fields.EntityID) ([]fields.SessionTokenKey, error)
+	// ValidateToken validates a session.
+	// It returns SessionNotFoundError if the session does not exist or is expired.
+	ValidateToken(ctx context.Context, tokenKey fields.SessionTokenKey) error
+	// GetSession returns the session with the given key, its Token is empty.
+	// It returns SessionNotFoundError if the session does not exist or is expired.
+	GetSession(ctx context.Context, tokenKey fields.SessionTokenKey) (*entities.Session, error)
+	// SetValue sets a value for the given key in the session.
+	// It returns SessionNotFoundError if the session does not exist or is expired.
+	SetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString, value any) error
+	// GetValue returns the value associated with the given key in the session.
+	// It returns SessionNotFoundError if the session does not exist or is expired and KeyNotFoundError if the key does not exist.
+	GetValue(ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString) ([]byte, error)
 
 	Serialize(value any) ([]byte, error)
 	Deserialize(value []byte, target any) error
 }
 
-func SessionValueFromAdapter[V any](adapter SessionPort, ctx context.Context, token fields.SessionToken, key fields.RequiredString, defaultValue ...V) (V, error) {
+func SessionValueFromAdapter[V any](adapter SessionPort, ctx context.Context, tokenKey fields.SessionTokenKey, key fields.RequiredString, defaultValue ...V) (V, error) {
 	var zero V
-	value, err := adapter.GetValue(ctx, token, key)
+	value, err := adapter.GetValue(ctx, tokenKey, key)
 	if err != nil {
-		if _, ok := err.(KeyNotFoundError); ok && len(defaultValue) > 0 {
+		if _, ok := err.(*KeyNotFoundError); ok && len(defaultValue) > 0 {
 			return defaultValue[0], nil
 		} else {
 			return zero, err
@@ -52,9 +60,9 @@ func SessionValueFromAdapter[V any](adapter SessionPort, ctx context.Context, to
 	err = adapter.Deserialize(value, &zero)
 	if err != nil {
 		return zero, &InvalidValueTypeError{
-			Token:  token,
-			Key:    key,
-			Reason: err.Error(),
+			TokenKey: tokenKey,
+			Key:      key,
+			Reason:   err.Error(),
 		}
 	}
 	return zero, nil
@@ -71,104 +79,113 @@ func (e CreateSessionFailedError) Error() string {
 }
 
 type ValidateTokenFailedError struct {
-	Token fields.SessionToken
-	Err   error
+	TokenKey fields.SessionTokenKey
+	Err      error
 }
 
 func (e ValidateTokenFailedError) Error() string {
-	return "ValidateToken failed for token " + e.Token.String() + ": " + e.Err.Error()
+	return "ValidateToken failed for session " + e.TokenKey.String() + ": " + e.Err.Error()
 }
 
 type SessionNotFoundError struct {
-	Token fields.SessionToken
+	TokenKey fields.SessionTokenKey
 }
 
 func (e SessionNotFoundError) Error() string {
-	return "Session not found for token " + e.Token.String()
+	return "Session not found for key " + e.TokenKey.String()
 }
 
 type InvalidTokenError struct {
-	Token fields.SessionToken
+	TokenKey fields.SessionTokenKey
 }
 
 func (e InvalidTokenError) Error() string {
-	return "Token " + e.Token.String() + " is invalid"
+	return "Session " + e.TokenKey.String() + " is invalid"
 }
 
 type ExpiredTokenError struct {
-	Token fields.SessionToken
+	TokenKey fields.SessionTokenKey
 }
 
 func (e ExpiredTokenError) Error() string {
-	return "Token " + e.Token.String() + " is expired"
+	return "Session " + e.TokenKey.String() + " is expired"
 }
 
 type GetSessionFailedError struct {
-	Token fields.SessionToken
-	Err   error
+	TokenKey fields.SessionTokenKey
+	Err      error
 }
 
 func (e GetSessionFailedError) Error() string {
-	return "GetSession failed for token " + e.Token.String() + ": " + e.Err.Error()
+	return "GetSession failed for session " + e.TokenKey.String() + ": " + e.Err.Error()
+}
+
+type TouchSessionFailedError struct {
+	TokenKey fields.SessionTokenKey
+	Err      error
+}
+
+func (e TouchSessionFailedError) Error() string {
+	return "TouchSession failed for session " + e.TokenKey.String() + ": " + e.Err.Error()
 }
 
 type SetValueFailedError struct {
-	Token fields.SessionToken
-	Key   fields.RequiredString
-	Value any
-	Err   error
+	TokenKey fields.SessionTokenKey
+	Key      fields.RequiredString
+	Value    any
+	Err      error
 }
 
 func (e SetValueFailedError) Error() string {
-	return "SetValue failed for token " + e.Token.String() + " and key " + e.Key.String() + ": " + e.Err.Error()
+	return "SetValue failed for session " + e.TokenKey.String() + " and key " + e.Key.String() + ": " + e.Err.Error()
 }
 
 type GetValueFailedError struct {
-	Token fields.SessionToken
-	Key   fields.RequiredString
-	Err   error
+	TokenKey fields.SessionTokenKey
+	Key      fields.RequiredString
+	Err      error
 }
 
 func (e GetValueFailedError) Error() string {
-	return "GetValue failed for token " + e.Token.String() + " and key " + e.Key.String() + ": " + e.Err.Error()
+	return "GetValue failed for session " + e.TokenKey.String() + " and key " + e.Key.String() + ": " + e.Err.Error()
 }
 
 type KeyNotFoundError struct {
-	Token fields.SessionToken
-	Key   fields.RequiredString
+	TokenKey fields.SessionTokenKey
+	Key      fields.RequiredString
 }
 
 func (e KeyNotFoundError) Error() string {
-	return "Key " + e.Key.String() + " not found in session for token " + e.Token.String()
+	return "Key " + e.Key.String() + " not found in session " + e.TokenKey.String()
 }
 
 type InvalidValueTypeError struct {
-	Token  fields.SessionToken
-	Key    fields.RequiredString
-	Reason string
+	TokenKey fields.SessionTokenKey
+	Key      fields.RequiredString
+	Reason   string
 }
 
 func (e InvalidValueTypeError) Error() string {
-	return "Invalid value type for key " + e.Key.String() + " in session for token " + e.Token.String() + ": " + e.Reason
+	return "Invalid value type for key " + e.Key.String() + " in session " + e.TokenKey.String() + ": " + e.Reason
 }
 
 type SessionAdapterLinkSessionToUserFailedError struct {
-	Token  fields.SessionToken
-	UserID fields.EntityID
-	Err    error
+	TokenKey fields.SessionTokenKey
+	UserID   fields.EntityID
+	Err      error
 }
 
 func (e SessionAdapterLinkSessionToUserFailedError) Error() string {
-	return "LinkSessionToUser failed for token " + e.Token.String() + " and user " + e.UserID.String() + ": " + e.Err.Error()
+	return "LinkSessionToUser failed for session " + e.TokenKey.String() + " and user " + e.UserID.String() + ": " + e.Err.Error()
 }
 
 type SessionAdapterInvalidateSessionFailedError struct {
-	Token fields.SessionToken
-	Err   error
+	TokenKey fields.SessionTokenKey
+	Err      error
 }
 
 func (e SessionAdapterInvalidateSessionFailedError) Error() string {
-	return "InvalidateSession failed for token " + e.Token.String() + ": " + e.Err.Error()
+	return "InvalidateSession failed for session " + e.TokenKey.String() + ": " + e.Err.Error()
 }
 
 type SessionAdapterGetLinkedSessionsFailedError struct {
@@ -9,8 +9,51 @@ import (
 )
 
 type StoragePort interface {
+	// PublishPackage stores a new version of a package and points the dist-tags of the manifest to it.
+	// Returns StorageAdapterVersionAlreadyExistsError if the version was published before, even if it was deleted since.
+	// Returns StorageAdapterPublishPackageError if the version could not be stored.
 	PublishPackage(ctx context.Context, creatorID fields.EntityID, manifest *entities.PackageVersion) error
+	// GetPackage returns the given version of a package. The version may also be a dist-tag like "latest".
+	// Returns StorageAdapterPackageNotFoundError if the package or version does not exist.
+	// Returns StorageAdapterGetPackageError if the package could not be loaded.
 	GetPackage(ctx context.Context, name fields.PackageName, version fields.RequiredString) (*entities.PackageVersion, error)
+	// GetPackument returns the package with all of its non-deleted versions, its dist-tags and maintainers.
+	// Returns StorageAdapterPackageNotFoundError if the package does not exist or has no versions left.
+	// Returns StorageAdapterGetPackageError if the package could not be loaded.
+	GetPackument(ctx context.Context, name fields.PackageName) (*entities.Package, error)
+	// ListPackageNames returns the names of all packages that are not deleted.
+	// Returns StorageAdapterGetPackageError if the packages could not be loaded.
+	ListPackageNames(ctx context.Context) ([]fields.PackageName, error)
+	// UnpublishVersion marks the version of a package as deleted. The latest tag moves to the highest remaining version,
+	// every other tag of the version is removed. The package is unpublished along with its last version.
+	// Returns StorageAdapterPackageNotFoundError if the package or version does not exist.
+	// Returns StorageAdapterUnpublishError if the version could not be deleted.
+	UnpublishVersion(ctx context.Context, name fields.PackageName, version fields.Version) error
+	// UnpublishPackage marks the package and all of its versions as deleted and removes its dist-tags.
+	// Returns StorageAdapterPackageNotFoundError if the package does not exist.
+	// Returns StorageAdapterUnpublishError if the package could not be deleted.
+	UnpublishPackage(ctx context.Context, name fields.PackageName) error
+	// DeprecateVersion sets the deprecation message of a version. An empty message removes the deprecation.
+	// Returns StorageAdapterPackageNotFoundError if the package or version does not exist.
+	// Returns StorageAdapterUpdatePackageError if the version could not be updated.
+	DeprecateVersion(ctx context.Context, name fields.PackageName, version fields.Version, message string) error
+	// GetDistTags returns the dist-tags of a package mapped to the versions they point to.
+	// Returns StorageAdapterPackageNotFoundError if the package does not exist.
+	// Returns StorageAdapterDistTagError if the dist-tags could not be loaded.
+	GetDistTags(ctx context.Context, name fields.PackageName) (map[fields.RequiredString]fields.RequiredString, error)
+	// SetDistTag points the tag to the given version of a package, creating the tag if needed.
+	// Returns StorageAdapterPackageNotFoundError if the package or version does not exist.
+	// Returns StorageAdapterDistTagError if the dist-tag could not be stored.
+	SetDistTag(ctx context.Context, name fields.PackageName, tag fields.RequiredString, version fields.Version) error
+	// RemoveDistTag removes the tag from a package.
+	// Returns StorageAdapterDistTagNotFoundError if the package has no such tag.
+	// Returns StorageAdapterDistTagError if the dist-tag could not be removed.
+	RemoveDistTag(ctx context.Context, name fields.PackageName, tag fields.RequiredString) error
+	// ReplaceDistTags points the tags to the given versions of a package and removes every other tag except latest
+	// at once.
+	// Returns StorageAdapterPackageNotFoundError if the package or one of the versions does not exist.
+	// Returns StorageAdapterDistTagError if the dist-tags could not be stored.
+	ReplaceDistTags(ctx context.Context, name fields.PackageName, tags map[fields.RequiredString]fields.Version) error
 }
 
 // errors
@@ -29,6 +72,9 @@ type StorageAdapterPackageNotFoundError struct {
 }
 
 func (e *StorageAdapterPackageNotFoundError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("storage adapter didn't find package: %s", e.Name)
+	}
 	return fmt.Sprintf("storage adapter didn't find package: %s@%s", e.Name, e.Version)
 }
 
@@ -39,5 +85,62 @@ type StorageAdapterGetPackageError struct {
 }
 
 func (e *StorageAdapterGetPackageError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("storage adapter failed to get package: %s: %s", e.Name, e.Err)
+	}
 	return fmt.Sprintf("storage adapter failed to get package: %s@%s: %s", e.Name, e.Version, e.Err)
 }
+
+type StorageAdapterDistTagNotFoundError struct {
+	Name fields.PackageName
+	Tag  fields.RequiredString
+}
+
+func (e *StorageAdapterDistTagNotFoundError) Error() string {
+	return fmt.Sprintf("storage adapter didn't find dist-tag %s of package %s", e.Tag, e.Name)
+}
+
+type StorageAdapterDistTagError struct {
+	Name fields.PackageName
+	Tag  fields.RequiredString
+	Err  error
+}
+
+func (e *StorageAdapterDistTagError) Error() string {
+	if e.Tag == "" {
+		return fmt.Sprintf("storage adapter failed to handle dist-tags of package %s: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("storage adapter failed to handle dist-tag %s of package %s: %s", e.Tag, e.Name, e.Err)
+}
+
+type StorageAdapterVersionAlreadyExistsError struct {
+	Name    fields.PackageName
+	Version fields.Version
+}
+
+func (e *StorageAdapterVersionAlreadyExistsError) Error() string {
+	return fmt.Sprintf("storage adapter found existing package version: %s@%s", e.Name, e.Version)
+}
+
+type StorageAdapterUnpublishError struct {
+	Name    fields.PackageName
+	Version fields.RequiredString
+	Err     error
+}
+
+func (e *StorageAdapterUnpublishError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("storage adapter failed to unpublish package %s: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("storage adapter failed to unpublish package %s@%s: %s", e.Name, e.Version, e.Err)
+}
+
+type StorageAdapterUpdatePackageError struct {
+	Name    fields.PackageName
+	Version fields.RequiredString
+	Err     error
+}
+
+func (e *StorageAdapterUpdatePackageError) Error() string {
+	return fmt.Sprintf("storage adapter failed to update package %s@%s: %s", e.Name, e.Version, e.Err)
+}
@@ -0,0 +1,76 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+type TokenPort interface {
+	// CreateToken stores a new access token and returns it with its ID and timestamps set.
+	// Returns TokenAdapterCreateTokenError if the token could not be stored.
+	CreateToken(ctx context.Context, token *entities.AccessToken) (*entities.AccessToken, error)
+	// GetToken returns the token with the given key unless it was revoked.
+	// Returns TokenAdapterTokenNotFoundError if there is no such token.
+	// Returns TokenAdapterGetTokenError if the token could not be loaded.
+	GetToken(ctx context.Context, key fields.AccessTokenKey) (*entities.AccessToken, error)
+	// ListTokens returns all tokens of the user that are not revoked, newest first.
+	// Returns TokenAdapterGetTokenError if the tokens could not be loaded.
+	ListTokens(ctx context.Context, userID fields.EntityID) ([]*entities.AccessToken, error)
+	// RevokeToken marks the token of the user with the given key as deleted.
+	// Returns TokenAdapterTokenNotFoundError if the user has no such token.
+	// Returns TokenAdapterRevokeTokenError if the token could not be revoked.
+	RevokeToken(ctx context.Context, userID fields.EntityID, key fields.AccessTokenKey) error
+	// TouchToken records that the token was used at the given time.
+	// Returns TokenAdapterTokenNotFoundError if there is no such token.
+	// Returns TokenAdapterUpdateTokenError if the token could not be updated.
+	TouchToken(ctx context.Context, key fields.AccessTokenKey, usedAt time.Time) error
+}
+
+// errors
+
+type TokenAdapterTokenNotFoundError struct {
+	Key fields.AccessTokenKey
+}
+
+func (e *TokenAdapterTokenNotFoundError) Error() string {
+	return fmt.Sprintf("access token %s not found", e.Key)
+}
+
+type TokenAdapterCreateTokenError struct {
+	UserID fields.EntityID
+	Err    error
+}
+
+func (e *TokenAdapterCreateTokenError) Error() string {
+	return fmt.Sprintf("token adapter failed to create access token for user %s: %s", e.UserID, e.Err)
+}
+
+type TokenAdapterGetTokenError struct {
+	Err error
+}
+
+func (e *TokenAdapterGetTokenError) Error() string {
+	return fmt.Sprintf("token adapter failed to get access tokens: %s", e.Err)
+}
+
+type TokenAdapterRevokeTokenError struct {
+	Key fields.AccessTokenKey
+	Err error
+}
+
+func (e *TokenAdapterRevokeTokenError) Error() string {
+	return fmt.Sprintf("token adapter failed to revoke access token %s: %s", e.Key, e.Err)
+}
+
+type TokenAdapterUpdateTokenError struct {
+	Key fields.AccessTokenKey
+	Err error
+}
+
+func (e *TokenAdapterUpdateTokenError) Error() string {
+	return fmt.Sprintf("token adapter failed to update access token %s: %s", e.Key, e.Err)
+}
@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+type TwoFactorPort interface {
+	// GetTwoFactor returns the two-factor settings of the user.
+	// Returns TwoFactorAdapterNotFoundError if the user has not enrolled.
+	// Returns TwoFactorAdapterGetError if the settings could not be loaded.
+	GetTwoFactor(ctx context.Context, userID fields.EntityID) (*entities.TwoFactor, error)
+	// SaveTwoFactor creates or replaces the two-factor settings of the user.
+	// Returns TwoFactorAdapterSaveError if the settings could not be stored.
+	SaveTwoFactor(ctx context.Context, twoFactor *entities.TwoFactor) error
+	// DeleteTwoFactor removes the two-factor settings of the user.
+	// Returns TwoFactorAdapterNotFoundError if the user has not enrolled.
+	// Returns TwoFactorAdapterSaveError if the settings could not be removed.
+	DeleteTwoFactor(ctx context.Context, userID fields.EntityID) error
+}
+
+// errors
+
+type TwoFactorAdapterNotFoundError struct {
+	UserID fields.EntityID
+}
+
+func (e *TwoFactorAdapterNotFoundError) Error() string {
+	return fmt.Sprintf("user %s has no two-factor authentication", e.UserID)
+}
+
+type TwoFactorAdapterGetError struct {
+	UserID fields.EntityID
+	Err    error
+}
+
+func (e *TwoFactorAdapterGetError) Error() string {
+	return fmt.Sprintf("two-factor adapter failed to get settings of user %s: %s", e.UserID, e.Err)
+}
+
+type TwoFactorAdapterSaveError struct {
+	UserID fields.EntityID
+	Err    error
+}
+
+func (e *TwoFactorAdapterSaveError) Error() string {
+	return fmt.Sprintf("two-factor adapter failed to save settings of user %s: %s", e.UserID, e.Err)
+}
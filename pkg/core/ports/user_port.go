@@ -14,6 +14,8 @@ type CreateUserInput struct {
 	Email    fields.Email
 	Password fields.Password
 	RoleID   fields.EntityID
+	// AuthSource is the directory managing the user, empty for local users.
+	AuthSource string
 }
 
 // UpdateUserInput contains the fields that can be updated.
@@ -37,6 +39,10 @@ type UserPort interface {
 	// Returns UserAdapterGetUserByIDFailedError if failed to get user.
 	// Returns UserAdapterUserNotFoundError if user with the given ID does not exist.
 	GetUserByID(ctx context.Context, userID fields.EntityID) (*entities.User, error)
+	// GetUserByUsername returns the user with the given username.
+	// Returns UserAdapterGetUserByUsernameFailedError if failed to get user.
+	// Returns UserAdapterUsernameNotFoundError if user with the given username does not exist.
+	GetUserByUsername(ctx context.Context, username fields.Username) (*entities.User, error)
 	// GetAllUsers returns all users.
 	// Returns UserAdapterGetAllUsersFailedError if failed to get all users.
 	GetAllUsers(ctx context.Context) ([]*entities.User, error)
@@ -97,6 +103,23 @@ func (e UserAdapterUserNotFoundError) Error() string {
 	return fmt.Sprintf("user with id %v not found", e.ID)
 }
 
+type UserAdapterGetUserByUsernameFailedError struct {
+	Username fields.Username
+	Err      error
+}
+
+func (e UserAdapterGetUserByUsernameFailedError) Error() string {
+	return fmt.Sprintf("failed to get user by username %v: %v", e.Username, e.Err)
+}
+
+type UserAdapterUsernameNotFoundError struct {
+	Username fields.Username
+}
+
+func (e UserAdapterUsernameNotFoundError) Error() string {
+	return fmt.Sprintf("user with username %v not found", e.Username)
+}
+
 type UserAdapterUpdateUserFailedError struct {
 	ID  fields.EntityID
 	Err error
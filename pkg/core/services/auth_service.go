@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"github.com/mrparano1d/noxite/pkg/core/entities"
 	"github.com/mrparano1d/noxite/pkg/core/fields"
@@ -11,23 +14,83 @@ import (
 
 type AuthService struct {
 	adapter ports.AuthPort
+	hasher  ports.PasswordHasherPort
+
+	twoFactorAdapter ports.TwoFactorPort
+	userAdapter      ports.UserPort
+
+	// externalAuth are asked in order before the local credentials.
+	externalAuth []ports.ExternalAuthPort
 
 	sessionService *SessionService
+
+	// throttleAdapter counts failed logins, lockouts are recorded by auditAdapter.
+	throttleAdapter ports.LoginThrottlePort
+	auditAdapter    ports.AuditPort
+	throttleConfig  LoginThrottleConfig
+
+	// dummyHash is verified for unknown users, so that logins of unknown and known users take the same time.
+	dummyHash     fields.PasswordHash
+	dummyHashOnce sync.Once
 }
 
 func NewAuthService(
 	adapter ports.AuthPort,
+	hasher ports.PasswordHasherPort,
+	twoFactorAdapter ports.TwoFactorPort,
+	userAdapter ports.UserPort,
+	externalAuth []ports.ExternalAuthPort,
 	sessionService *SessionService,
+	throttleAdapter ports.LoginThrottlePort,
+	auditAdapter ports.AuditPort,
+	throttleConfig LoginThrottleConfig,
 ) *AuthService {
 	return &AuthService{
-		adapter:        adapter,
-		sessionService: sessionService,
+		adapter:          adapter,
+		hasher:           hasher,
+		twoFactorAdapter: twoFactorAdapter,
+		userAdapter:      userAdapter,
+		externalAuth:     externalAuth,
+		sessionService:   sessionService,
+		throttleAdapter:  throttleAdapter,
+		auditAdapter:     auditAdapter,
+		throttleConfig:   throttleConfig,
 	}
 }
 
 // usecases
 
+// Login creates a session for the user. Failed logins delay and eventually lock out further logins of the account
+// and the IP address of the client, see LoginThrottleConfig. Blocked logins return AuthServiceLoginThrottledError.
 func (s *AuthService) Login(ctx context.Context, usernameOrEmail string, password string) (*entities.Session, error) {
+	account, ip, err := s.loginThrottleKeys(ctx, usernameOrEmail)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginThrottle(ctx, account, ip); err != nil {
+		return nil, err
+	}
+
+	sess, err := s.login(ctx, usernameOrEmail, password)
+	if err != nil {
+		if isLoginFailure(err) {
+			if err := s.registerLoginFailure(ctx, account, ip); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	// failures of the IP address are kept, so logins to an own account do not hide guesses for others
+	if err := s.throttleAdapter.Reset(ctx, account); err != nil {
+		return nil, handleErrors(err)
+	}
+
+	return sess, nil
+}
+
+// login authenticates the user with the external directories or the local credentials and creates a session.
+func (s *AuthService) login(ctx context.Context, usernameOrEmail string, password string) (*entities.Session, error) {
 
 	var err error
 	var userEmail *fields.Email
@@ -44,33 +107,282 @@ func (s *AuthService) Login(ctx context.Context, usernameOrEmail string, passwor
 		userEmail = &possibleEmail
 	}
 
-	pw, err := fields.PasswordFromString(password)
+	var user *entities.User
+	var unavailable *AuthServiceDirectoryUnavailableError
+
+	if userName != nil {
+		user, err = s.authenticateExternal(ctx, *userName, password)
+		if err != nil {
+			var ok bool
+			if unavailable, ok = err.(*AuthServiceDirectoryUnavailableError); !ok {
+				return nil, err
+			}
+		}
+	}
+
+	// users unknown to the external directories log in with their local credentials
+	if user == nil {
+		pw, err := fields.PasswordFromString(password)
+		if err != nil {
+			return nil, unavailableOr(unavailable, nil, handleErrors(err))
+		}
+
+		var hash fields.PasswordHash
+
+		if userName != nil {
+			user, hash, err = s.adapter.GetCredentials(ctx, *userName)
+		}
+
+		if userEmail != nil {
+			user, hash, err = s.adapter.GetCredentialsByEmail(ctx, *userEmail)
+		}
+
+		if err := s.verifyCredentials(ctx, user, hash, err, pw); err != nil {
+			return nil, unavailableOr(unavailable, user, err)
+		}
+	}
+
+	if err := s.requireTwoFactor(ctx, user, twoFactorLogin); err != nil {
+		return nil, err
+	}
+
+	sess, err := s.sessionService.CreateSessionForUser(ctx, user)
 	if err != nil {
 		return nil, handleErrors(err)
 	}
 
-	var user *entities.User
+	return sess, nil
+}
 
-	if userName != nil {
-		user, err = s.adapter.Login(ctx, *userName, pw)
+// VerifyPassword checks the password of an already authenticated user, e.g. before an access token is created.
+// Users with two-factor authentication must send a one-time password as well. Failures count against the user
+// like failed logins, so a stolen session can't guess the password or one-time password.
+func (s *AuthService) VerifyPassword(ctx context.Context, user *entities.User, password string) error {
+	return s.throttleVerification(ctx, user, func() error {
+		return s.verifyPassword(ctx, user, password)
+	})
+}
+
+func (s *AuthService) verifyPassword(ctx context.Context, user *entities.User, password string) error {
+	external, err := s.authenticateExternal(ctx, user.Username, password)
+	unavailable, isUnavailable := err.(*AuthServiceDirectoryUnavailableError)
+	if err != nil && !isUnavailable {
+		return err
+	}
+	if external != nil {
+		if external.ID != user.ID {
+			return &AuthServiceLoginFailedError{
+				Username: user.Username,
+				Err:      fmt.Errorf("password belongs to another user"),
+			}
+		}
+		return s.requireTwoFactor(ctx, user, twoFactorLogin)
+	}
+
+	pw, err := fields.PasswordFromString(password)
+	if err != nil {
+		return unavailableOr(unavailable, nil, &AuthServiceLoginFailedError{
+			Username: user.Username,
+			Err:      err,
+		})
+	}
+
+	stored, hash, err := s.adapter.GetCredentials(ctx, user.Username)
+	if err := s.verifyCredentials(ctx, stored, hash, err, pw); err != nil {
+		return unavailableOr(unavailable, stored, err)
+	}
+
+	if stored.ID != user.ID {
+		return &AuthServiceLoginFailedError{
+			Username: user.Username,
+			Err:      fmt.Errorf("password belongs to another user"),
+		}
+	}
+
+	return s.requireTwoFactor(ctx, user, twoFactorLogin)
+}
+
+// helpers
+
+// authenticateExternal asks the external directories for the user and returns the local user of the first directory
+// knowing the user, which is created or updated from the directory. It returns nil if no directory knows the user.
+// Directories knowing a user of the same name they don't manage are skipped, so the local credentials decide.
+// Directories that can't be queried are skipped as well, AuthServiceDirectoryUnavailableError is returned with a nil
+// user then, so that local users can still log in during an outage.
+func (s *AuthService) authenticateExternal(ctx context.Context, username fields.Username, password string) (*entities.User, error) {
+	var unavailable *AuthServiceDirectoryUnavailableError
+
+	for _, external := range s.externalAuth {
+		identity, err := external.Authenticate(ctx, username, password)
 		if err != nil {
+			switch e := err.(type) {
+			case *ports.ExternalAuthUserNotFoundError:
+				continue
+			case *ports.ExternalAuthError:
+				unavailable = &AuthServiceDirectoryUnavailableError{
+					Username: username,
+					Err:      e,
+				}
+				continue
+			}
 			return nil, handleErrors(err)
 		}
+
+		user, err := s.provisionUser(ctx, identity)
+		if err != nil {
+			if _, ok := err.(*AuthServiceUserNotLinkedError); ok {
+				continue
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if unavailable != nil {
+		return nil, unavailable
+	}
+	return nil, nil
+}
+
+// unavailableOr returns the unavailable directory instead of the failed local login of a user who isn't local, since
+// the directory might have known the user. These aren't failed logins, which are throttled.
+func unavailableOr(unavailable *AuthServiceDirectoryUnavailableError, user *entities.User, err error) error {
+	if unavailable != nil && (user == nil || user.AuthSource != "") {
+		return unavailable
 	}
+	return err
+}
+
+// provisionUser creates the local user of an external identity or updates its email and role.
+// New users get a random password, so they can only log in through the directory.
+// Existing users are only logged in by the directory managing them, otherwise an account of a directory could take over
+// a local user of the same name like an administrator. Returns AuthServiceUserNotLinkedError for them.
+func (s *AuthService) provisionUser(ctx context.Context, identity *entities.ExternalIdentity) (*entities.User, error) {
+	user, err := s.userAdapter.GetUserByUsername(ctx, identity.Username)
+	if err != nil {
+		if _, ok := err.(*ports.UserAdapterUsernameNotFoundError); !ok {
+			return nil, handleErrors(err)
+		}
+
+		password, err := randomPassword()
+		if err != nil {
+			return nil, handleErrors(err)
+		}
 
-	if userEmail != nil {
-		user, err = s.adapter.LoginByEmail(ctx, *userEmail, pw)
+		userID, err := s.userAdapter.CreateUser(ctx, ports.CreateUserInput{
+			Username:   identity.Username,
+			Email:      identity.Email,
+			Password:   password,
+			RoleID:     identity.RoleID,
+			AuthSource: identity.Source,
+		})
 		if err != nil {
 			return nil, handleErrors(err)
 		}
+
+		user, err = s.userAdapter.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, handleErrors(err)
+		}
+		return user, nil
 	}
 
-	sess, err := s.sessionService.CreateSessionForUser(ctx, user)
+	// directories which only provision users, like htpasswd files getting the entries of local signups, log in local users as well
+	if user.AuthSource != identity.Source && !(identity.ProvisionOnly && user.AuthSource == "") {
+		return nil, &AuthServiceUserNotLinkedError{
+			Username: user.Username,
+			Source:   identity.Source,
+		}
+	}
+
+	if identity.ProvisionOnly {
+		return user, nil
+	}
+
+	var update ports.UpdateUserInput
+	if user.Email != identity.Email {
+		update.Email = &identity.Email
+	}
+	if user.Role == nil || user.Role.ID != identity.RoleID {
+		update.RoleID = &identity.RoleID
+	}
+	if update.Email == nil && update.RoleID == nil {
+		return user, nil
+	}
+
+	if err := s.userAdapter.UpdateUser(ctx, user.ID, update); err != nil {
+		return nil, handleErrors(err)
+	}
+
+	// the directory changed the role, sessions granted by the previous role end
+	if update.RoleID != nil {
+		if err := s.sessionService.InvalidateUserSessions(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		s.sessionService.forgetUser(user.ID)
+	}
+
+	user, err = s.userAdapter.GetUserByID(ctx, user.ID)
 	if err != nil {
 		return nil, handleErrors(err)
 	}
+	return user, nil
+}
 
-	return sess, nil
+// randomPassword returns a password nobody knows for users managed by an external directory.
+func randomPassword() (fields.Password, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return fields.Password(hex.EncodeToString(b)), nil
+}
+
+// verifyCredentials verifies the password against the credentials returned by the adapter with lookupErr.
+// Unknown users are verified against a dummy hash, so they can't be told apart from wrong passwords by timing.
+// Outdated hashes, including legacy plaintext passwords, are replaced with a hash of the current algorithm.
+func (s *AuthService) verifyCredentials(ctx context.Context, user *entities.User, hash fields.PasswordHash, lookupErr error, password fields.Password) error {
+	if lookupErr != nil {
+		if _, ok := lookupErr.(*ports.AuthAdapterUserNotFoundError); ok {
+			s.hasher.Verify(password, s.getDummyHash())
+		}
+		return handleErrors(lookupErr)
+	}
+
+	ok, err := s.hasher.Verify(password, hash)
+	if err != nil {
+		return &AuthServiceLoginFailedError{
+			Username: user.Username,
+			Err:      err,
+		}
+	}
+	if !ok {
+		return &AuthServiceLoginFailedError{
+			Username: user.Username,
+			Err:      &ports.AuthAdapterInvalidCredentialsError{Username: user.Username},
+		}
+	}
+
+	if s.hasher.NeedsRehash(hash) {
+		rehashed, err := s.hasher.Hash(password)
+		if err != nil {
+			return handleErrors(err)
+		}
+		if err := s.adapter.UpdatePasswordHash(ctx, user.ID, rehashed); err != nil {
+			return handleErrors(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *AuthService) getDummyHash() fields.PasswordHash {
+	s.dummyHashOnce.Do(func() {
+		// an error leaves the hash empty, which is verified as a plaintext password and returns early
+		s.dummyHash, _ = s.hasher.Hash(fields.Password("dummy password of unknown users"))
+	})
+	return s.dummyHash
 }
 
 // service errors
@@ -92,6 +404,16 @@ func handleErrors(err error) error {
 			Username: e.Username,
 			Err:      e,
 		}
+	case *ports.ExternalAuthInvalidCredentialsError:
+		return &AuthServiceLoginFailedError{
+			Username: e.Username,
+			Err:      e,
+		}
+	case *ports.ExternalAuthNotAllowedError:
+		return &AuthServiceLoginFailedError{
+			Username: e.Username,
+			Err:      e,
+		}
 	default:
 		return &AuthServiceUnknownError{
 			Err: e,
@@ -108,6 +430,26 @@ func (e *AuthServiceLoginFailedError) Error() string {
 	return fmt.Sprintf("login failed for user %s: %s", e.Username, e.Err)
 }
 
+// AuthServiceUserNotLinkedError is returned if a directory authenticated a user of the same name as a user it doesn't manage.
+type AuthServiceUserNotLinkedError struct {
+	Username fields.Username
+	Source   string
+}
+
+func (e *AuthServiceUserNotLinkedError) Error() string {
+	return fmt.Sprintf("user %s is not managed by %s, an administrator has to link the user to it first", e.Username, e.Source)
+}
+
+// AuthServiceDirectoryUnavailableError is returned if an external directory couldn't be queried and the user isn't local.
+type AuthServiceDirectoryUnavailableError struct {
+	Username fields.Username
+	Err      error
+}
+
+func (e *AuthServiceDirectoryUnavailableError) Error() string {
+	return fmt.Sprintf("user %s can't be authenticated while the directory is unavailable: %s", e.Username, e.Err)
+}
+
 type AuthServiceRegistrationFailedError struct {
 	Username fields.Username
 	Email    fields.Email
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// LoginThrottleConfig defines how failed logins slow down and lock out further logins.
+type LoginThrottleConfig struct {
+	// FreeFailures are the failed logins of an account before each further failure delays the next login,
+	// starting at BaseDelay and doubling up to MaxDelay.
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// LockoutFailures are the failed logins of an account which lock it for LockoutDuration.
+	LockoutFailures int
+	// IPLockoutFailures are the failed logins from an IP address which lock it for LockoutDuration. It is higher
+	// than LockoutFailures, since many users may share an address.
+	IPLockoutFailures int
+	LockoutDuration   time.Duration
+	// Window is the time without failed logins after which the failures are forgotten.
+	Window time.Duration
+}
+
+var DefaultLoginThrottleConfig = LoginThrottleConfig{
+	FreeFailures:      3,
+	BaseDelay:         time.Second,
+	MaxDelay:          time.Minute,
+	LockoutFailures:   10,
+	IPLockoutFailures: 100,
+	LockoutDuration:   15 * time.Minute,
+	Window:            time.Hour,
+}
+
+// UnlockUser lets the user log in again after failed logins locked the account.
+func (s *AuthService) UnlockUser(ctx context.Context, user *entities.User, userID string) error {
+
+	if user == nil || user.Role.Permissions.UpdateUser == false {
+		return &coreerrors.NotAllowedToUpdateUserError{}
+	}
+
+	id, err := fields.EntityIDFromString(userID)
+	if err != nil {
+		return handleUserServiceRequestValidationError("id", err.Error())
+	}
+
+	locked, err := s.userAdapter.GetUserByID(ctx, id)
+	if err != nil {
+		return handleUserServiceErrors(err)
+	}
+
+	// logins and verifications of users are throttled by their ID, failures from before the account existed, e.g.
+	// before the first login with an external directory, by the name used
+	for _, key := range []string{accountThrottleKey(locked.Username.String()), accountThrottleKey(locked.Email.String()), userThrottleKey(locked.ID)} {
+		if err := s.throttleAdapter.Reset(ctx, key); err != nil {
+			return handleErrors(err)
+		}
+	}
+
+	return s.audit(ctx, ports.RecordAuditEntryInput{
+		Action:  ports.AuditActionLoginUnlock,
+		ActorID: &user.ID,
+		Subject: locked.Username.String(),
+		IP:      sessionClientFromContext(ctx).IP,
+	})
+}
+
+// helpers
+
+// loginThrottleKeys returns the keys of the account and of the IP address of the client, which is empty if the
+// address is unknown. Users are keyed by their ID, so failures with their username and email count together with
+// those of VerifyPassword. Unknown accounts are keyed by the name used.
+func (s *AuthService) loginThrottleKeys(ctx context.Context, usernameOrEmail string) (account string, ip string, err error) {
+	var user *entities.User
+	if email, emailErr := fields.EmailFromString(usernameOrEmail); emailErr == nil {
+		user, _, err = s.adapter.GetCredentialsByEmail(ctx, email)
+	} else if username, usernameErr := fields.UsernameFromString(usernameOrEmail); usernameErr == nil {
+		user, _, err = s.adapter.GetCredentials(ctx, username)
+	}
+	if err != nil {
+		if _, ok := err.(*ports.AuthAdapterUserNotFoundError); !ok {
+			return "", "", handleErrors(err)
+		}
+		user = nil
+	}
+
+	if user == nil {
+		return accountThrottleKey(usernameOrEmail), ipThrottleKey(ctx), nil
+	}
+	return userThrottleKey(user.ID), ipThrottleKey(ctx), nil
+}
+
+// ipThrottleKey returns the key of the IP address of the client, which is empty if the address is unknown.
+func ipThrottleKey(ctx context.Context) string {
+	if clientIP := sessionClientFromContext(ctx).IP; clientIP != "" {
+		return "ip:" + clientIP
+	}
+	return ""
+}
+
+func accountThrottleKey(usernameOrEmail string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(usernameOrEmail))
+}
+
+// userThrottleKey is the key of a known user, whose logins and verifications of passwords or one-time passwords
+// are throttled together.
+func userThrottleKey(userID fields.EntityID) string {
+	return "user:" + userID.String()
+}
+
+// throttleVerification runs verify, which checks a password or one-time password of an already authenticated user,
+// throttled like a login: failures delay and lock out further verifications of the user and the IP address.
+// Returns AuthServiceLoginThrottledError while they are blocked.
+func (s *AuthService) throttleVerification(ctx context.Context, user *entities.User, verify func() error) error {
+	account, ip := userThrottleKey(user.ID), ipThrottleKey(ctx)
+	if err := s.checkLoginThrottle(ctx, account, ip); err != nil {
+		return err
+	}
+
+	if err := verify(); err != nil {
+		if isLoginFailure(err) {
+			if err := s.registerLoginFailure(ctx, account, ip); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkLoginThrottle returns AuthServiceLoginThrottledError if logins of any of the keys are blocked.
+func (s *AuthService) checkLoginThrottle(ctx context.Context, keys ...string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		until, err := s.throttleAdapter.BlockedUntil(ctx, key)
+		if err != nil {
+			return handleErrors(err)
+		}
+		if wait := until.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &AuthServiceLoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// registerLoginFailure counts the failed login and delays or locks further logins of the account and the IP address.
+func (s *AuthService) registerLoginFailure(ctx context.Context, account string, ip string) error {
+	failures, err := s.throttleAdapter.RegisterFailure(ctx, account, s.throttleConfig.Window)
+	if err != nil {
+		return handleErrors(err)
+	}
+
+	if failures >= s.throttleConfig.LockoutFailures {
+		if err := s.lockOut(ctx, account, failures); err != nil {
+			return err
+		}
+	} else if failures > s.throttleConfig.FreeFailures {
+		if err := s.throttleAdapter.Block(ctx, account, time.Now().Add(s.loginDelay(failures))); err != nil {
+			return handleErrors(err)
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	failures, err = s.throttleAdapter.RegisterFailure(ctx, ip, s.throttleConfig.Window)
+	if err != nil {
+		return handleErrors(err)
+	}
+	if failures >= s.throttleConfig.IPLockoutFailures {
+		return s.lockOut(ctx, ip, failures)
+	}
+
+	return nil
+}
+
+// loginDelay returns the delay after the given number of failed logins.
+func (s *AuthService) loginDelay(failures int) time.Duration {
+	delay := s.throttleConfig.BaseDelay
+	for i := s.throttleConfig.FreeFailures + 1; i < failures && delay < s.throttleConfig.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.throttleConfig.MaxDelay {
+		return s.throttleConfig.MaxDelay
+	}
+	return delay
+}
+
+// lockOut blocks logins of the key for the lockout duration. The failures start anew, so the key is locked out
+// again only after as many further failures.
+func (s *AuthService) lockOut(ctx context.Context, key string, failures int) error {
+	if err := s.throttleAdapter.Reset(ctx, key); err != nil {
+		return handleErrors(err)
+	}
+	if err := s.throttleAdapter.Block(ctx, key, time.Now().Add(s.throttleConfig.LockoutDuration)); err != nil {
+		return handleErrors(err)
+	}
+
+	return s.audit(ctx, ports.RecordAuditEntryInput{
+		Action:  ports.AuditActionLoginLockout,
+		Subject: key,
+		IP:      sessionClientFromContext(ctx).IP,
+		Details: fmt.Sprintf("locked for %s after %d failed logins", s.throttleConfig.LockoutDuration, failures),
+	})
+}
+
+func (s *AuthService) audit(ctx context.Context, input ports.RecordAuditEntryInput) error {
+	if err := s.auditAdapter.RecordAuditEntry(ctx, input); err != nil {
+		return handleErrors(err)
+	}
+	return nil
+}
+
+// isLoginFailure reports whether the error of a login is caused by wrong credentials, which are throttled.
+func isLoginFailure(err error) bool {
+	switch err.(type) {
+	case *AuthServiceLoginFailedError, *TwoFactorInvalidCodeError:
+		return true
+	default:
+		return false
+	}
+}
+
+// errors
+
+// AuthServiceLoginThrottledError is returned for logins while failed logins block the account or the IP address.
+type AuthServiceLoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AuthServiceLoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed logins, retry in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds returns RetryAfter in whole seconds, rounded up, as sent in Retry-After headers.
+func (e *AuthServiceLoginThrottledError) RetryAfterSeconds() int {
+	seconds := int((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -14,15 +15,28 @@ import (
 type PackageService struct {
 	packageAdapter ports.PackagePort
 	storageAdapter ports.StoragePort
+	blobAdapter    ports.BlobPort
+
+	// searchService updates the search index after writes.
+	searchService *SearchService
+
+	// authService checks the one-time passwords of writes.
+	authService *AuthService
 }
 
 func NewPackageService(
 	packageAdapter ports.PackagePort,
 	storageAdapter ports.StoragePort,
+	blobAdapter ports.BlobPort,
+	searchService *SearchService,
+	authService *AuthService,
 ) *PackageService {
 	return &PackageService{
 		packageAdapter: packageAdapter,
 		storageAdapter: storageAdapter,
+		blobAdapter:    blobAdapter,
+		searchService:  searchService,
+		authService:    authService,
 	}
 }
 
@@ -47,34 +61,211 @@ func (s *PackageService) PublishPackage(ctx context.Context, user *entities.User
 		return &coreerrors.NotAllowedToPublishPackageError{}
 	}
 
+	if err := checkPackageAccess(user, manifest.Name); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	if err := verifyTarball(manifest); err != nil {
+		return err
+	}
+
+	if err := s.crossCheckTarball(ctx, manifest); err != nil {
+		return err
+	}
+
+	if len(manifest.DistTags) == 0 {
+		manifest.DistTags = []fields.RequiredString{latestDistTag}
+	}
+
+	for _, tag := range manifest.DistTags {
+		if err := validateDistTag(tag); err != nil {
+			return err
+		}
+	}
+
+	tags, err := s.keepLatestDistTag(ctx, manifest)
+	if err != nil {
+		return err
+	}
+	manifest.DistTags = tags
+
+	// blobs are content addressed and may be shared, so they are kept even if storing the version fails
+	digest, err := s.blobAdapter.Put(ctx, bytes.NewReader(manifest.Tarball))
+	if err != nil {
+		return handlePackageErrors(err)
+	}
+	manifest.TarballDigest = digest
+
 	if err := s.storageAdapter.PublishPackage(ctx, user.ID, manifest); err != nil {
 		return handlePackageErrors(err)
 	}
+
+	s.searchService.reindex(ctx, manifest.Name)
 	return nil
 }
 
+// GetPackage returns the package version with exactly the given version, dist-tags and ranges are not resolved.
 func (s *PackageService) GetPackage(ctx context.Context, user *entities.User, name string, version string) (*entities.PackageVersion, error) {
+	packageName, err := s.checkGetPackage(user, name)
+	if err != nil {
+		return nil, err
+	}
+
+	packageVersion, err := fields.VersionFromString(version)
+	if err != nil {
+		return nil, &InvalidGetPackageFieldError{
+			Field:  "version",
+			Reason: err.Error(),
+		}
+	}
+
+	rev, err := fields.RequiredStringFromString(packageVersion.String())
+	if err != nil {
+		return nil, &InvalidGetPackageFieldError{
+			Field:  "version",
+			Reason: err.Error(),
+		}
+	}
+
+	data, err := s.storageAdapter.GetPackage(ctx, packageName, rev)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	// the storage resolves dist-tags first, which must never shadow a version
+	if !data.Version.Equal(packageVersion) {
+		return nil, &PackageServicePackageNotFoundError{
+			Name:    packageName.String(),
+			Version: packageVersion.String(),
+		}
+	}
+
+	return data, nil
+}
+
+// ResolvePackage returns the package version a dist-tag, an exact version or a range like "^1.2.0" resolves to.
+// Ranges resolve to the highest satisfying version.
+func (s *PackageService) ResolvePackage(ctx context.Context, user *entities.User, name string, spec string) (*entities.PackageVersion, error) {
+	packageName, err := s.checkGetPackage(user, name)
+	if err != nil {
+		return nil, err
+	}
+
+	packageSpec, err := fields.RequiredStringFromString(spec)
+	if err != nil {
+		return nil, &InvalidGetPackageFieldError{
+			Field:  "version",
+			Reason: err.Error(),
+		}
+	}
+
+	data, err := s.storageAdapter.GetPackage(ctx, packageName, packageSpec)
+	if err == nil {
+		return data, nil
+	}
+
+	// neither a dist-tag nor an exact version, resolve it as a range
+	if _, ok := err.(*ports.StorageAdapterPackageNotFoundError); ok {
+		if versionRange, rangeErr := fields.VersionRangeFromString(spec); rangeErr == nil {
+			return s.getMaxSatisfyingVersion(ctx, packageName, versionRange)
+		}
+	}
+
+	return nil, handlePackageErrors(err)
+}
+
+// checkGetPackage checks that the user may get the package and returns its name.
+func (s *PackageService) checkGetPackage(user *entities.User, name string) (fields.PackageName, error) {
 	if user.Role.Permissions.GetPackage == false {
-		return nil, &coreerrors.NotAllowedToGetPackageError{}
+		return "", &coreerrors.NotAllowedToGetPackageError{}
 	}
 
 	packageName, err := fields.PackageNameFromString(name)
 	if err != nil {
-		return nil, &InvalidGetPackageFieldError{
+		return "", &InvalidGetPackageFieldError{
 			Field:  "name",
 			Reason: err.Error(),
 		}
 	}
 
-	packageVersion, err := fields.RequiredStringFromString(version)
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return "", err
+	}
+
+	return packageName, nil
+}
+
+// GetTarball returns a reader for the tarball of the package version and its size in bytes.
+// The reader must be closed by the caller.
+func (s *PackageService) GetTarball(ctx context.Context, user *entities.User, pkg *entities.PackageVersion) (io.ReadCloser, int64, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, 0, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	if err := checkPackageAccess(user, pkg.Name); err != nil {
+		return nil, 0, err
+	}
+
+	if pkg.TarballDigest == "" {
+		return nil, 0, &PackageServiceTarballNotFoundError{
+			Name:    pkg.Name.String(),
+			Version: pkg.Version.String(),
+		}
+	}
+
+	tarball, size, err := s.blobAdapter.Get(ctx, pkg.TarballDigest)
+	if err != nil {
+		if _, ok := err.(*ports.BlobAdapterNotFoundError); ok {
+			return nil, 0, &PackageServiceTarballNotFoundError{
+				Name:    pkg.Name.String(),
+				Version: pkg.Version.String(),
+			}
+		}
+		return nil, 0, handlePackageErrors(err)
+	}
+
+	return tarball, size, nil
+}
+
+func (s *PackageService) GetPackument(ctx context.Context, user *entities.User, name string) (*entities.Package, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
 	if err != nil {
 		return nil, &InvalidGetPackageFieldError{
-			Field:  "version",
+			Field:  "name",
 			Reason: err.Error(),
 		}
 	}
 
-	data, err := s.storageAdapter.GetPackage(ctx, packageName, packageVersion)
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return nil, err
+	}
+
+	pkg, err := s.storageAdapter.GetPackument(ctx, packageName)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	return pkg, nil
+}
+
+func (s *PackageService) SerializeManifest(ctx context.Context, user *entities.User, pkg *entities.Package) ([]byte, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	if err := checkPackageAccess(user, pkg.Name); err != nil {
+		return nil, err
+	}
+
+	data, err := s.packageAdapter.SerializeManifest(ctx, pkg)
 	if err != nil {
 		return nil, handlePackageErrors(err)
 	}
@@ -82,12 +273,16 @@ func (s *PackageService) GetPackage(ctx context.Context, user *entities.User, na
 	return data, nil
 }
 
-func (s *PackageService) SerializeManifest(ctx context.Context, user *entities.User, manifest *entities.PackageVersion) ([]byte, error) {
+func (s *PackageService) SerializeAbbreviatedManifest(ctx context.Context, user *entities.User, pkg *entities.Package) ([]byte, error) {
 	if user.Role.Permissions.GetPackage == false {
 		return nil, &coreerrors.NotAllowedToGetPackageError{}
 	}
 
-	data, err := s.packageAdapter.SerializeManifest(ctx, manifest)
+	if err := checkPackageAccess(user, pkg.Name); err != nil {
+		return nil, err
+	}
+
+	data, err := s.packageAdapter.SerializeAbbreviatedManifest(ctx, pkg)
 	if err != nil {
 		return nil, handlePackageErrors(err)
 	}
@@ -95,6 +290,256 @@ func (s *PackageService) SerializeManifest(ctx context.Context, user *entities.U
 	return data, nil
 }
 
+func (s *PackageService) GetDistTags(ctx context.Context, user *entities.User, name string) (map[fields.RequiredString]fields.RequiredString, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return nil, &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.storageAdapter.GetDistTags(ctx, packageName)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	return tags, nil
+}
+
+func (s *PackageService) SetDistTag(ctx context.Context, user *entities.User, name string, tag string, version string) error {
+	if user.Role.Permissions.UpdatePackage == false {
+		return &coreerrors.NotAllowedToUpdatePackageError{}
+	}
+
+	packageName, distTag, err := distTagRequestToFields(name, tag)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	packageVersion, err := fields.VersionFromString(version)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "version",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := s.storageAdapter.SetDistTag(ctx, packageName, distTag, packageVersion); err != nil {
+		return handlePackageErrors(err)
+	}
+
+	// the search document is built from the version tagged latest
+	if distTag == latestDistTag {
+		s.searchService.reindex(ctx, packageName)
+	}
+
+	return nil
+}
+
+func (s *PackageService) RemoveDistTag(ctx context.Context, user *entities.User, name string, tag string) error {
+	if user.Role.Permissions.UpdatePackage == false {
+		return &coreerrors.NotAllowedToUpdatePackageError{}
+	}
+
+	packageName, distTag, err := distTagRequestToFields(name, tag)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	if distTag == latestDistTag {
+		return &PackageServiceInvalidDistTagError{
+			Tag:    distTag.String(),
+			Reason: "the latest tag cannot be removed",
+		}
+	}
+
+	if err := s.storageAdapter.RemoveDistTag(ctx, packageName, distTag); err != nil {
+		return handlePackageErrors(err)
+	}
+
+	return nil
+}
+
+// ReplaceDistTags sets all given tags and removes every other tag of the package at once.
+// The latest tag is kept if it is not part of the given tags.
+func (s *PackageService) ReplaceDistTags(ctx context.Context, user *entities.User, name string, tags map[string]string) error {
+	if user.Role.Permissions.UpdatePackage == false {
+		return &coreerrors.NotAllowedToUpdatePackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	distTags := make(map[fields.RequiredString]fields.Version, len(tags))
+	for tag, version := range tags {
+		_, distTag, err := distTagRequestToFields(name, tag)
+		if err != nil {
+			return err
+		}
+
+		packageVersion, err := fields.VersionFromString(version)
+		if err != nil {
+			return &InvalidGetPackageFieldError{
+				Field:  "version",
+				Reason: err.Error(),
+			}
+		}
+
+		distTags[distTag] = packageVersion
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	if err := s.storageAdapter.ReplaceDistTags(ctx, packageName, distTags); err != nil {
+		return handlePackageErrors(err)
+	}
+
+	// the search document is built from the version tagged latest
+	if _, ok := distTags[latestDistTag]; ok {
+		s.searchService.reindex(ctx, packageName)
+	}
+
+	return nil
+}
+
+// helpers
+
+const latestDistTag fields.RequiredString = "latest"
+
+// checkPackageAccess returns NotAllowedToAccessPackageError if the access token of the user is restricted to other packages.
+func checkPackageAccess(user *entities.User, name fields.PackageName) error {
+	if !user.CanAccessPackage(name) {
+		return &coreerrors.NotAllowedToAccessPackageError{
+			Name: name.String(),
+		}
+	}
+	return nil
+}
+
+// keepLatestDistTag drops the latest tag from the manifest if the package already has a higher latest version,
+// so that publishing a backport like 1.2.9 after 2.0.0 doesn't move latest backwards.
+func (s *PackageService) keepLatestDistTag(ctx context.Context, manifest *entities.PackageVersion) ([]fields.RequiredString, error) {
+	current, err := s.storageAdapter.GetDistTags(ctx, manifest.Name)
+	if err != nil {
+		if _, ok := err.(*ports.StorageAdapterPackageNotFoundError); ok {
+			return manifest.DistTags, nil
+		}
+		return nil, handlePackageErrors(err)
+	}
+
+	latest, ok := current[latestDistTag]
+	if !ok {
+		return manifest.DistTags, nil
+	}
+
+	latestVersion, err := fields.VersionFromString(latest.String())
+	if err != nil || !latestVersion.GreaterThan(manifest.Version) {
+		return manifest.DistTags, nil
+	}
+
+	tags := make([]fields.RequiredString, 0, len(manifest.DistTags))
+	for _, tag := range manifest.DistTags {
+		if tag != latestDistTag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (s *PackageService) getMaxSatisfyingVersion(ctx context.Context, name fields.PackageName, versionRange fields.VersionRange) (*entities.PackageVersion, error) {
+	pkg, err := s.storageAdapter.GetPackument(ctx, name)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	var max *entities.PackageVersion
+	for _, ver := range pkg.Versions {
+		if versionRange.Contains(ver.Version) && (max == nil || ver.Version.GreaterThan(max.Version)) {
+			max = ver
+		}
+	}
+
+	if max == nil {
+		return nil, &PackageServicePackageNotFoundError{
+			Name:    name.String(),
+			Version: versionRange.String(),
+		}
+	}
+
+	return max, nil
+}
+
+// validateDistTag rejects tags that could be confused with a version or range like "1.x".
+func validateDistTag(tag fields.RequiredString) error {
+	if _, err := fields.VersionRangeFromString(tag.String()); err == nil {
+		return &PackageServiceInvalidDistTagError{
+			Tag:    tag.String(),
+			Reason: "tag must not be a valid semver range",
+		}
+	}
+	return nil
+}
+
+func distTagRequestToFields(name string, tag string) (fields.PackageName, fields.RequiredString, error) {
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return "", "", &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	distTag, err := fields.RequiredStringFromString(tag)
+	if err != nil {
+		return "", "", &PackageServiceInvalidDistTagError{
+			Tag:    tag,
+			Reason: err.Error(),
+		}
+	}
+
+	if err := validateDistTag(distTag); err != nil {
+		return "", "", err
+	}
+
+	return packageName, distTag, nil
+}
+
 // errors
 
 type PackageServiceManifestParseError struct {
@@ -127,6 +572,9 @@ type PackageServicePackageNotFoundError struct {
 }
 
 func (e *PackageServicePackageNotFoundError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("package %s not found", e.Name)
+	}
 	return fmt.Sprintf("package %s@%s not found", e.Name, e.Version)
 }
 
@@ -137,6 +585,9 @@ type PackageServiceGetPackageError struct {
 }
 
 func (e *PackageServiceGetPackageError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("failed to get package %s: %s", e.Name, e.Err)
+	}
 	return fmt.Sprintf("failed to get package %s@%s: %s", e.Name, e.Version, e.Err)
 }
 
@@ -149,6 +600,73 @@ func (e *InvalidGetPackageFieldError) Error() string {
 	return fmt.Sprintf("invalid get package field %s: %s", e.Field, e.Reason)
 }
 
+type PackageServiceVersionAlreadyExistsError struct {
+	Name    string
+	Version string
+}
+
+func (e *PackageServiceVersionAlreadyExistsError) Error() string {
+	return fmt.Sprintf("cannot publish over the previously published version %s@%s", e.Name, e.Version)
+}
+
+type PackageServiceTarballNotFoundError struct {
+	Name    string
+	Version string
+}
+
+func (e *PackageServiceTarballNotFoundError) Error() string {
+	return fmt.Sprintf("tarball of package %s@%s not found", e.Name, e.Version)
+}
+
+type PackageServiceTarballError struct {
+	Err error
+}
+
+func (e *PackageServiceTarballError) Error() string {
+	return fmt.Sprintf("failed to handle tarball: %s", e.Err)
+}
+
+type PackageServiceDistTagNotFoundError struct {
+	Name string
+	Tag  string
+}
+
+func (e *PackageServiceDistTagNotFoundError) Error() string {
+	return fmt.Sprintf("dist-tag %s of package %s not found", e.Tag, e.Name)
+}
+
+type PackageServiceDistTagError struct {
+	Name string
+	Tag  string
+	Err  error
+}
+
+func (e *PackageServiceDistTagError) Error() string {
+	if e.Tag == "" {
+		return fmt.Sprintf("failed to handle dist-tags of package %s: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("failed to handle dist-tag %s of package %s: %s", e.Tag, e.Name, e.Err)
+}
+
+type PackageServiceInvalidDistTagError struct {
+	Tag    string
+	Reason string
+}
+
+func (e *PackageServiceInvalidDistTagError) Error() string {
+	return fmt.Sprintf("invalid dist-tag %q: %s", e.Tag, e.Reason)
+}
+
+type PackageServiceUpdatePackageError struct {
+	Name    string
+	Version string
+	Err     error
+}
+
+func (e *PackageServiceUpdatePackageError) Error() string {
+	return fmt.Sprintf("failed to update package %s@%s: %s", e.Name, e.Version, e.Err)
+}
+
 // service errors
 
 func handlePackageErrors(err error) error {
@@ -157,6 +675,10 @@ func handlePackageErrors(err error) error {
 		return &PackageServiceManifestParseError{
 			Err: e.Err,
 		}
+	case *ports.PackageAdapterTarballError:
+		return &PackageServiceManifestParseError{
+			Err: e.Err,
+		}
 	case *ports.StorageAdapterPublishPackageError:
 		return &PackageServicePublishPackageError{
 			Err: e.Err,
@@ -172,6 +694,38 @@ func handlePackageErrors(err error) error {
 			Version: e.Version.String(),
 			Err:     e.Err,
 		}
+	case *ports.StorageAdapterVersionAlreadyExistsError:
+		return &PackageServiceVersionAlreadyExistsError{
+			Name:    e.Name.String(),
+			Version: e.Version.String(),
+		}
+	case *ports.BlobAdapterNotFoundError, *ports.BlobAdapterPutError, *ports.BlobAdapterGetError, *ports.BlobAdapterDeleteError:
+		return &PackageServiceTarballError{
+			Err: e,
+		}
+	case *ports.StorageAdapterDistTagNotFoundError:
+		return &PackageServiceDistTagNotFoundError{
+			Name: e.Name.String(),
+			Tag:  e.Tag.String(),
+		}
+	case *ports.StorageAdapterDistTagError:
+		return &PackageServiceDistTagError{
+			Name: e.Name.String(),
+			Tag:  e.Tag.String(),
+			Err:  e.Err,
+		}
+	case *ports.StorageAdapterUpdatePackageError:
+		return &PackageServiceUpdatePackageError{
+			Name:    e.Name.String(),
+			Version: e.Version.String(),
+			Err:     e.Err,
+		}
+	case *ports.StorageAdapterUnpublishError:
+		return &PackageServiceUnpublishError{
+			Name:    e.Name.String(),
+			Version: e.Version.String(),
+			Err:     e.Err,
+		}
 	default:
 		return &PackageServiceUnknownError{
 			Err: e,
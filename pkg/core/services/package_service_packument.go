@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// ParsePackument parses a package document sent with PUT to tell publishes apart from metadata updates.
+func (s *PackageService) ParsePackument(ctx context.Context, user *entities.User, r io.Reader) (*entities.PackumentUpdate, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	update, err := s.packageAdapter.ParsePackument(ctx, r)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	return update, nil
+}
+
+// UpdatePackument applies a package document sent by the npm cli to an existing package.
+// Versions missing from the document are unpublished, which is how "npm unpublish <name>@<version>" works,
+// and changed deprecation messages are stored, which is how "npm deprecate" works.
+// rev must match the current revision of the package document. Without rev only deprecations are applied, since a
+// document based on an outdated revision would unpublish the versions published since.
+func (s *PackageService) UpdatePackument(ctx context.Context, user *entities.User, name string, rev string, update *entities.PackumentUpdate) error {
+	if user.Role.Permissions.UpdatePackage == false && user.Role.Permissions.UnpublishPackage == false {
+		return &coreerrors.NotAllowedToUpdatePackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	if update.Name != packageName {
+		return &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: fmt.Sprintf("document of package %s sent to package %s", update.Name, packageName),
+		}
+	}
+
+	var pkg *entities.Package
+	if rev == "" {
+		pkg, err = s.storageAdapter.GetPackument(ctx, packageName)
+		if err != nil {
+			return handlePackageErrors(err)
+		}
+	} else {
+		pkg, err = s.getPackumentAtRevision(ctx, packageName, rev)
+		if err != nil {
+			return err
+		}
+	}
+
+	removed := make([]fields.Version, 0)
+	deprecations := make(map[*entities.PackageVersion]string)
+	for _, ver := range pkg.Versions {
+		updated := findUpdateVersion(update.Versions, ver.Version)
+		if updated == nil {
+			removed = append(removed, ver.Version)
+			continue
+		}
+
+		var current string
+		if ver.Deprecated != nil {
+			current = *ver.Deprecated
+		}
+		if updated.Deprecated != current {
+			deprecations[ver] = updated.Deprecated
+		}
+	}
+
+	if len(removed) > 0 && rev == "" {
+		return &PackageServiceRevisionRequiredError{Name: packageName.String()}
+	}
+	if len(removed) > 0 && user.Role.Permissions.UnpublishPackage == false {
+		return &coreerrors.NotAllowedToUnpublishPackageError{}
+	}
+	if len(deprecations) > 0 && user.Role.Permissions.UpdatePackage == false {
+		return &coreerrors.NotAllowedToUpdatePackageError{}
+	}
+
+	for ver, message := range deprecations {
+		if err := s.storageAdapter.DeprecateVersion(ctx, packageName, ver.Version, message); err != nil {
+			return handlePackageErrors(err)
+		}
+	}
+	if len(deprecations) > 0 {
+		s.searchService.reindex(ctx, packageName)
+	}
+
+	for _, ver := range removed {
+		if err := s.unpublishVersion(ctx, packageName, ver); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findUpdateVersion(versions []entities.PackumentUpdateVersion, version fields.Version) *entities.PackumentUpdateVersion {
+	for i := range versions {
+		if versions[i].Version.Equal(version) {
+			return &versions[i]
+		}
+	}
+	return nil
+}
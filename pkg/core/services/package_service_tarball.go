@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"path"
+	"strings"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// sriHashes are the hash algorithms supported in subresource integrity strings like "sha512-<base64>".
+var sriHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// verifyTarball checks the declared integrity, shasum and length of the manifest against its tarball.
+func verifyTarball(manifest *entities.PackageVersion) error {
+	if len(manifest.Tarball) == 0 {
+		return &PackageServiceManifestParseError{
+			Err: fmt.Errorf("tarball is missing"),
+		}
+	}
+
+	if manifest.Length != 0 && manifest.Length != len(manifest.Tarball) {
+		return &PackageServiceManifestParseError{
+			Err: &PackageServiceTarballMismatchError{
+				Field:    "length",
+				Declared: fmt.Sprintf("%d", manifest.Length),
+				Actual:   fmt.Sprintf("%d", len(manifest.Tarball)),
+			},
+		}
+	}
+	manifest.Length = len(manifest.Tarball)
+
+	shasum := sha1.Sum(manifest.Tarball)
+	if computed := hex.EncodeToString(shasum[:]); !strings.EqualFold(manifest.SHASUM.String(), computed) {
+		return &PackageServiceManifestParseError{
+			Err: &PackageServiceTarballMismatchError{
+				Field:    "shasum",
+				Declared: manifest.SHASUM.String(),
+				Actual:   computed,
+			},
+		}
+	}
+
+	if err := verifyIntegrity(manifest.Integrity.String(), manifest.Tarball); err != nil {
+		return &PackageServiceManifestParseError{
+			Err: err,
+		}
+	}
+
+	return nil
+}
+
+// verifyIntegrity checks every hash of a subresource integrity string with a supported algorithm.
+// At least one of them must be supported.
+func verifyIntegrity(integrity string, data []byte) error {
+	verified := false
+
+	for _, entry := range strings.Fields(integrity) {
+		algorithm, digest, ok := strings.Cut(entry, "-")
+		if !ok {
+			return &PackageServiceTarballMismatchError{
+				Field:    "integrity",
+				Declared: integrity,
+				Actual:   "invalid subresource integrity " + entry,
+			}
+		}
+
+		newHash, ok := sriHashes[algorithm]
+		if !ok {
+			continue
+		}
+
+		// options like "?foo" may follow the digest
+		digest, _, _ = strings.Cut(digest, "?")
+
+		h := newHash()
+		h.Write(data)
+		computed := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		if digest != computed {
+			return &PackageServiceTarballMismatchError{
+				Field:    "integrity",
+				Declared: entry,
+				Actual:   algorithm + "-" + computed,
+			}
+		}
+		verified = true
+	}
+
+	if !verified {
+		return &PackageServiceTarballMismatchError{
+			Field:    "integrity",
+			Declared: integrity,
+			Actual:   "no supported hash algorithm",
+		}
+	}
+
+	return nil
+}
+
+// crossCheckTarball rejects tarballs whose package.json differs from the published manifest, which prevents
+// manifest confusion. The files of the manifest are replaced by the files that actually ship.
+func (s *PackageService) crossCheckTarball(ctx context.Context, manifest *entities.PackageVersion) error {
+	tarball, err := s.packageAdapter.ReadTarball(ctx, bytes.NewReader(manifest.Tarball))
+	if err != nil {
+		return handlePackageErrors(err)
+	}
+
+	if tarball.Name != manifest.Name.String() {
+		return tarballMismatch("name", manifest.Name.String(), tarball.Name)
+	}
+
+	version, err := fields.VersionFromString(tarball.Version)
+	if err != nil || !version.Equal(manifest.Version) {
+		return tarballMismatch("version", manifest.Version.String(), tarball.Version)
+	}
+
+	dependencies := make(map[string]string, len(manifest.Dependencies))
+	for name, versionRange := range manifest.Dependencies {
+		dependencies[name.String()] = versionRange.String()
+	}
+	if !stringMapsEqual(dependencies, tarball.Dependencies) {
+		return tarballMismatch("dependencies", fmt.Sprint(dependencies), fmt.Sprint(tarball.Dependencies))
+	}
+
+	bin := make(map[string]string, len(manifest.Bin))
+	for command, file := range manifest.Bin {
+		bin[command.String()] = file.String()
+	}
+	if !stringMapsEqual(normalizeBin(bin), normalizeBin(tarball.Bin)) {
+		return tarballMismatch("bin", fmt.Sprint(bin), fmt.Sprint(tarball.Bin))
+	}
+
+	files := make([]fields.RequiredString, 0, len(tarball.Files))
+	for _, file := range tarball.Files {
+		if f, err := fields.RequiredStringFromString(file); err == nil {
+			files = append(files, f)
+		}
+	}
+	manifest.Files = files
+
+	return nil
+}
+
+func tarballMismatch(field, declared, actual string) error {
+	return &PackageServiceManifestParseError{
+		Err: &PackageServiceTarballMismatchError{
+			Field:    "package.json " + field,
+			Declared: declared,
+			Actual:   actual,
+		},
+	}
+}
+
+// normalizeBin cleans the paths of bin entries, so that "./bin/cli.js" and "bin/cli.js" are equal.
+func normalizeBin(bin map[string]string) map[string]string {
+	normalized := make(map[string]string, len(bin))
+	for command, file := range bin {
+		normalized[command] = path.Clean(file)
+	}
+	return normalized
+}
+
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// errors
+
+type PackageServiceTarballMismatchError struct {
+	Field    string
+	Declared string
+	Actual   string
+}
+
+func (e *PackageServiceTarballMismatchError) Error() string {
+	return fmt.Sprintf("tarball %s mismatch: declared %s, actual %s", e.Field, e.Declared, e.Actual)
+}
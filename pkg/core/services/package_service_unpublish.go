@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+)
+
+// UnpublishVersion removes a single version of a package. Dist-tags pointing to the version are moved:
+// latest moves to the highest remaining version, every other tag is removed.
+// The whole package is unpublished once its last version is gone.
+// rev must match the current revision of the package document. npm removes the version from the document first,
+// see UpdatePackument, so a version which is already gone is no error.
+func (s *PackageService) UnpublishVersion(ctx context.Context, user *entities.User, name string, version string, rev string) error {
+	if user.Role.Permissions.UnpublishPackage == false {
+		return &coreerrors.NotAllowedToUnpublishPackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	packageVersion, err := fields.VersionFromString(version)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "version",
+			Reason: err.Error(),
+		}
+	}
+
+	pkg, err := s.getPackumentAtRevision(ctx, packageName, rev)
+	if err != nil {
+		if _, ok := err.(*PackageServicePackageNotFoundError); ok {
+			// the last version was removed along with the package
+			return nil
+		}
+		return err
+	}
+
+	for _, ver := range pkg.Versions {
+		if ver.Version.Equal(packageVersion) {
+			return s.unpublishVersion(ctx, packageName, packageVersion)
+		}
+	}
+
+	return nil
+}
+
+// UnpublishPackage removes a package with all of its versions and dist-tags.
+// rev must match the current revision of the package document.
+func (s *PackageService) UnpublishPackage(ctx context.Context, user *entities.User, name string, rev string) error {
+	if user.Role.Permissions.UnpublishPackage == false {
+		return &coreerrors.NotAllowedToUnpublishPackageError{}
+	}
+
+	packageName, err := fields.PackageNameFromString(name)
+	if err != nil {
+		return &InvalidGetPackageFieldError{
+			Field:  "name",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := checkPackageAccess(user, packageName); err != nil {
+		return err
+	}
+
+	if err := s.authService.requireTwoFactor(ctx, user, twoFactorWrite); err != nil {
+		return err
+	}
+
+	if _, err := s.getPackumentAtRevision(ctx, packageName, rev); err != nil {
+		return err
+	}
+
+	if err := s.storageAdapter.UnpublishPackage(ctx, packageName); err != nil {
+		return handlePackageErrors(err)
+	}
+
+	s.searchService.reindex(ctx, packageName)
+	return nil
+}
+
+func (s *PackageService) unpublishVersion(ctx context.Context, name fields.PackageName, version fields.Version) error {
+	if err := s.storageAdapter.UnpublishVersion(ctx, name, version); err != nil {
+		return handlePackageErrors(err)
+	}
+
+	s.searchService.reindex(ctx, name)
+	return nil
+}
+
+func (s *PackageService) getPackumentAtRevision(ctx context.Context, name fields.PackageName, rev string) (*entities.Package, error) {
+	pkg, err := s.storageAdapter.GetPackument(ctx, name)
+	if err != nil {
+		return nil, handlePackageErrors(err)
+	}
+
+	if pkg.Rev() != rev {
+		return nil, &PackageServiceRevisionMismatchError{
+			Name:     name.String(),
+			Expected: pkg.Rev(),
+			Actual:   rev,
+		}
+	}
+
+	return pkg, nil
+}
+
+// highestVersion returns the highest stable version, or the highest prerelease if there is no stable version.
+func highestVersion(versions []*entities.PackageVersion) fields.Version {
+	vs := make([]fields.Version, 0, len(versions))
+	for _, ver := range versions {
+		vs = append(vs, ver.Version)
+	}
+	return fields.HighestVersion(vs)
+}
+
+// errors
+
+type PackageServiceRevisionMismatchError struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *PackageServiceRevisionMismatchError) Error() string {
+	return fmt.Sprintf("revision %s of package %s is outdated, current revision is %s", e.Actual, e.Name, e.Expected)
+}
+
+// PackageServiceRevisionRequiredError is returned for package documents without revision which would unpublish versions.
+type PackageServiceRevisionRequiredError struct {
+	Name string
+}
+
+func (e *PackageServiceRevisionRequiredError) Error() string {
+	return fmt.Sprintf("unpublishing versions of package %s requires the current revision of the package document", e.Name)
+}
+
+type PackageServiceUnpublishError struct {
+	Name    string
+	Version string
+	Err     error
+}
+
+func (e *PackageServiceUnpublishError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("failed to unpublish package %s: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("failed to unpublish package %s@%s: %s", e.Name, e.Version, e.Err)
+}
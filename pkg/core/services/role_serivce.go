@@ -11,11 +11,12 @@ import (
 )
 
 type RoleService struct {
-	adapter ports.RolePort
+	adapter        ports.RolePort
+	sessionService *SessionService
 }
 
-func NewRoleService(adapter ports.RolePort) *RoleService {
-	return &RoleService{adapter: adapter}
+func NewRoleService(adapter ports.RolePort, sessionService *SessionService) *RoleService {
+	return &RoleService{adapter: adapter, sessionService: sessionService}
 }
 
 // use cases
@@ -91,6 +92,9 @@ func (s *RoleService) UpdateRole(ctx context.Context, user *entities.User, roleI
 		return handleRoleServiceErrors(err)
 	}
 
+	// sessions resolve their users per request, the cached users still hold the old permissions
+	s.sessionService.forgetAllUsers()
+
 	return nil
 }
 
@@ -110,15 +114,18 @@ func (s *RoleService) DeleteRole(ctx context.Context, user *entities.User, roleI
 		return handleRoleServiceErrors(err)
 	}
 
+	s.sessionService.forgetAllUsers()
+
 	return nil
 }
 
 // requests
 
 type CreateRoleRequest struct {
-	Name        string
-	Description string
-	Permissions []string
+	Name             string
+	Description      string
+	Permissions      []string
+	RequireTwoFactor bool
 }
 
 func CreateRoleRequestToInput(req CreateRoleRequest) (ports.CreateRoleInput, error) {
@@ -126,16 +133,18 @@ func CreateRoleRequestToInput(req CreateRoleRequest) (ports.CreateRoleInput, err
 	permissions.FromSlice(req.Permissions)
 
 	return ports.CreateRoleInput{
-		Name:        fields.RequiredString(req.Name),
-		Description: req.Description,
-		Permissions: permissions,
+		Name:             fields.RequiredString(req.Name),
+		Description:      req.Description,
+		Permissions:      permissions,
+		RequireTwoFactor: req.RequireTwoFactor,
 	}, nil
 }
 
 type UpdateRoleRequest struct {
-	Name        *string
-	Description *string
-	Permissions *[]string
+	Name             *string
+	Description      *string
+	Permissions      *[]string
+	RequireTwoFactor *bool
 }
 
 func UpdateRoleRequestToInput(req UpdateRoleRequest) (ports.UpdateRoleInput, error) {
@@ -156,9 +165,10 @@ func UpdateRoleRequestToInput(req UpdateRoleRequest) (ports.UpdateRoleInput, err
 	}
 
 	return ports.UpdateRoleInput{
-		Name:        name,
-		Description: req.Description,
-		Permissions: permissions,
+		Name:             name,
+		Description:      req.Description,
+		Permissions:      permissions,
+		RequireTwoFactor: req.RequireTwoFactor,
 	}, nil
 }
 
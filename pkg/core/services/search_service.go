@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+const (
+	defaultSearchSize = 20
+	maxSearchSize     = 250
+)
+
+type SearchService struct {
+	searchAdapter  ports.SearchPort
+	storageAdapter ports.StoragePort
+
+	// pending are the packages whose search document could not be updated after a write.
+	pendingMu sync.Mutex
+	pending   map[fields.PackageName]bool
+}
+
+func NewSearchService(searchAdapter ports.SearchPort, storageAdapter ports.StoragePort) *SearchService {
+	return &SearchService{
+		searchAdapter:  searchAdapter,
+		storageAdapter: storageAdapter,
+		pending:        make(map[fields.PackageName]bool),
+	}
+}
+
+// usecases
+
+// Search returns the packages matching the text, which may contain the qualifiers "keywords:a,b", "author:name",
+// "maintainer:name", "scope:name", "is:deprecated" and "not:deprecated" besides plain words.
+// A size of 0 selects the default page size.
+func (s *SearchService) Search(ctx context.Context, user *entities.User, text string, size int, from int) (*entities.SearchResult, error) {
+	if user.Role.Permissions.GetPackage == false {
+		return nil, &coreerrors.NotAllowedToGetPackageError{}
+	}
+
+	if size == 0 {
+		size = defaultSearchSize
+	}
+	if size < 0 || size > maxSearchSize {
+		return nil, &InvalidSearchFieldError{
+			Field:  "size",
+			Reason: fmt.Sprintf("must be between 1 and %d", maxSearchSize),
+		}
+	}
+	if from < 0 {
+		return nil, &InvalidSearchFieldError{
+			Field:  "from",
+			Reason: "must not be negative",
+		}
+	}
+
+	query := searchQueryFromText(text)
+	query.Size = size
+	query.From = from
+	if user.AccessToken != nil {
+		query.Restriction = user.AccessToken.Restriction
+	}
+
+	result, err := s.searchAdapter.Search(ctx, query)
+	if err != nil {
+		return nil, handleSearchErrors(err)
+	}
+
+	return result, nil
+}
+
+// RebuildIndex indexes all packages of the storage and returns the number of indexed packages.
+// It is run on startup since the index may be held in memory or miss writes whose indexing was lost.
+func (s *SearchService) RebuildIndex(ctx context.Context) (int, error) {
+	names, err := s.storageAdapter.ListPackageNames(ctx)
+	if err != nil {
+		return 0, handlePackageErrors(err)
+	}
+
+	for _, name := range names {
+		if err := indexPackage(ctx, s.storageAdapter, s.searchAdapter, name); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(names), nil
+}
+
+// IndexPending indexes the packages again whose search document could not be updated after a write and returns
+// the number of indexed packages. Packages failing again stay pending, the first error is returned.
+func (s *SearchService) IndexPending(ctx context.Context) (int, error) {
+	s.pendingMu.Lock()
+	names := make([]fields.PackageName, 0, len(s.pending))
+	for name := range s.pending {
+		names = append(names, name)
+	}
+	s.pendingMu.Unlock()
+
+	indexed := 0
+	var firstErr error
+	for _, name := range names {
+		if err := indexPackage(ctx, s.storageAdapter, s.searchAdapter, name); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		s.pendingMu.Lock()
+		delete(s.pending, name)
+		s.pendingMu.Unlock()
+		indexed++
+	}
+
+	return indexed, firstErr
+}
+
+// helpers
+
+// reindex updates the search document of the package after a write. The write is stored at this point, so a failure
+// must not fail it: the package is kept pending for IndexPending instead.
+func (s *SearchService) reindex(ctx context.Context, name fields.PackageName) {
+	if err := indexPackage(ctx, s.storageAdapter, s.searchAdapter, name); err != nil {
+		s.pendingMu.Lock()
+		s.pending[name] = true
+		s.pendingMu.Unlock()
+	}
+}
+
+// indexPackage updates the search document of the package, or removes it once the package is gone.
+func indexPackage(ctx context.Context, storageAdapter ports.StoragePort, searchAdapter ports.SearchPort, name fields.PackageName) error {
+	pkg, err := storageAdapter.GetPackument(ctx, name)
+	if err != nil {
+		if _, ok := err.(*ports.StorageAdapterPackageNotFoundError); ok {
+			if err := searchAdapter.Remove(ctx, name); err != nil {
+				return handleSearchErrors(err)
+			}
+			return nil
+		}
+		return handlePackageErrors(err)
+	}
+
+	if err := searchAdapter.Index(ctx, searchDocumentFromPackage(pkg)); err != nil {
+		return handleSearchErrors(err)
+	}
+
+	return nil
+}
+
+// searchDocumentFromPackage builds the search document of the version tagged latest.
+func searchDocumentFromPackage(pkg *entities.Package) *entities.SearchDocument {
+	var latest *entities.PackageVersion
+	if tagged, ok := pkg.DistTags[latestDistTag]; ok {
+		for _, ver := range pkg.Versions {
+			if ver.Version.String() == tagged.String() {
+				latest = ver
+			}
+		}
+	}
+	if latest == nil {
+		highest := highestVersion(pkg.Versions)
+		for _, ver := range pkg.Versions {
+			if ver.Version.Equal(highest) {
+				latest = ver
+			}
+		}
+	}
+
+	doc := &entities.SearchDocument{
+		Name:        pkg.Name,
+		Version:     latest.Version,
+		Keywords:    fields.StringsFromRequiredStrings(latest.Keywords),
+		Publisher:   latest.Publisher,
+		Maintainers: pkg.Maintainers,
+		Deprecated:  latest.Deprecated != nil,
+		Date:        latest.CreatedAt,
+	}
+
+	if latest.Description != nil {
+		doc.Description = *latest.Description
+	}
+	if latest.Readme != nil {
+		doc.Readme = *latest.Readme
+	}
+
+	if latest.Author != nil {
+		if author, ok := fields.Author[any](*latest.Author).TryForeignAuthor(); ok {
+			doc.Author = author.Name.String()
+		}
+	}
+	if doc.Author == "" && latest.Publisher != nil {
+		doc.Author = latest.Publisher.Name.String()
+	}
+
+	return doc
+}
+
+// searchQueryFromText splits the search text into words and qualifiers. Unknown qualifiers are searched as words.
+func searchQueryFromText(text string) *entities.SearchQuery {
+	query := &entities.SearchQuery{}
+
+	for _, word := range strings.Fields(text) {
+		qualifier, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" {
+			query.Terms = append(query.Terms, word)
+			continue
+		}
+
+		switch strings.ToLower(qualifier) {
+		case "keywords":
+			for _, keyword := range strings.Split(value, ",") {
+				if keyword != "" {
+					query.Keywords = append(query.Keywords, keyword)
+				}
+			}
+		case "author":
+			query.Author = value
+		case "maintainer":
+			query.Maintainer = value
+		case "scope":
+			query.Scope = strings.TrimPrefix(value, "@")
+		case "is", "not":
+			if strings.ToLower(value) != "deprecated" {
+				query.Terms = append(query.Terms, word)
+				continue
+			}
+			deprecated := strings.ToLower(qualifier) == "is"
+			query.Deprecated = &deprecated
+		default:
+			query.Terms = append(query.Terms, word)
+		}
+	}
+
+	return query
+}
+
+// errors
+
+type InvalidSearchFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidSearchFieldError) Error() string {
+	return fmt.Sprintf("invalid search field %s: %s", e.Field, e.Reason)
+}
+
+type SearchServiceIndexError struct {
+	Name string
+	Err  error
+}
+
+func (e *SearchServiceIndexError) Error() string {
+	return fmt.Sprintf("failed to index package %s: %s", e.Name, e.Err)
+}
+
+type SearchServiceSearchError struct {
+	Err error
+}
+
+func (e *SearchServiceSearchError) Error() string {
+	return fmt.Sprintf("failed to search packages: %s", e.Err)
+}
+
+// service errors
+
+func handleSearchErrors(err error) error {
+	switch e := err.(type) {
+	case *ports.SearchAdapterIndexError:
+		return &SearchServiceIndexError{
+			Name: e.Name.String(),
+			Err:  e.Err,
+		}
+	case *ports.SearchAdapterSearchError:
+		return &SearchServiceSearchError{
+			Err: e.Err,
+		}
+	default:
+		return &PackageServiceUnknownError{
+			Err: e,
+		}
+	}
+}
@@ -10,34 +10,51 @@ import (
 )
 
 type SessionService struct {
-	adapter ports.SessionPort
+	adapter     ports.SessionPort
+	userAdapter ports.UserPort
+	principals  *principalCache
 }
 
-func NewSessionService(adapter ports.SessionPort) *SessionService {
+func NewSessionService(adapter ports.SessionPort, userAdapter ports.UserPort) *SessionService {
 	return &SessionService{
-		adapter: adapter,
+		adapter:     adapter,
+		userAdapter: userAdapter,
+		principals:  newPrincipalCache(),
 	}
 }
 
+type sessionClientContextKey struct{}
+
+// ContextWithSessionClient returns a context carrying the client of a request, which is recorded in the sessions it uses.
+func ContextWithSessionClient(ctx context.Context, client entities.SessionClient) context.Context {
+	return context.WithValue(ctx, sessionClientContextKey{}, client)
+}
+
+func sessionClientFromContext(ctx context.Context) entities.SessionClient {
+	client, _ := ctx.Value(sessionClientContextKey{}).(entities.SessionClient)
+	return client
+}
+
 func (s *SessionService) CreateSession(ctx context.Context) (*entities.Session, error) {
-	session, err := s.adapter.CreateSession(ctx)
+	session, err := s.adapter.CreateSession(ctx, sessionClientFromContext(ctx))
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
 	return session, nil
 }
 
+// CreateSessionForUser creates a session linked to the user. Only the ID of the user is stored, see GetSessionUser.
 func (s *SessionService) CreateSessionForUser(ctx context.Context, user *entities.User) (*entities.Session, error) {
-	session, err := s.adapter.CreateSession(ctx)
+	session, err := s.adapter.CreateSession(ctx, sessionClientFromContext(ctx))
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
 
-	if err := s.adapter.LinkSessionToUser(ctx, session.Token, user.ID); err != nil {
+	if err := s.adapter.LinkSessionToUser(ctx, session.Key, user.ID); err != nil {
 		return nil, handleSessionErrors(err)
 	}
 
-	err = s.adapter.SetValue(ctx, session.Token, "user", user)
+	err = s.adapter.SetValue(ctx, session.Key, sessionUserKey, user.ID)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
@@ -45,26 +62,26 @@ func (s *SessionService) CreateSessionForUser(ctx context.Context, user *entitie
 	return session, nil
 }
 
-func (s *SessionService) GetLinkedSessions(ctx context.Context, userID string) ([]fields.SessionToken, error) {
+func (s *SessionService) GetLinkedSessions(ctx context.Context, userID string) ([]fields.SessionTokenKey, error) {
 	uID, err := fields.EntityIDFromString(userID)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
 
-	tokens, err := s.adapter.GetLinkedSessions(ctx, uID)
+	tokenKeys, err := s.adapter.GetLinkedSessions(ctx, uID)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
-	return tokens, nil
+	return tokenKeys, nil
 }
 
 func (s *SessionService) InvalidateSession(ctx context.Context, token string) error {
-	sessionToken, err := fields.SessionTokenFromString(token)
+	tokenKey, err := sessionTokenKey(token)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
 
-	err = s.adapter.InvalidateSession(ctx, sessionToken)
+	err = s.adapter.InvalidateSession(ctx, tokenKey)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
@@ -72,12 +89,12 @@ func (s *SessionService) InvalidateSession(ctx context.Context, token string) er
 }
 
 func (s *SessionService) ValidateToken(ctx context.Context, token string) error {
-	sessionToken, err := fields.SessionTokenFromString(token)
+	tokenKey, err := sessionTokenKey(token)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
 
-	err = s.adapter.ValidateToken(ctx, sessionToken)
+	err = s.adapter.ValidateToken(ctx, tokenKey)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
@@ -85,12 +102,12 @@ func (s *SessionService) ValidateToken(ctx context.Context, token string) error
 }
 
 func (s *SessionService) GetSession(ctx context.Context, token string) (*entities.Session, error) {
-	sessionToken, err := fields.SessionTokenFromString(token)
+	tokenKey, err := sessionTokenKey(token)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
 
-	session, err := s.adapter.GetSession(ctx, sessionToken)
+	session, err := s.adapter.GetSession(ctx, tokenKey)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
@@ -98,7 +115,7 @@ func (s *SessionService) GetSession(ctx context.Context, token string) (*entitie
 }
 
 func (s *SessionService) SetValue(ctx context.Context, token string, key string, value any) error {
-	sessionToken, err := fields.SessionTokenFromString(token)
+	tokenKey, err := sessionTokenKey(token)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
@@ -108,7 +125,7 @@ func (s *SessionService) SetValue(ctx context.Context, token string, key string,
 		return handleSessionErrors(err)
 	}
 
-	err = s.adapter.SetValue(ctx, sessionToken, keyNZ, value)
+	err = s.adapter.SetValue(ctx, tokenKey, keyNZ, value)
 	if err != nil {
 		return handleSessionErrors(err)
 	}
@@ -116,7 +133,7 @@ func (s *SessionService) SetValue(ctx context.Context, token string, key string,
 }
 
 func (s *SessionService) GetValue(ctx context.Context, token string, key string) (any, error) {
-	sessionToken, err := fields.SessionTokenFromString(token)
+	tokenKey, err := sessionTokenKey(token)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
@@ -126,7 +143,7 @@ func (s *SessionService) GetValue(ctx context.Context, token string, key string)
 		return nil, handleSessionErrors(err)
 	}
 
-	value, err := s.adapter.GetValue(ctx, sessionToken, keyNZ)
+	value, err := s.adapter.GetValue(ctx, tokenKey, keyNZ)
 	if err != nil {
 		return nil, handleSessionErrors(err)
 	}
@@ -157,15 +174,24 @@ func SessionValueFromService[V any](service *SessionService, ctx context.Context
 		v, ok = value.(V)
 		if !ok {
 			return nil, &InvalidValueTypeError{
-				Token: token,
-				Key:   key,
-				Value: value,
+				TokenKey: fields.SessionToken(token).Key().String(),
+				Key:      key,
+				Value:    value,
 			}
 		}
 	}
 	return &v, nil
 }
 
+// sessionTokenKey returns the key under which the session of the token is stored.
+func sessionTokenKey(token string) (fields.SessionTokenKey, error) {
+	sessionToken, err := fields.SessionTokenFromString(token)
+	if err != nil {
+		return "", err
+	}
+	return sessionToken.Key(), nil
+}
+
 func handleSessionErrors(err error) error {
 	switch et := err.(type) {
 	case *ports.SessionAdapterGetLinkedSessionsFailedError:
@@ -175,19 +201,19 @@ func handleSessionErrors(err error) error {
 		}
 	case *ports.SessionAdapterInvalidateSessionFailedError:
 		return &InvalidateSessionFailedError{
-			Token: et.Token.String(),
-			Err:   et.Err,
+			TokenKey: et.TokenKey.String(),
+			Err:      et.Err,
 		}
 	case *ports.SessionAdapterLinkSessionToUserFailedError:
 		return &LinkSessionToUserFailedError{
-			Token:  et.Token.String(),
-			UserID: et.UserID.String(),
-			Err:    et.Err,
+			TokenKey: et.TokenKey.String(),
+			UserID:   et.UserID.String(),
+			Err:      et.Err,
 		}
 	case *fields.EmptySessionTokenError:
 		return &InvalidTokenError{
-			Token:  "",
-			Reason: "empty token",
+			TokenKey: "",
+			Reason:   "empty token",
 		}
 	case *ports.CreateSessionFailedError:
 		return &CreateSessionFailedError{
@@ -195,49 +221,54 @@ func handleSessionErrors(err error) error {
 		}
 	case *ports.ValidateTokenFailedError:
 		return &ValidateTokenFailedError{
-			Token: et.Token.String(),
-			Err:   et.Err,
+			TokenKey: et.TokenKey.String(),
+			Err:      et.Err,
 		}
 	case *ports.InvalidTokenError:
 		return &InvalidTokenError{
-			Token:  et.Token.String(),
-			Reason: et.Error(),
+			TokenKey: et.TokenKey.String(),
+			Reason:   et.Error(),
 		}
 	case *ports.ExpiredTokenError:
 		return &ExpiredTokenError{
-			Token: et.Token.String(),
+			TokenKey: et.TokenKey.String(),
 		}
 	case *ports.KeyNotFoundError:
 		return &KeyNotFoundError{
-			Token: et.Token.String(),
-			Key:   et.Key.String(),
+			TokenKey: et.TokenKey.String(),
+			Key:      et.Key.String(),
 		}
 	case *ports.InvalidValueTypeError:
 		return &InvalidValueTypeError{
-			Token: et.Token.String(),
-			Key:   et.Key.String(),
+			TokenKey: et.TokenKey.String(),
+			Key:      et.Key.String(),
+		}
+	case *ports.TouchSessionFailedError:
+		return &TouchSessionFailedError{
+			TokenKey: et.TokenKey.String(),
+			Err:      et.Err,
 		}
 	case *ports.GetSessionFailedError:
 		return &GetSessionFailedError{
-			Token: et.Token.String(),
-			Err:   et.Err,
+			TokenKey: et.TokenKey.String(),
+			Err:      et.Err,
 		}
 	case *ports.SetValueFailedError:
 		return &SetValueFailedError{
-			Token: et.Token.String(),
-			Key:   et.Key.String(),
-			Err:   et.Err,
+			TokenKey: et.TokenKey.String(),
+			Key:      et.Key.String(),
+			Err:      et.Err,
 		}
 	case *ports.GetValueFailedError:
 		return &GetValueFailedError{
-			Token: et.Token.String(),
-			Key:   et.Key.String(),
-			Err:   et.Err,
+			TokenKey: et.TokenKey.String(),
+			Key:      et.Key.String(),
+			Err:      et.Err,
 		}
 	case *fields.RequiredStringEmptyError:
 		return &KeyNotFoundError{
-			Token: "",
-			Key:   "",
+			TokenKey: "",
+			Key:      "",
 		}
 	default:
 		return err
@@ -256,77 +287,86 @@ func (e CreateSessionFailedError) Error() string {
 }
 
 type ValidateTokenFailedError struct {
-	Token string
-	Err   error
+	TokenKey string
+	Err      error
 }
 
 func (e ValidateTokenFailedError) Error() string {
-	return "ValidateToken failed for token " + e.Token + ": " + e.Err.Error()
+	return "ValidateToken failed for session " + e.TokenKey + ": " + e.Err.Error()
 }
 
 type InvalidTokenError struct {
-	Token  string
-	Reason string
+	TokenKey string
+	Reason   string
 }
 
 func (e InvalidTokenError) Error() string {
-	return "Token " + e.Token + " is invalid: " + e.Reason
+	return "Session " + e.TokenKey + " is invalid: " + e.Reason
 }
 
 type ExpiredTokenError struct {
-	Token string
+	TokenKey string
 }
 
 func (e ExpiredTokenError) Error() string {
-	return "Token " + e.Token + " is expired"
+	return "Session " + e.TokenKey + " is expired"
 }
 
 type KeyNotFoundError struct {
-	Token string
-	Key   string
+	TokenKey string
+	Key      string
 }
 
 func (e KeyNotFoundError) Error() string {
-	return "Key " + e.Key + " not found for token " + e.Token
+	return "Key " + e.Key + " not found for session " + e.TokenKey
 }
 
 type InvalidValueTypeError struct {
-	Token string
-	Key   string
-	Value any
+	TokenKey string
+	Key      string
+	Value    any
 }
 
 func (e InvalidValueTypeError) Error() string {
-	return fmt.Sprintf("Value %T for key %s is invalid for token %s", e.Value, e.Key, e.Token)
+	return fmt.Sprintf("Value %T for key %s is invalid for session %s", e.Value, e.Key, e.TokenKey)
 }
 
 type GetSessionFailedError struct {
-	Token string
-	Err   error
+	TokenKey string
+	Err      error
 }
 
 func (e GetSessionFailedError) Error() string {
-	return "GetSession failed for token " + e.Token + ": " + e.Err.Error()
+	return "GetSession failed for session " + e.TokenKey + ": " + e.Err.Error()
+}
+
+type TouchSessionFailedError struct {
+	TokenKey string
+	Err      error
+}
+
+func (e TouchSessionFailedError) Error() string {
+	return "TouchSession failed for session " + e.TokenKey + ": " + e.Err.Error()
 }
 
 type SetValueFailedError struct {
-	Token string
-	Key   string
-	Err   error
+	TokenKey string
+	Key      string
+	Err      error
 }
 
 func (e SetValueFailedError) Error() string {
-	return "SetValue failed for token " + e.Token + " and key " + e.Key + ": " + e.Err.Error()
+	return "SetValue failed for session " + e.TokenKey + " and key " + e.Key + ": " + e.Err.Error()
 }
 
 type GetValueFailedError struct {
-	Token string
-	Key   string
-	Err   error
+	TokenKey string
+	Key      string
+	Err      error
 }
 
 func (e GetValueFailedError) Error() string {
-	return "GetValue failed for token " + e.Token + " and key " + e.Key + ": " + e.Err.Error()
+	return "GetValue failed for session " + e.TokenKey + " and key " + e.Key + ": " + e.Err.Error()
 }
 
 type GetLinkedSessionsFailedError struct {
@@ -339,20 +379,20 @@ func (e GetLinkedSessionsFailedError) Error() string {
 }
 
 type InvalidateSessionFailedError struct {
-	Token string
-	Err   error
+	TokenKey string
+	Err      error
 }
 
 func (e InvalidateSessionFailedError) Error() string {
-	return "InvalidateSession failed for token " + e.Token + ": " + e.Err.Error()
+	return "InvalidateSession failed for session " + e.TokenKey + ": " + e.Err.Error()
 }
 
 type LinkSessionToUserFailedError struct {
-	Token  string
-	UserID string
-	Err    error
+	TokenKey string
+	UserID   string
+	Err      error
 }
 
 func (e LinkSessionToUserFailedError) Error() string {
-	return "LinkSessionToUser failed for token " + e.Token + " and user " + e.UserID + ": " + e.Err.Error()
+	return "LinkSessionToUser failed for session " + e.TokenKey + " and user " + e.UserID + ": " + e.Err.Error()
 }
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// sessionUserKey holds the ID of the user of a session. The user itself is loaded per request, so
+// changes of its role or permissions apply to existing sessions. The Redis session adapter migrates the user
+// of legacy sessions to this key.
+const sessionUserKey = "user_id"
+
+// principalCacheTTL bounds how long a changed user or role keeps its old permissions. Changes made through
+// this instance apply immediately, other instances notice them when their cache entry expires.
+const principalCacheTTL = 30 * time.Second
+
+// principalCacheSweepSize is the number of entries from which expired entries are removed on insert.
+const principalCacheSweepSize = 1024
+
+// GetSessionUser returns the current user of the session and records the use of the session.
+// Sessions of deleted users are invalidated.
+func (s *SessionService) GetSessionUser(ctx context.Context, token string) (*entities.User, error) {
+	tokenKey, err := sessionTokenKey(token)
+	if err != nil {
+		return nil, handleSessionErrors(err)
+	}
+
+	if err := s.touchSession(ctx, tokenKey); err != nil {
+		return nil, handleSessionErrors(err)
+	}
+
+	userID, err := ports.SessionValueFromAdapter[fields.EntityID](s.adapter, ctx, tokenKey, sessionUserKey)
+	if err != nil {
+		return nil, handleSessionErrors(err)
+	}
+
+	if user, ok := s.principals.get(userID); ok {
+		return user, nil
+	}
+
+	user, err := s.userAdapter.GetUserByID(ctx, userID)
+	if err != nil {
+		if _, ok := err.(*ports.UserAdapterUserNotFoundError); ok {
+			if err := s.adapter.InvalidateSession(ctx, tokenKey); err != nil {
+				return nil, handleSessionErrors(err)
+			}
+			return nil, &InvalidTokenError{
+				TokenKey: tokenKey.String(),
+				Reason:   "user of the session does not exist",
+			}
+		}
+		return nil, handleUserServiceErrors(err)
+	}
+
+	s.principals.put(user)
+	return user, nil
+}
+
+// touchSession records the use of the session, unless it was used by the same client within the touch interval.
+// The expiry lags behind the idle timeout by at most the touch interval.
+func (s *SessionService) touchSession(ctx context.Context, tokenKey fields.SessionTokenKey) error {
+	client := sessionClientFromContext(ctx)
+
+	session, err := s.adapter.GetSession(ctx, tokenKey)
+	if err != nil {
+		return err
+	}
+	if session.Client == client && !touchDue(session.LastUsedAt, time.Now()) {
+		return nil
+	}
+
+	_, err = s.adapter.TouchSession(ctx, tokenKey, client)
+	return err
+}
+
+// InvalidateUserSessions invalidates all sessions of the user, e.g. after the user was deleted or got another role.
+func (s *SessionService) InvalidateUserSessions(ctx context.Context, userID fields.EntityID) error {
+	s.principals.forget(userID)
+
+	tokenKeys, err := s.adapter.GetLinkedSessions(ctx, userID)
+	if err != nil {
+		return handleSessionErrors(err)
+	}
+
+	for _, tokenKey := range tokenKeys {
+		if err := s.adapter.InvalidateSession(ctx, tokenKey); err != nil {
+			return handleSessionErrors(err)
+		}
+	}
+
+	return nil
+}
+
+// forgetUser drops the cached user, so the next request of its sessions loads it again.
+func (s *SessionService) forgetUser(userID fields.EntityID) {
+	s.principals.forget(userID)
+}
+
+// forgetAllUsers drops all cached users, e.g. after the permissions of a role changed.
+func (s *SessionService) forgetAllUsers() {
+	s.principals.clear()
+}
+
+// principalCache holds the users of sessions for principalCacheTTL.
+type principalCache struct {
+	mu      sync.Mutex
+	entries map[fields.EntityID]principalCacheEntry
+}
+
+type principalCacheEntry struct {
+	user      *entities.User
+	expiresAt time.Time
+}
+
+func newPrincipalCache() *principalCache {
+	return &principalCache{
+		entries: make(map[fields.EntityID]principalCacheEntry),
+	}
+}
+
+func (c *principalCache) get(userID fields.EntityID) (*entities.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	// callers may modify the user, e.g. to attach an access token
+	user := *entry.user
+	return &user, true
+}
+
+func (c *principalCache) put(user *entities.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= principalCacheSweepSize {
+		for userID, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, userID)
+			}
+		}
+	}
+
+	cached := *user
+	c.entries[user.ID] = principalCacheEntry{
+		user:      &cached,
+		expiresAt: now.Add(principalCacheTTL),
+	}
+}
+
+func (c *principalCache) forget(userID fields.EntityID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+func (c *principalCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[fields.EntityID]principalCacheEntry)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// ListMySessions returns the sessions of the user, most recently used first.
+func (s *SessionService) ListMySessions(ctx context.Context, user *entities.User) ([]*entities.Session, error) {
+	return s.linkedSessions(ctx, user.ID)
+}
+
+// RevokeMySession invalidates the session of the user with the given ID, see entities.Session.ID.
+func (s *SessionService) RevokeMySession(ctx context.Context, user *entities.User, sessionID string) error {
+	return s.revokeSession(ctx, user.ID, sessionID)
+}
+
+// RevokeMyOtherSessions invalidates all sessions of the user except the one with the given token.
+// It returns the number of invalidated sessions.
+func (s *SessionService) RevokeMyOtherSessions(ctx context.Context, user *entities.User, token string) (int, error) {
+	return s.revokeSessions(ctx, user.ID, fields.SessionToken(token).Key())
+}
+
+// ListUserSessions returns the sessions of any user, most recently used first.
+func (s *SessionService) ListUserSessions(ctx context.Context, user *entities.User, userID string) ([]*entities.Session, error) {
+
+	if user == nil || user.Role.Permissions.GetUser == false {
+		return nil, &coreerrors.NotAllowedToGetUserError{}
+	}
+
+	id, err := fields.EntityIDFromString(userID)
+	if err != nil {
+		return nil, handleUserServiceRequestValidationError("id", err.Error())
+	}
+
+	return s.linkedSessions(ctx, id)
+}
+
+// RevokeUserSession invalidates the session with the given ID of any user.
+func (s *SessionService) RevokeUserSession(ctx context.Context, user *entities.User, userID string, sessionID string) error {
+
+	if user == nil || user.Role.Permissions.UpdateUser == false {
+		return &coreerrors.NotAllowedToUpdateUserError{}
+	}
+
+	id, err := fields.EntityIDFromString(userID)
+	if err != nil {
+		return handleUserServiceRequestValidationError("id", err.Error())
+	}
+
+	return s.revokeSession(ctx, id, sessionID)
+}
+
+// RevokeAllUserSessions invalidates all sessions of any user and returns their number.
+func (s *SessionService) RevokeAllUserSessions(ctx context.Context, user *entities.User, userID string) (int, error) {
+
+	if user == nil || user.Role.Permissions.UpdateUser == false {
+		return 0, &coreerrors.NotAllowedToUpdateUserError{}
+	}
+
+	id, err := fields.EntityIDFromString(userID)
+	if err != nil {
+		return 0, handleUserServiceRequestValidationError("id", err.Error())
+	}
+
+	return s.revokeSessions(ctx, id, "")
+}
+
+// helpers
+
+// linkedSessions returns the valid sessions of the user, most recently used first.
+func (s *SessionService) linkedSessions(ctx context.Context, userID fields.EntityID) ([]*entities.Session, error) {
+	tokenKeys, err := s.adapter.GetLinkedSessions(ctx, userID)
+	if err != nil {
+		return nil, handleSessionErrors(err)
+	}
+
+	sessions := make([]*entities.Session, 0, len(tokenKeys))
+	for _, tokenKey := range tokenKeys {
+		session, err := s.adapter.GetSession(ctx, tokenKey)
+		if err != nil {
+			// the session expired or was invalidated since it was listed
+			if _, ok := err.(*ports.SessionNotFoundError); ok {
+				continue
+			}
+			return nil, handleSessionErrors(err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// revokeSession invalidates the session of the user with the given ID.
+// Sessions are looked up among the sessions of the user, so nobody can end sessions of other users by their ID.
+func (s *SessionService) revokeSession(ctx context.Context, userID fields.EntityID, sessionID string) error {
+	sessions, err := s.linkedSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID() == sessionID {
+			if err := s.adapter.InvalidateSession(ctx, session.Key); err != nil {
+				return handleSessionErrors(err)
+			}
+			return nil
+		}
+	}
+
+	return &SessionServiceSessionNotFoundError{ID: sessionID}
+}
+
+// revokeSessions invalidates all sessions of the user except the kept one and returns their number.
+func (s *SessionService) revokeSessions(ctx context.Context, userID fields.EntityID, keep fields.SessionTokenKey) (int, error) {
+	tokenKeys, err := s.adapter.GetLinkedSessions(ctx, userID)
+	if err != nil {
+		return 0, handleSessionErrors(err)
+	}
+
+	revoked := 0
+	for _, tokenKey := range tokenKeys {
+		if tokenKey == keep {
+			continue
+		}
+		if err := s.adapter.InvalidateSession(ctx, tokenKey); err != nil {
+			return revoked, handleSessionErrors(err)
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// errors
+
+type SessionServiceSessionNotFoundError struct {
+	ID string
+}
+
+func (e SessionServiceSessionNotFoundError) Error() string {
+	return "session " + e.ID + " not found"
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// SignupConfig configures who may create an account with `npm adduser`.
+type SignupConfig struct {
+	Policy fields.SignupPolicy
+	// DefaultRoleID is the role of new users unless their invite names another role.
+	DefaultRoleID fields.EntityID
+	// AllowedDomains are the email domains accepted by SignupPolicyDomain.
+	AllowedDomains []string
+}
+
+type SignupService struct {
+	config SignupConfig
+
+	inviteAdapter ports.InvitePort
+	// registration receives the credentials of new users if it is set, e.g. to append them to an htpasswd file.
+	registration ports.ExternalRegistrationPort
+	userService  *UserService
+}
+
+func NewSignupService(
+	config SignupConfig,
+	inviteAdapter ports.InvitePort,
+	registration ports.ExternalRegistrationPort,
+	userService *UserService,
+) *SignupService {
+	return &SignupService{
+		config:        config,
+		inviteAdapter: inviteAdapter,
+		registration:  registration,
+		userService:   userService,
+	}
+}
+
+// usecases
+
+// SignUp creates a new user if the signup policy allows the email address.
+// The role of the request is ignored, new users get the default role or the role of their invite.
+func (s *SignupService) SignUp(ctx context.Context, req CreateUserRequest) (fields.EntityID, error) {
+	if s.config.Policy == fields.SignupPolicyDisabled {
+		return fields.EntityID(0), &SignupDisabledError{}
+	}
+
+	email, err := fields.EmailFromString(req.Email)
+	if err != nil {
+		return fields.EntityID(0), handleUserServiceRequestValidationError("email", err.Error())
+	}
+
+	roleID := s.config.DefaultRoleID
+	var inv *entities.Invite
+
+	switch s.config.Policy {
+	case fields.SignupPolicyDomain:
+		if !s.domainAllowed(email) {
+			return fields.EntityID(0), &SignupNotAllowedError{
+				Email:  email,
+				Reason: "the email domain is not allowed to sign up",
+			}
+		}
+	case fields.SignupPolicyInvite:
+		inv, err = s.getUsableInvite(ctx, email)
+		if err != nil {
+			return fields.EntityID(0), err
+		}
+		if inv.RoleID != nil {
+			roleID = *inv.RoleID
+		}
+	}
+
+	req.RoleID = roleID.String()
+	userID, err := s.userService.createUser(ctx, req)
+	if err != nil {
+		return fields.EntityID(0), err
+	}
+
+	// the local user is created first, so a directory entry can never take over an existing user
+	if s.registration != nil {
+		if err := s.registration.Register(ctx, fields.Username(req.Username), req.Password); err != nil {
+			if deleteErr := s.userService.deleteUser(ctx, userID); deleteErr != nil {
+				return fields.EntityID(0), deleteErr
+			}
+			return fields.EntityID(0), handleSignupErrors(err)
+		}
+	}
+
+	if inv != nil {
+		if err := s.inviteAdapter.UseInvite(ctx, inv.ID, userID); err != nil {
+			return fields.EntityID(0), handleSignupErrors(err)
+		}
+	}
+
+	return userID, nil
+}
+
+// CreateInvite allows the email address to sign up while the signup policy is invite-only.
+// A zero expiresIn creates an invite that does not expire.
+func (s *SignupService) CreateInvite(ctx context.Context, user *entities.User, req CreateInviteRequest) (fields.EntityID, error) {
+	if user == nil || user.Role.Permissions.CreateUser == false {
+		return fields.EntityID(0), &coreerrors.NotAllowedToCreateUserError{}
+	}
+
+	input, err := CreateInviteRequestToInput(req)
+	if err != nil {
+		return fields.EntityID(0), err
+	}
+	input.CreatedBy = user.ID
+
+	inviteID, err := s.inviteAdapter.CreateInvite(ctx, input)
+	if err != nil {
+		return fields.EntityID(0), handleSignupErrors(err)
+	}
+
+	return inviteID, nil
+}
+
+// helpers
+
+func (s *SignupService) domainAllowed(email fields.Email) bool {
+	domain := email.Domain()
+	for _, allowed := range s.config.AllowedDomains {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SignupService) getUsableInvite(ctx context.Context, email fields.Email) (*entities.Invite, error) {
+	invites, err := s.inviteAdapter.GetInvitesByEmail(ctx, email)
+	if err != nil {
+		return nil, handleSignupErrors(err)
+	}
+
+	now := time.Now()
+	for _, inv := range invites {
+		if inv.Usable(now) {
+			return inv, nil
+		}
+	}
+
+	return nil, &SignupNotAllowedError{
+		Email:  email,
+		Reason: "the email address has no valid invite",
+	}
+}
+
+// requests
+
+type CreateInviteRequest struct {
+	Email     string
+	RoleID    *string
+	ExpiresIn time.Duration
+}
+
+func CreateInviteRequestToInput(req CreateInviteRequest) (ports.CreateInviteInput, error) {
+	var input ports.CreateInviteInput
+	var err error
+
+	if input.Email, err = fields.EmailFromString(req.Email); err != nil {
+		return input, &InvalidInviteFieldError{Field: "email", Reason: err.Error()}
+	}
+
+	if req.RoleID != nil {
+		roleID, err := fields.EntityIDFromString(*req.RoleID)
+		if err != nil {
+			return input, &InvalidInviteFieldError{Field: "role_id", Reason: err.Error()}
+		}
+		input.RoleID = &roleID
+	}
+
+	if req.ExpiresIn < 0 {
+		return input, &InvalidInviteFieldError{Field: "expires_in", Reason: "must not be negative"}
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(req.ExpiresIn)
+		input.ExpiresAt = &expiresAt
+	}
+
+	return input, nil
+}
+
+// errors
+
+type SignupDisabledError struct{}
+
+func (e *SignupDisabledError) Error() string {
+	return "signup is disabled, ask an admin to create your account"
+}
+
+type SignupNotAllowedError struct {
+	Email  fields.Email
+	Reason string
+}
+
+func (e *SignupNotAllowedError) Error() string {
+	return fmt.Sprintf("signup of %s is not allowed: %s", e.Email, e.Reason)
+}
+
+type InvalidInviteFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidInviteFieldError) Error() string {
+	return fmt.Sprintf("invalid invite field %s: %s", e.Field, e.Reason)
+}
+
+type SignupServiceError struct {
+	Err error
+}
+
+func (e *SignupServiceError) Error() string {
+	return fmt.Sprintf("signup error: %s", e.Err)
+}
+
+// service errors
+
+func handleSignupErrors(err error) error {
+	switch e := err.(type) {
+	case *ports.InviteAdapterInviteNotFoundError:
+		return &SignupNotAllowedError{
+			Reason: "the invite was used already",
+		}
+	case *ports.ExternalAuthUserExistsError:
+		return &UserServiceUserAlreadyExistsError{
+			Username: e.Username,
+		}
+	default:
+		return &SignupServiceError{
+			Err: e,
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+const (
+	pendingLoginKey = "pending_login"
+	// webLoginTokenKey holds the session token of a finished web login in the session npm polls.
+	webLoginTokenKey = "web_login_token"
+)
+
+// SSOConfig configures single sign-on.
+type SSOConfig struct {
+	// WebRedirectURL is the page of the web UI browser logins return to, the token is appended as "#token=".
+	WebRedirectURL string
+}
+
+// SSOService logs users in through an OpenID Connect provider, either in the browser or with `npm login --auth-type=web`.
+// Pending logins are anonymous sessions, so they expire like sessions and need no storage of their own.
+// Two-factor authentication of the registry is not asked for, the provider is responsible for it.
+type SSOService struct {
+	config   SSOConfig
+	provider ports.IdentityProviderPort
+
+	authService    *AuthService
+	sessionService *SessionService
+}
+
+func NewSSOService(
+	config SSOConfig,
+	provider ports.IdentityProviderPort,
+	authService *AuthService,
+	sessionService *SessionService,
+) *SSOService {
+	return &SSOService{
+		config:         config,
+		provider:       provider,
+		authService:    authService,
+		sessionService: sessionService,
+	}
+}
+
+// usecases
+
+// CreateWebLogin starts a login of `npm login --auth-type=web`.
+func (s *SSOService) CreateWebLogin(ctx context.Context) (*entities.WebLogin, error) {
+	done, err := s.sessionService.CreateSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	login, err := s.sessionService.CreateSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sessionService.SetValue(ctx, login.Token.String(), pendingLoginKey, entities.PendingLogin{
+		DoneToken: done.Token.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.WebLogin{
+		LoginToken: login.Token.String(),
+		DoneToken:  done.Token.String(),
+	}, nil
+}
+
+// BeginLogin returns the authorization URL the browser is redirected to. An empty loginToken starts a browser login,
+// otherwise it continues the web login of npm.
+// The returned binding has to be kept by the browser, e.g. in a cookie, and passed to CompleteLogin, so a login can only
+// be completed in the browser that began it. Otherwise an attacker could send the callback URL of an own login to a
+// victim, who would be logged in as the attacker.
+func (s *SSOService) BeginLogin(ctx context.Context, loginToken string) (authURL string, binding string, err error) {
+	pending := &entities.PendingLogin{}
+
+	if loginToken == "" {
+		login, err := s.sessionService.CreateSession(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		loginToken = login.Token.String()
+	} else {
+		pending, err = s.getPendingLogin(ctx, loginToken)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	verifier, err := randomURLToken()
+	if err != nil {
+		return "", "", &SSOServiceError{Err: err}
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return "", "", &SSOServiceError{Err: err}
+	}
+
+	pending.CodeVerifier = verifier
+	pending.Nonce = nonce
+	if err := s.sessionService.SetValue(ctx, loginToken, pendingLoginKey, pending); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	return s.provider.AuthCodeURL(loginToken, nonce, base64.RawURLEncoding.EncodeToString(challenge[:])), stateBinding(loginToken), nil
+}
+
+// CompleteLogin handles the callback of the provider and creates the session of the user. binding is the one
+// BeginLogin returned to the browser.
+// It returns the URL of the web UI for browser logins and an empty URL for web logins of npm, which receive the token by polling.
+func (s *SSOService) CompleteLogin(ctx context.Context, state string, code string, binding string) (*entities.Session, string, error) {
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(stateBinding(state))) != 1 {
+		return nil, "", &SSOLoginBindingError{}
+	}
+
+	pending, err := s.getPendingLogin(ctx, state)
+	if err != nil {
+		return nil, "", err
+	}
+	if pending.CodeVerifier == "" {
+		return nil, "", &SSOLoginNotFoundError{}
+	}
+
+	// the state is single use
+	if err := s.sessionService.InvalidateSession(ctx, state); err != nil {
+		return nil, "", err
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, "", handleSSOErrors(err)
+	}
+
+	user, err := s.authService.provisionUser(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.sessionService.CreateSessionForUser(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pending.DoneToken != "" {
+		if err := s.sessionService.SetValue(ctx, pending.DoneToken, webLoginTokenKey, session.Token.String()); err != nil {
+			return nil, "", err
+		}
+		return session, "", nil
+	}
+
+	return session, s.config.WebRedirectURL + "#token=" + session.Token.String(), nil
+}
+
+// PollWebLogin returns the session token of a finished web login. The login is pending as long as done is false.
+func (s *SSOService) PollWebLogin(ctx context.Context, doneToken string) (token string, done bool, err error) {
+	if err := s.sessionService.ValidateToken(ctx, doneToken); err != nil {
+		return "", false, &SSOLoginNotFoundError{}
+	}
+
+	value, err := SessionValueFromService[string](s.sessionService, ctx, doneToken, webLoginTokenKey)
+	if err != nil {
+		if _, ok := err.(*KeyNotFoundError); ok {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	// the token is handed out once
+	if err := s.sessionService.InvalidateSession(ctx, doneToken); err != nil {
+		return "", false, err
+	}
+
+	return *value, true, nil
+}
+
+// helpers
+
+func (s *SSOService) getPendingLogin(ctx context.Context, loginToken string) (*entities.PendingLogin, error) {
+	if err := s.sessionService.ValidateToken(ctx, loginToken); err != nil {
+		return nil, &SSOLoginNotFoundError{}
+	}
+
+	pending, err := SessionValueFromService[entities.PendingLogin](s.sessionService, ctx, loginToken, pendingLoginKey)
+	if err != nil {
+		if _, ok := err.(*KeyNotFoundError); ok {
+			return nil, &SSOLoginNotFoundError{}
+		}
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// stateBinding returns the hash of the state, which the browser keeps instead of the state itself.
+func stateBinding(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomURLToken returns 32 random bytes encoded for URLs, as required for PKCE verifiers.
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// errors
+
+// SSOLoginNotFoundError is returned for unknown, expired or finished logins.
+type SSOLoginNotFoundError struct{}
+
+func (e *SSOLoginNotFoundError) Error() string {
+	return "login not found or expired, start the login again"
+}
+
+// SSOLoginBindingError is returned if the callback of a login reaches another browser than the one that began the login.
+type SSOLoginBindingError struct{}
+
+func (e *SSOLoginBindingError) Error() string {
+	return "login was started in another browser, start the login again"
+}
+
+// SSOLoginFailedError is returned if the identity provider did not confirm the login.
+type SSOLoginFailedError struct {
+	Err error
+}
+
+func (e *SSOLoginFailedError) Error() string {
+	return fmt.Sprintf("sso login failed: %s", e.Err)
+}
+
+type SSOServiceError struct {
+	Err error
+}
+
+func (e *SSOServiceError) Error() string {
+	return fmt.Sprintf("sso error: %s", e.Err)
+}
+
+// service errors
+
+func handleSSOErrors(err error) error {
+	switch err.(type) {
+	case *ports.IdentityProviderExchangeError:
+		return &SSOLoginFailedError{
+			Err: err,
+		}
+	default:
+		return handleErrors(err)
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/coreerrors"
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+type TokenService struct {
+	adapter     ports.TokenPort
+	userAdapter ports.UserPort
+
+	authService *AuthService
+}
+
+func NewTokenService(
+	adapter ports.TokenPort,
+	userAdapter ports.UserPort,
+	authService *AuthService,
+) *TokenService {
+	return &TokenService{
+		adapter:     adapter,
+		userAdapter: userAdapter,
+		authService: authService,
+	}
+}
+
+// CreateTokenRequest contains the options of a new access token as sent by "npm token create".
+type CreateTokenRequest struct {
+	// Password of the user, which is verified again before a token is created.
+	Password      string
+	Readonly      bool
+	CIDRWhitelist []string
+	// Automation tokens skip the one-time password of writes, they require two-factor authentication to be enabled.
+	Automation bool
+	// Packages and Scopes restrict the token to the listed packages and scopes, e.g. for the pipeline of a single package.
+	Packages []string
+	Scopes   []string
+	// ExpiresIn is the lifetime of the token. Tokens with a zero lifetime never expire.
+	ExpiresIn time.Duration
+}
+
+// usecases
+
+// CreateToken creates an access token for the user. The returned token is the only time the secret is available.
+func (s *TokenService) CreateToken(ctx context.Context, user *entities.User, req CreateTokenRequest) (*entities.AccessToken, fields.AccessToken, error) {
+	if err := checkTokenManagement(user); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.authService.VerifyPassword(ctx, user, req.Password); err != nil {
+		return nil, "", err
+	}
+
+	// writes with automation tokens skip the one-time password, so they must not bypass the enrollment the role requires
+	if err := requireTwoFactorEnrollment(ctx, s.authService.twoFactorAdapter, user); err != nil {
+		return nil, "", err
+	}
+
+	if req.Automation {
+		tf, err := getTwoFactor(ctx, s.authService.twoFactorAdapter, user)
+		if err != nil {
+			return nil, "", err
+		}
+		if !tf.Enabled() {
+			return nil, "", &InvalidTokenFieldError{
+				Field:  "automation",
+				Reason: "automation tokens require two-factor authentication",
+			}
+		}
+	}
+
+	cidrs := make([]fields.CIDR, 0, len(req.CIDRWhitelist))
+	for _, c := range req.CIDRWhitelist {
+		cidr, err := fields.CIDRFromString(c)
+		if err != nil {
+			return nil, "", &InvalidTokenFieldError{
+				Field:  "cidr_whitelist",
+				Reason: err.Error(),
+			}
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	restriction, err := packageRestrictionFromRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if req.ExpiresIn < 0 {
+		return nil, "", &InvalidTokenFieldError{
+			Field:  "expires",
+			Reason: "must not be negative",
+		}
+	}
+	var expiresAt *time.Time
+	if req.ExpiresIn > 0 {
+		t := time.Now().Add(req.ExpiresIn)
+		expiresAt = &t
+	}
+
+	secret, err := fields.NewAccessToken()
+	if err != nil {
+		return nil, "", &TokenServiceError{
+			Err: err,
+		}
+	}
+
+	token, err := s.adapter.CreateToken(ctx, &entities.AccessToken{
+		UserID:        user.ID,
+		Key:           secret.Key(),
+		DisplayPrefix: secret.DisplayPrefix(),
+		Readonly:      req.Readonly,
+		Automation:    req.Automation,
+		CIDRWhitelist: cidrs,
+		Restriction:   restriction,
+		ExpiresAt:     expiresAt,
+	})
+	if err != nil {
+		return nil, "", handleTokenErrors(err)
+	}
+
+	return token, secret, nil
+}
+
+func (s *TokenService) ListTokens(ctx context.Context, user *entities.User) ([]*entities.AccessToken, error) {
+	if err := checkTokenManagement(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.adapter.ListTokens(ctx, user.ID)
+	if err != nil {
+		return nil, handleTokenErrors(err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes the access token of the user with the given key.
+func (s *TokenService) RevokeToken(ctx context.Context, user *entities.User, key string) error {
+	if err := checkTokenManagement(user); err != nil {
+		return err
+	}
+
+	tokenKey, err := fields.AccessTokenKeyFromString(key)
+	if err != nil {
+		return &InvalidTokenFieldError{
+			Field:  "key",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := s.adapter.RevokeToken(ctx, user.ID, tokenKey); err != nil {
+		return handleTokenErrors(err)
+	}
+	return nil
+}
+
+// RevokeTokenBySecret revokes the access token of the user, e.g. on "npm logout".
+// Unlike RevokeToken any token may revoke itself, since it has to be known to be revoked.
+func (s *TokenService) RevokeTokenBySecret(ctx context.Context, user *entities.User, token string) error {
+	secret, err := fields.AccessTokenFromString(token)
+	if err != nil {
+		return &InvalidTokenFieldError{
+			Field:  "token",
+			Reason: err.Error(),
+		}
+	}
+
+	if err := s.adapter.RevokeToken(ctx, user.ID, secret.Key()); err != nil {
+		return handleTokenErrors(err)
+	}
+	return nil
+}
+
+// Authenticate resolves an access token used from the given IP address into its user, which is the principal
+// of the request. The principal carries the token, so package restrictions of the token are enforced by the
+// services. Readonly tokens narrow the permissions of the user to reading.
+func (s *TokenService) Authenticate(ctx context.Context, token string, remoteIP string) (*entities.User, error) {
+	secret, err := fields.AccessTokenFromString(token)
+	if err != nil {
+		return nil, &TokenServiceInvalidTokenError{
+			Reason: err.Error(),
+		}
+	}
+
+	accessToken, err := s.adapter.GetToken(ctx, secret.Key())
+	if err != nil {
+		if _, ok := err.(*ports.TokenAdapterTokenNotFoundError); ok {
+			return nil, &TokenServiceInvalidTokenError{
+				Reason: "token is unknown or revoked",
+			}
+		}
+		return nil, handleTokenErrors(err)
+	}
+
+	now := time.Now()
+	if accessToken.Expired(now) {
+		return nil, &TokenServiceInvalidTokenError{
+			Reason: "token expired",
+		}
+	}
+
+	if len(accessToken.CIDRWhitelist) > 0 {
+		ip, err := netip.ParseAddr(remoteIP)
+		if err != nil || !cidrsContain(accessToken.CIDRWhitelist, ip) {
+			return nil, &TokenServiceInvalidTokenError{
+				Reason: fmt.Sprintf("token may not be used from %s", remoteIP),
+			}
+		}
+	}
+
+	user, err := s.userAdapter.GetUserByID(ctx, accessToken.UserID)
+	if err != nil {
+		if _, ok := err.(*ports.UserAdapterUserNotFoundError); ok {
+			return nil, &TokenServiceInvalidTokenError{
+				Reason: "user of the token does not exist",
+			}
+		}
+		return nil, &TokenServiceError{
+			Err: err,
+		}
+	}
+
+	if accessToken.LastUsedAt == nil || touchDue(*accessToken.LastUsedAt, now) {
+		if err := s.adapter.TouchToken(ctx, accessToken.Key, now); err != nil {
+			return nil, handleTokenErrors(err)
+		}
+	}
+
+	return tokenPrincipal(user, accessToken), nil
+}
+
+// helpers
+
+// touchInterval is how often the last use of tokens and sessions is written, so requests do not write on every use.
+const touchInterval = time.Minute
+
+// touchDue reports whether a token or session last used at the given time must record a use again.
+func touchDue(lastUsedAt time.Time, now time.Time) bool {
+	return now.Sub(lastUsedAt) >= touchInterval
+}
+
+// tokenPrincipal returns a copy of the user carrying the token. The role of readonly tokens only grants reading.
+func tokenPrincipal(user *entities.User, token *entities.AccessToken) *entities.User {
+	principal := *user
+	principal.AccessToken = token
+
+	if token.Readonly {
+		role := *user.Role
+		role.Permissions = entities.Permissions{
+			GetUser:    user.Role.Permissions.GetUser,
+			GetRole:    user.Role.Permissions.GetRole,
+			GetPackage: user.Role.Permissions.GetPackage,
+		}
+		principal.Role = &role
+	}
+
+	return &principal
+}
+
+// checkTokenManagement returns NotAllowedToManageTokensError unless the user logged in or uses an access token
+// with full write access, so a leaked readonly or restricted token can't list, create or revoke tokens.
+func checkTokenManagement(user *entities.User) error {
+	if user == nil {
+		return &coreerrors.NotAllowedToManageTokensError{}
+	}
+
+	token := user.AccessToken
+	if token != nil && (token.Readonly || len(token.CIDRWhitelist) > 0 || token.Restriction.IsRestricted()) {
+		return &coreerrors.NotAllowedToManageTokensError{}
+	}
+	return nil
+}
+
+func packageRestrictionFromRequest(req CreateTokenRequest) (entities.PackageRestriction, error) {
+	restriction := entities.PackageRestriction{
+		Packages: make([]fields.PackageName, 0, len(req.Packages)),
+		Scopes:   make([]fields.PackageScope, 0, len(req.Scopes)),
+	}
+
+	for _, p := range req.Packages {
+		name, err := fields.PackageNameFromString(p)
+		if err != nil {
+			return restriction, &InvalidTokenFieldError{
+				Field:  "packages",
+				Reason: err.Error(),
+			}
+		}
+		restriction.Packages = append(restriction.Packages, name)
+	}
+
+	for _, sc := range req.Scopes {
+		scope, err := fields.PackageScopeFromString(sc)
+		if err != nil {
+			return restriction, &InvalidTokenFieldError{
+				Field:  "scopes",
+				Reason: err.Error(),
+			}
+		}
+		restriction.Scopes = append(restriction.Scopes, scope)
+	}
+
+	return restriction, nil
+}
+
+func cidrsContain(cidrs []fields.CIDR, ip netip.Addr) bool {
+	for _, cidr := range cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// errors
+
+type InvalidTokenFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidTokenFieldError) Error() string {
+	return fmt.Sprintf("invalid token field %s: %s", e.Field, e.Reason)
+}
+
+type TokenServiceTokenNotFoundError struct {
+	Key string
+}
+
+func (e *TokenServiceTokenNotFoundError) Error() string {
+	return fmt.Sprintf("access token %s not found", e.Key)
+}
+
+type TokenServiceInvalidTokenError struct {
+	Reason string
+}
+
+func (e *TokenServiceInvalidTokenError) Error() string {
+	return "access token is invalid: " + e.Reason
+}
+
+type TokenServiceError struct {
+	Err error
+}
+
+func (e *TokenServiceError) Error() string {
+	return fmt.Sprintf("access token error: %s", e.Err)
+}
+
+// service errors
+
+func handleTokenErrors(err error) error {
+	switch e := err.(type) {
+	case *ports.TokenAdapterTokenNotFoundError:
+		return &TokenServiceTokenNotFoundError{
+			Key: e.Key.String(),
+		}
+	default:
+		return &TokenServiceError{
+			Err: e,
+		}
+	}
+}
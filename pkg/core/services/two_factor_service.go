@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrparano1d/noxite/pkg/core/entities"
+	"github.com/mrparano1d/noxite/pkg/core/fields"
+	"github.com/mrparano1d/noxite/pkg/core/ports"
+)
+
+// twoFactorIssuer is the name authenticator apps show for the account.
+const twoFactorIssuer = "noxite"
+
+// recoveryCodeCount is the number of recovery codes generated on enrolment.
+const recoveryCodeCount = 10
+
+type TwoFactorService struct {
+	adapter ports.TwoFactorPort
+
+	authService *AuthService
+}
+
+func NewTwoFactorService(
+	adapter ports.TwoFactorPort,
+	authService *AuthService,
+) *TwoFactorService {
+	return &TwoFactorService{
+		adapter:     adapter,
+		authService: authService,
+	}
+}
+
+type otpContextKey struct{}
+
+// ContextWithOTP returns a context carrying the one-time password sent with a request, e.g. in the npm-otp header.
+func ContextWithOTP(ctx context.Context, otp string) context.Context {
+	return context.WithValue(ctx, otpContextKey{}, otp)
+}
+
+func otpFromContext(ctx context.Context) string {
+	otp, _ := ctx.Value(otpContextKey{}).(string)
+	return otp
+}
+
+// usecases
+
+// GetTwoFactor returns the two-factor settings of the user or nil if the user has not enrolled.
+func (s *TwoFactorService) GetTwoFactor(ctx context.Context, user *entities.User) (*entities.TwoFactor, error) {
+	tf, err := s.adapter.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		if _, ok := err.(*ports.TwoFactorAdapterNotFoundError); ok {
+			return nil, nil
+		}
+		return nil, handleTwoFactorErrors(err)
+	}
+	return tf, nil
+}
+
+// Enroll starts the enrolment of the user and returns the otpauth URI of the new secret, which must be confirmed
+// with a first code. If two-factor authentication is already enabled, only the mode is changed and the URI is empty.
+func (s *TwoFactorService) Enroll(ctx context.Context, user *entities.User, password string, mode string) (string, error) {
+	twoFactorMode, err := fields.TwoFactorModeFromString(mode)
+	if err != nil {
+		return "", &InvalidTwoFactorFieldError{
+			Field:  "mode",
+			Reason: err.Error(),
+		}
+	}
+
+	// requires a one-time password if two-factor authentication is enabled already
+	if err := s.authService.VerifyPassword(ctx, user, password); err != nil {
+		return "", err
+	}
+
+	tf, err := s.GetTwoFactor(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	if tf.Enabled() {
+		tf.Mode = twoFactorMode
+		if err := s.adapter.SaveTwoFactor(ctx, tf); err != nil {
+			return "", handleTwoFactorErrors(err)
+		}
+		return "", nil
+	}
+
+	secret, err := fields.NewTOTPSecret()
+	if err != nil {
+		return "", &TwoFactorServiceError{
+			Err: err,
+		}
+	}
+
+	err = s.adapter.SaveTwoFactor(ctx, &entities.TwoFactor{
+		UserID:  user.ID,
+		Secret:  secret,
+		Mode:    twoFactorMode,
+		Pending: true,
+	})
+	if err != nil {
+		return "", handleTwoFactorErrors(err)
+	}
+
+	return secret.URI(twoFactorIssuer, user.Username.String()), nil
+}
+
+// Confirm completes the enrolment with a code of the authenticator and returns the recovery codes,
+// which are shown only once.
+func (s *TwoFactorService) Confirm(ctx context.Context, user *entities.User, otp string) ([]fields.RecoveryCode, error) {
+	tf, err := s.GetTwoFactor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, &TwoFactorNotEnabledError{}
+	}
+	if tf.Enabled() {
+		return nil, &TwoFactorAlreadyEnabledError{}
+	}
+
+	// the code is part of the request rather than a one-time password of the context, so it is an invalid field
+	code, err := fields.OTPCodeFromString(otp)
+	if err != nil || !code.IsTOTP() {
+		return nil, &InvalidTwoFactorFieldError{
+			Field:  "tfa",
+			Reason: "expected the 6 digit code of the authenticator",
+		}
+	}
+	step, ok := tf.Secret.Verify(code, time.Now())
+	if !ok {
+		return nil, &InvalidTwoFactorFieldError{
+			Field:  "tfa",
+			Reason: "the code does not match, check the clock of the device",
+		}
+	}
+
+	codes := make([]fields.RecoveryCode, 0, recoveryCodeCount)
+	keys := make([]fields.RecoveryCodeKey, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := fields.NewRecoveryCode()
+		if err != nil {
+			return nil, &TwoFactorServiceError{
+				Err: err,
+			}
+		}
+		codes = append(codes, code)
+		keys = append(keys, code.Key())
+	}
+
+	tf.Pending = false
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = keys
+	if err := s.adapter.SaveTwoFactor(ctx, tf); err != nil {
+		return nil, handleTwoFactorErrors(err)
+	}
+
+	return codes, nil
+}
+
+// Disable removes two-factor authentication of the user. It requires the password and a one-time password.
+func (s *TwoFactorService) Disable(ctx context.Context, user *entities.User, password string) error {
+	if err := s.authService.VerifyPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if err := s.adapter.DeleteTwoFactor(ctx, user.ID); err != nil {
+		if _, ok := err.(*ports.TwoFactorAdapterNotFoundError); ok {
+			return &TwoFactorNotEnabledError{}
+		}
+		return handleTwoFactorErrors(err)
+	}
+
+	return nil
+}
+
+// helpers
+
+type twoFactorAction int
+
+const (
+	twoFactorLogin twoFactorAction = iota
+	// twoFactorWrite are publishes, unpublishes, deprecations and dist-tag changes.
+	twoFactorWrite
+)
+
+// requireTwoFactor checks the one-time password of the context if the user enabled two-factor authentication
+// for the action. Writes of users whose role requires two-factor authentication are rejected until they enrolled,
+// with access tokens as well. Automation tokens are exempt from the one-time password of writes like those of npm,
+// since they were created with a one-time password. Other access tokens need one, as they may predate the enrollment.
+// Wrong one-time passwords of writes count against the user like failed logins, logins and VerifyPassword count
+// them themselves.
+func (s *AuthService) requireTwoFactor(ctx context.Context, user *entities.User, action twoFactorAction) error {
+	tf, err := getTwoFactor(ctx, s.twoFactorAdapter, user)
+	if err != nil {
+		return err
+	}
+
+	if !tf.Enabled() {
+		if action == twoFactorWrite && user.Role.RequireTwoFactor {
+			return &TwoFactorEnrollmentRequiredError{
+				Role: user.Role.Name.String(),
+			}
+		}
+		return nil
+	}
+
+	if action == twoFactorWrite && (tf.Mode != fields.TwoFactorModeAuthAndWrites || user.AccessToken != nil && user.AccessToken.Automation) {
+		return nil
+	}
+
+	if action == twoFactorWrite {
+		return s.throttleVerification(ctx, user, func() error {
+			return verifyOTP(ctx, s.twoFactorAdapter, tf)
+		})
+	}
+	return verifyOTP(ctx, s.twoFactorAdapter, tf)
+}
+
+// requireTwoFactorEnrollment returns TwoFactorEnrollmentRequiredError if the role of the user requires two-factor
+// authentication and the user didn't enable it yet.
+func requireTwoFactorEnrollment(ctx context.Context, adapter ports.TwoFactorPort, user *entities.User) error {
+	if !user.Role.RequireTwoFactor {
+		return nil
+	}
+
+	tf, err := getTwoFactor(ctx, adapter, user)
+	if err != nil {
+		return err
+	}
+
+	if !tf.Enabled() {
+		return &TwoFactorEnrollmentRequiredError{
+			Role: user.Role.Name.String(),
+		}
+	}
+	return nil
+}
+
+// getTwoFactor returns the two-factor authentication of the user, nil if the user never enrolled.
+func getTwoFactor(ctx context.Context, adapter ports.TwoFactorPort, user *entities.User) (*entities.TwoFactor, error) {
+	tf, err := adapter.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		if _, ok := err.(*ports.TwoFactorAdapterNotFoundError); ok {
+			return nil, nil
+		}
+		return nil, handleTwoFactorErrors(err)
+	}
+	return tf, nil
+}
+
+// verifyOTP checks the one-time password of the context, either a TOTP code or a recovery code, which is used up.
+// TOTP codes of earlier time steps than the last accepted code are rejected. The last accepted code itself may be
+// sent again, since npm sends it with each request of a command like "npm unpublish".
+func verifyOTP(ctx context.Context, adapter ports.TwoFactorPort, tf *entities.TwoFactor) error {
+	code, err := fields.OTPCodeFromString(otpFromContext(ctx))
+	if err != nil {
+		return &TwoFactorRequiredError{}
+	}
+
+	if code.IsTOTP() {
+		step, ok := tf.Secret.Verify(code, time.Now())
+		if !ok || step < tf.LastUsedStep {
+			return &TwoFactorInvalidCodeError{}
+		}
+		if step == tf.LastUsedStep {
+			return nil
+		}
+		tf.LastUsedStep = step
+	} else {
+		key := fields.RecoveryCode(code).Key()
+		remaining := make([]fields.RecoveryCodeKey, 0, len(tf.RecoveryCodes))
+		for _, k := range tf.RecoveryCodes {
+			if k != key {
+				remaining = append(remaining, k)
+			}
+		}
+		if len(remaining) == len(tf.RecoveryCodes) {
+			return &TwoFactorInvalidCodeError{}
+		}
+		tf.RecoveryCodes = remaining
+	}
+
+	if err := adapter.SaveTwoFactor(ctx, tf); err != nil {
+		return handleTwoFactorErrors(err)
+	}
+
+	return nil
+}
+
+// errors
+
+type InvalidTwoFactorFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidTwoFactorFieldError) Error() string {
+	return fmt.Sprintf("invalid two-factor field %s: %s", e.Field, e.Reason)
+}
+
+// TwoFactorRequiredError is returned if a one-time password is required but was not sent.
+type TwoFactorRequiredError struct{}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "this operation requires a one-time password"
+}
+
+type TwoFactorInvalidCodeError struct{}
+
+func (e *TwoFactorInvalidCodeError) Error() string {
+	return "the one-time password is invalid or was used already"
+}
+
+// TwoFactorEnrollmentRequiredError is returned for writes and token creations of users whose role requires two-factor
+// authentication.
+type TwoFactorEnrollmentRequiredError struct {
+	Role string
+}
+
+func (e *TwoFactorEnrollmentRequiredError) Error() string {
+	return fmt.Sprintf("role %s requires two-factor authentication, enable it with npm profile enable-2fa", e.Role)
+}
+
+type TwoFactorNotEnabledError struct{}
+
+func (e *TwoFactorNotEnabledError) Error() string {
+	return "two-factor authentication is not enabled"
+}
+
+type TwoFactorAlreadyEnabledError struct{}
+
+func (e *TwoFactorAlreadyEnabledError) Error() string {
+	return "two-factor authentication is already enabled"
+}
+
+type TwoFactorServiceError struct {
+	Err error
+}
+
+func (e *TwoFactorServiceError) Error() string {
+	return fmt.Sprintf("two-factor error: %s", e.Err)
+}
+
+// service errors
+
+func handleTwoFactorErrors(err error) error {
+	switch e := err.(type) {
+	case *ports.TwoFactorAdapterNotFoundError:
+		return &TwoFactorNotEnabledError{}
+	default:
+		return &TwoFactorServiceError{
+			Err: e,
+		}
+	}
+}
@@ -11,11 +11,12 @@ import (
 )
 
 type UserService struct {
-	adapter ports.UserPort
+	adapter        ports.UserPort
+	sessionService *SessionService
 }
 
-func NewUserService(adapter ports.UserPort) *UserService {
-	return &UserService{adapter: adapter}
+func NewUserService(adapter ports.UserPort, sessionService *SessionService) *UserService {
+	return &UserService{adapter: adapter, sessionService: sessionService}
 }
 
 func (s *UserService) CreateUser(ctx context.Context, user *entities.User, req CreateUserRequest) (fields.EntityID, error) {
@@ -24,6 +25,11 @@ func (s *UserService) CreateUser(ctx context.Context, user *entities.User, req C
 		return fields.EntityID(0), &coreerrors.NotAllowedToCreateUserError{}
 	}
 
+	return s.createUser(ctx, req)
+}
+
+// createUser creates the user without checking permissions. It is shared with signups, which have no user yet.
+func (s *UserService) createUser(ctx context.Context, req CreateUserRequest) (fields.EntityID, error) {
 	input, err := CreateUserRequestToInput(req)
 	if err != nil {
 		return fields.EntityID(0), err
@@ -37,6 +43,14 @@ func (s *UserService) CreateUser(ctx context.Context, user *entities.User, req C
 	return userID, nil
 }
 
+// deleteUser deletes a user without permission checks, e.g. to undo a signup that failed after the user was created.
+func (s *UserService) deleteUser(ctx context.Context, userID fields.EntityID) error {
+	if err := s.adapter.DeleteUser(ctx, userID); err != nil {
+		return handleUserServiceErrors(err)
+	}
+	return nil
+}
+
 func (s *UserService) GetAllUsers(ctx context.Context, user *entities.User) ([]*entities.User, error) {
 
 	if user == nil || user.Role.Permissions.GetUser == false {
@@ -69,6 +83,26 @@ func (s *UserService) GetUserByID(ctx context.Context, user *entities.User, user
 	return user, nil
 }
 
+// GetUserByUsername returns the user with the given username. Users may always get themselves.
+func (s *UserService) GetUserByUsername(ctx context.Context, user *entities.User, username string) (*entities.User, error) {
+
+	name, err := fields.UsernameFromString(username)
+	if err != nil {
+		return nil, handleUserServiceRequestValidationError("username", err.Error())
+	}
+
+	if user == nil || (user.Username != name && user.Role.Permissions.GetUser == false) {
+		return nil, &coreerrors.NotAllowedToGetUserError{}
+	}
+
+	user, err = s.adapter.GetUserByUsername(ctx, name)
+	if err != nil {
+		return nil, handleUserServiceErrors(err)
+	}
+
+	return user, nil
+}
+
 func (s *UserService) UpdateUser(ctx context.Context, user *entities.User, userID string, req UpdateUserRequest) error {
 
 	if user == nil || user.Role.Permissions.UpdateUser == false {
@@ -90,6 +124,12 @@ func (s *UserService) UpdateUser(ctx context.Context, user *entities.User, userI
 		return handleUserServiceErrors(err)
 	}
 
+	// sessions granted by the previous role end, other changes apply to the sessions on their next request
+	if input.RoleID != nil {
+		return s.sessionService.InvalidateUserSessions(ctx, id)
+	}
+	s.sessionService.forgetUser(id)
+
 	return nil
 }
 
@@ -109,7 +149,7 @@ func (s *UserService) DeleteUser(ctx context.Context, user *entities.User, userI
 		return handleUserServiceErrors(err)
 	}
 
-	return nil
+	return s.sessionService.InvalidateUserSessions(ctx, id)
 }
 
 // requests
@@ -118,6 +158,7 @@ type CreateUserRequest struct {
 	Username string
 	Email    string
 	Password string
+	RoleID   string
 }
 
 func CreateUserRequestToInput(req CreateUserRequest) (ports.CreateUserInput, error) {
@@ -136,6 +177,10 @@ func CreateUserRequestToInput(req CreateUserRequest) (ports.CreateUserInput, err
 		return input, handleUserServiceRequestValidationError("password", err.Error())
 	}
 
+	if input.RoleID, err = fields.EntityIDFromString(req.RoleID); err != nil {
+		return input, handleUserServiceRequestValidationError("role_id", err.Error())
+	}
+
 	return input, nil
 }
 
@@ -143,6 +188,7 @@ type UpdateUserRequest struct {
 	Username *string
 	Email    *string
 	Password *string
+	RoleID   *string
 }
 
 func UpdateUserRequestToInput(req UpdateUserRequest) (ports.UpdateUserInput, error) {
@@ -172,6 +218,14 @@ func UpdateUserRequestToInput(req UpdateUserRequest) (ports.UpdateUserInput, err
 		}
 	}
 
+	if req.RoleID != nil {
+		if roleID, err := fields.EntityIDFromString(*req.RoleID); err != nil {
+			return input, handleUserServiceRequestValidationError("role_id", err.Error())
+		} else {
+			input.RoleID = &roleID
+		}
+	}
+
 	return input, nil
 }
 
@@ -187,6 +241,10 @@ func handleUserServiceErrors(err error) error {
 		return &UserServiceGetUserByIDFailedError{ID: e.ID, Err: e.Err}
 	case *ports.UserAdapterUserNotFoundError:
 		return &UserServiceUserNotFoundError{ID: e.ID}
+	case *ports.UserAdapterGetUserByUsernameFailedError:
+		return &UserServiceGetUserByUsernameFailedError{Username: e.Username, Err: e.Err}
+	case *ports.UserAdapterUsernameNotFoundError:
+		return &UserServiceUsernameNotFoundError{Username: e.Username}
 	case *ports.UserAdapterUpdateUserFailedError:
 		return &UserServiceUpdateUserFailedError{ID: e.ID, Err: e.Err}
 	case *ports.UserAdapterDeleteUserFailedError:
@@ -253,6 +311,23 @@ func (e UserServiceUserNotFoundError) Error() string {
 	return fmt.Sprintf("user with ID %q not found", e.ID)
 }
 
+type UserServiceGetUserByUsernameFailedError struct {
+	Username fields.Username
+	Err      error
+}
+
+func (e UserServiceGetUserByUsernameFailedError) Error() string {
+	return fmt.Sprintf("failed to get user with username %q: %v", e.Username, e.Err)
+}
+
+type UserServiceUsernameNotFoundError struct {
+	Username fields.Username
+}
+
+func (e UserServiceUsernameNotFoundError) Error() string {
+	return fmt.Sprintf("user with username %q not found", e.Username)
+}
+
 type UserServiceUpdateUserFailedError struct {
 	ID  fields.EntityID
 	Err error
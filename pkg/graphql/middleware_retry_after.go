@@ -0,0 +1,84 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+type retryAfterContextKey struct{}
+
+// retryAfter holds the seconds a resolver asks the client to wait, zero if the request was not throttled.
+type retryAfter struct {
+	mu      sync.Mutex
+	seconds int
+}
+
+// RetryAfterMiddleware answers with 429 Too Many Requests and a Retry-After header if a resolver throttled the
+// request with SetRetryAfter, e.g. a login after too many failed logins.
+func RetryAfterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		holder := &retryAfter{}
+		ctx := context.WithValue(r.Context(), retryAfterContextKey{}, holder)
+		next.ServeHTTP(&retryAfterResponseWriter{ResponseWriter: w, retryAfter: holder}, r.WithContext(ctx))
+	})
+}
+
+// SetRetryAfter makes RetryAfterMiddleware ask the client to retry the request after the given seconds.
+func SetRetryAfter(ctx context.Context, seconds int) {
+	holder, ok := ctx.Value(retryAfterContextKey{}).(*retryAfter)
+	if !ok {
+		return
+	}
+
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	if seconds > holder.seconds {
+		holder.seconds = seconds
+	}
+}
+
+type retryAfterResponseWriter struct {
+	http.ResponseWriter
+	retryAfter  *retryAfter
+	wroteHeader bool
+}
+
+func (w *retryAfterResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+
+		w.retryAfter.mu.Lock()
+		seconds := w.retryAfter.seconds
+		w.retryAfter.mu.Unlock()
+
+		if seconds > 0 && code == http.StatusOK {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			code = http.StatusTooManyRequests
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryAfterResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush and Hijack keep subscriptions working, which need the underlying writer.
+func (w *retryAfterResponseWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *retryAfterResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *retryAfterResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}